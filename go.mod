@@ -3,6 +3,7 @@ module github.com/vkngwrapper/examples
 go 1.25
 
 require (
+	github.com/CannibalVox/cgoparam v1.1.0
 	github.com/g3n/engine v0.2.0
 	github.com/google/uuid v1.6.0
 	github.com/loov/hrtime v1.0.3
@@ -14,5 +15,3 @@ require (
 	github.com/vkngwrapper/math v1.1.2
 	golang.org/x/sync v0.19.0
 )
-
-require github.com/CannibalVox/cgoparam v1.1.0 // indirect
@@ -79,11 +79,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Copy/Blit Image", debugOptions)
 	if err != nil {
@@ -221,7 +217,7 @@ func main() {
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &cmdFence,
+	_, err = info.SyncGraphicsQueue.Submit(&cmdFence,
 		core1_0.SubmitInfo{
 			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
 			WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
@@ -235,6 +231,7 @@ func main() {
 	/* Make sure command buffer is finished before mapping */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, common.NoTimeout, cmdFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -406,7 +403,7 @@ func main() {
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence,
 		core1_0.SubmitInfo{
 			CommandBuffers: []core1_0.CommandBuffer{info.Cmd},
 		},
@@ -415,7 +412,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	_, err = info.DeviceDriver.QueueWaitIdle(info.GraphicsQueue)
+	_, err = info.SyncGraphicsQueue.WaitIdle()
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -425,6 +422,7 @@ func main() {
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -433,7 +431,7 @@ func main() {
 			break
 		}
 	}
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, khr_swapchain.PresentInfo{
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
 		Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
 		ImageIndices: []int{info.CurrentBuffer},
 	})
@@ -6,7 +6,6 @@ import (
 	"log"
 	"runtime/debug"
 	"time"
-	"unsafe"
 
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
@@ -81,11 +80,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Multi-pass render passes", debugOptions)
 	if err != nil {
@@ -161,7 +156,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBSolidFaceColorsData, binary.Size(utils.VBSolidFaceColorsData), int(unsafe.Sizeof(utils.Vertex{})), false)
+	err = info.InitVertexBuffers(utils.VBSolidFaceColorsData, binary.Size(utils.VBSolidFaceColorsData))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -470,7 +465,7 @@ func main() {
 		},
 		ClearValues: clearValues,
 	}
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, renderPassBegin)
+	err = info.BeginRenderPass(info.Cmd, "Stencil Render Pass", core1_0.SubpassContentsInline, renderPassBegin)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -496,7 +491,7 @@ func main() {
 	info.DeviceDriver.CmdSetScissor(info.Cmd, scissor)
 
 	/* Draw the cube into stencil */
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
+	info.Draw(info.Cmd, "Stencil Cube", 36, 1, 0, 0)
 
 	/* Advance to the next subpass */
 	info.DeviceDriver.CmdNextSubpass(info.Cmd, core1_0.SubpassContentsInline)
@@ -508,8 +503,8 @@ func main() {
 	info.DeviceDriver.CmdSetScissor(info.Cmd, scissor)
 
 	/* Draw the fullscreen pass */
-	info.DeviceDriver.CmdDraw(info.Cmd, 4, 1, 0, 0)
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.Draw(info.Cmd, "Stencil Fullscreen", 4, 1, 0, 0)
+	info.EndRenderPass(info.Cmd, "Stencil Render Pass")
 
 	/**
 	 * Second renderpass in this sample.
@@ -634,7 +629,7 @@ func main() {
 	/* Use our framebuffer and render pass */
 	renderPassBegin.Framebuffer = info.Framebuffer[info.CurrentBuffer]
 	renderPassBegin.RenderPass = blendRenderPass
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, renderPassBegin)
+	err = info.BeginRenderPass(info.Cmd, "Blend Render Pass", core1_0.SubpassContentsInline, renderPassBegin)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -646,7 +641,7 @@ func main() {
 	info.DeviceDriver.CmdSetScissor(info.Cmd, scissor)
 
 	/* Draw the cube blending */
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
+	info.Draw(info.Cmd, "Blend Cube", 36, 1, 0, 0)
 
 	/* Advance to the next subpass */
 	info.DeviceDriver.CmdNextSubpass(info.Cmd, core1_0.SubpassContentsInline)
@@ -662,10 +657,10 @@ func main() {
 
 	info.DeviceDriver.CmdSetViewport(info.Cmd, viewport)
 	info.DeviceDriver.CmdSetScissor(info.Cmd, scissor)
-	info.DeviceDriver.CmdDraw(info.Cmd, 4, 1, 0, 0)
+	info.Draw(info.Cmd, "Blend Fullscreen", 4, 1, 0, 0)
 
 	/* The second renderpass is complete */
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.EndRenderPass(info.Cmd, "Blend Render Pass")
 
 	/* VULKAN_KEY_END */
 
@@ -679,7 +674,7 @@ func main() {
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
 			CommandBuffers:   []core1_0.CommandBuffer{info.Cmd},
@@ -689,12 +684,14 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
 
 	/* Now present the image in the window */
 
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -704,7 +701,7 @@ func main() {
 		}
 	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, khr_swapchain.PresentInfo{
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
 		Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
 		ImageIndices: []int{info.CurrentBuffer},
 	})
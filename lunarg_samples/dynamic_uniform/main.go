@@ -1,14 +1,13 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"embed"
 	"encoding/binary"
 	"log"
 	"math"
 	"runtime/debug"
 	"time"
-	"unsafe"
 
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
@@ -16,6 +15,9 @@ import (
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/uniformring"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 	vkngmath "github.com/vkngwrapper/math"
@@ -83,11 +85,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Draw Cube", debugOptions)
 	if err != nil {
@@ -174,7 +172,13 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBSolidFaceColorsData, binary.Size(utils.VBSolidFaceColorsData), int(unsafe.Sizeof(utils.Vertex{})), false)
+	cube := mesh.Deduplicate(utils.VBSolidFaceColorsData)
+	err = info.InitVertexBuffers(cube.Vertices, binary.Size(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitIndexBuffer(cube.Indices, len(cube.Vertices))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -200,67 +204,28 @@ func main() {
 	mvp2.SetApplyTransform(&info.Model, &info.View)
 	mvp2.ApplyTransform(&info.Projection)
 
-	bufSize := int(unsafe.Sizeof(info.MVP))
-
-	if info.GpuProps.Limits.MinUniformBufferOffsetAlignment != 0 {
-		bufSize = (bufSize + info.GpuProps.Limits.MinUniformBufferOffsetAlignment - 1) &
-			^(info.GpuProps.Limits.MinUniformBufferOffsetAlignment - 1)
-	}
-
-	info.UniformData.Buf, _, err = info.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
-		Usage:       core1_0.BufferUsageUniformBuffer,
-		Size:        2 * bufSize,
-		SharingMode: core1_0.SharingModeExclusive,
-	})
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	memReqs := info.DeviceDriver.GetBufferMemoryRequirements(info.UniformData.Buf)
-
-	memoryTypeIndex, err := info.MemoryTypeFromProperties(memReqs.MemoryTypeBits, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	info.UniformData.Mem, _, err = info.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
-		AllocationSize:  memReqs.Size,
-		MemoryTypeIndex: memoryTypeIndex,
-	})
+	/* Both matrices are pushed into a ring buffer, which rounds each one up to
+	 * minUniformBufferOffsetAlignment so its offset can be used as a dynamic offset */
+	uniforms, err := uniformring.New(info.DeviceDriver, info.MemoryProperties, info.GpuProps.Limits, core1_0.BufferUsageUniformBuffer, 64*1024)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	/* Map the buffer memory and copy both matrices */
-	pData, _, err := info.DeviceDriver.MapMemory(info.UniformData.Mem, 0, memReqs.Size, 0)
+	firstMVP, err := uniforms.Push(info.MVP)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	dataBuffer := unsafe.Slice((*byte)(pData), memReqs.Size)
-
-	buf := &bytes.Buffer{}
-	err = binary.Write(buf, common.ByteOrder, info.MVP)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	err = binary.Write(buf, common.ByteOrder, mvp2)
+	secondMVP, err := uniforms.Push(mvp2)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	copy(dataBuffer, buf.Bytes())
-
-	info.DeviceDriver.UnmapMemory(info.UniformData.Mem)
-
-	_, err = info.DeviceDriver.BindBufferMemory(info.UniformData.Buf, info.UniformData.Mem, 0)
+	mvpLayout, err := blocklayout.TypeOf(blocklayout.Std140, &info.MVP)
 	if err != nil {
 		log.Fatalln(err)
 	}
-
-	info.UniformData.BufferInfo.Buffer = info.UniformData.Buf
-	info.UniformData.BufferInfo.Offset = 0
-	info.UniformData.BufferInfo.Range = bufSize
+	info.UniformData.BufferInfo = uniforms.BufferInfo(mvpLayout.Size)
 
 	/* Init desciptor and pipeline layouts - descriptor type is
 	 * UNIFORM_BUFFER_DYNAMIC */
@@ -353,7 +318,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
 		RenderPass:  info.RenderPass,
 		Framebuffer: info.Framebuffer[info.CurrentBuffer],
 		RenderArea: core1_0.Rect2D{
@@ -369,32 +334,30 @@ func main() {
 	info.DeviceDriver.CmdBindPipeline(info.Cmd, core1_0.PipelineBindPointGraphics, info.Pipeline)
 
 	/* The first draw should use the first matrix in the buffer */
-	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, []int{0})
+	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, []int{firstMVP.Offset})
 
 	info.DeviceDriver.CmdBindVertexBuffers(info.Cmd, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+	info.DeviceDriver.CmdBindIndexBuffer(info.Cmd, info.IndexBuffer.Buf, 0, info.IndexBuffer.Type)
 
 	info.InitViewports()
 	info.InitScissors()
 
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
+	info.DrawIndexed(info.Cmd, "First Cube", info.IndexBuffer.Count, 1, 0, 0, 0)
 
 	/* The second draw should use the
 	   second matrix in the buffer */
-	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, []int{bufSize})
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
+	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, []int{secondMVP.Offset})
+	info.DrawIndexed(info.Cmd, "Second Cube", info.IndexBuffer.Count, 1, 0, 0, 0)
 
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	drawFence, _, err := info.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
-	if err != nil {
-		log.Fatalln(err)
-	}
 
-	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
+	/* Queue the command buffer for execution. The frame number it returns
+	 * completes once the GPU is done with the command buffer and uniforms */
+	frame, _, err := info.Frames.Submit(info.SyncGraphicsQueue,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
 			CommandBuffers:   []core1_0.CommandBuffer{info.Cmd},
@@ -404,19 +367,20 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	// The frame counter has no fence to give the tracker, so the submission is completed
+	// by hand once the frame is waited on
+	crumbs := info.Breadcrumbs.Submitted(info.GraphicsQueue, core1_0.Fence{}, info.Cmd)
+	uniforms.EndFrameCounter(info.Frames, frame)
 
 	/* Now present the image in the window */
-	for {
-		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		if res != core1_0.VKTimeout {
-			break
-		}
+	res, err := info.Frames.Wait(context.Background(), frame)
+	info.Breadcrumbs.Check(res)
+	if err != nil {
+		log.Fatalln(err)
 	}
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, khr_swapchain.PresentInfo{
+	info.Breadcrumbs.CompleteSubmission(crumbs)
+	uniforms.RetireCompleted(frame)
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
 		Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
 		ImageIndices: []int{info.CurrentBuffer},
 	})
@@ -438,17 +402,17 @@ func main() {
 		}
 	}
 
-	info.DeviceDriver.DestroyFence(drawFence, nil)
 	info.DeviceDriver.DestroySemaphore(imageAcquiredSemaphore, nil)
 	info.DestroyPipeline()
 	info.DestroyPipelineCache()
 	info.DestroyDescriptorPool()
 	info.DestroyVertexBuffer()
+	info.DestroyIndexBuffer()
 	info.DestroyFramebuffers()
 	info.DestroyShaders()
 	info.DestroyRenderpass()
 	info.DestroyDescriptorAndPipelineLayouts()
-	info.DestroyUniformBuffer()
+	uniforms.Destroy()
 	info.DestroyDepthBuffer()
 	info.DestroySwapchain()
 	info.DestroyCommandBuffer()
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/vkngwrapper/core/v3"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/events"
 )
 
 /*
@@ -94,7 +98,7 @@ func main() {
 	submitInfo := core1_0.SubmitInfo{
 		CommandBuffers: []core1_0.CommandBuffer{info.Cmd},
 	}
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &fence, submitInfo)
+	_, err = info.SyncGraphicsQueue.Submit(&fence, submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -104,6 +108,7 @@ func main() {
 	timeouts := -1
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, fence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -124,7 +129,7 @@ func main() {
 	}
 
 	// Now create an event and wait for it on the GPU
-	event, _, err := info.DeviceDriver.CreateEvent(nil, core1_0.EventCreateInfo{})
+	event, err := events.New(info.DeviceDriver, "Sample Event")
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -133,7 +138,7 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	err = info.DeviceDriver.CmdWaitEvents(info.Cmd, []core1_0.Event{event}, core1_0.PipelineStageHost, core1_0.PipelineStageBottomOfPipe, nil, nil, nil)
+	err = event.CmdWaitHost(info.Cmd, core1_0.PipelineStageBottomOfPipe, events.Barriers{})
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -146,32 +151,34 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	// Note that stepping through this code in the debugger is a bad idea because the
-	// GPU can TDR waiting for the event.  Execute the code from vkQueueSubmit through
-	// vkSetEvent without breakpoints
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &fence, submitInfo)
-	if err != nil {
-		log.Fatalln(err)
+	// Vulkan requires an event the GPU waits on from the host to be set before the
+	// command buffer is submitted; setting it afterwards may hang the GPU. The check
+	// catches the missing Set.
+	err = events.CheckSubmit(event)
+	if !errors.Is(err, events.ErrHostSignalPending) {
+		log.Fatalf("Expected the unset event to be caught before submit, got %v\n", err)
 	}
+	fmt.Printf("Caught before submit: %s\n", err)
 
-	// We should timeout waiting for the fence because the GPU should be waiting
-	// on the event
-	res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, fence)
+	// Set the event from the CPU, submit and wait for the fence.  This should
+	// succeed since we set the event
+	err = event.Set()
 	if err != nil {
 		log.Fatalln(err)
 	}
-	if res != core1_0.VKTimeout {
-		log.Fatalln("Didn't get expected timeout in WaitForFences, exiting")
+	err = events.CheckSubmit(event)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	// Set the event from the CPU and wait for the fence.  This should succeed
-	// since we set the event
-	_, err = info.DeviceDriver.SetEvent(event)
+	_, err = info.SyncGraphicsQueue.Submit(&fence, submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
+
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, fence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -189,7 +196,7 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	_, err = info.DeviceDriver.ResetEvent(event)
+	err = event.Reset()
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -199,7 +206,7 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	info.DeviceDriver.CmdSetEvent(info.Cmd, event, core1_0.PipelineStageBottomOfPipe)
+	event.CmdSignal(info.Cmd, core1_0.PipelineStageBottomOfPipe)
 	err = info.ExecuteEndCommandBuffer()
 	if err != nil {
 		log.Fatalln(err)
@@ -207,29 +214,32 @@ func main() {
 
 	// Look for the event on the CPU. It should be RESET since we haven't sent
 	// the command buffer yet.
-	res, _ = info.DeviceDriver.GetEventStatus(event)
-	if res != core1_0.VKEventReset {
-		log.Fatalf("Unexpected status from event, expected %s, got %s\n", core1_0.VKEventReset, res)
+	set, err := event.IsSet()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if set {
+		log.Fatalf("Unexpected status from event, expected %s, got %s\n", core1_0.VKEventReset, core1_0.VKEventSet)
 	}
 
-	// Send the command buffer and loop waiting for the event
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &fence, submitInfo)
+	// Send the command buffer and wait for the event, polling with backoff
+	_, err = info.SyncGraphicsQueue.Submit(&fence, submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	polls := 0
-	for res != core1_0.VKEventSet {
-		res, err = info.DeviceDriver.GetEventStatus(event)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		polls++
+	ctx, cancel := context.WithTimeout(context.Background(), utils.FenceTimeout)
+	waitStart := time.Now()
+	err = event.Wait(ctx)
+	cancel()
+	if err != nil {
+		log.Fatalln(err)
 	}
-	fmt.Printf("%d polls to find the event set\n", polls)
+	fmt.Printf("Waited %s to find the event set\n", time.Since(waitStart))
 
 	for {
-		res, err = info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, fence)
+		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, fence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -239,7 +249,7 @@ func main() {
 		}
 	}
 
-	info.DeviceDriver.DestroyEvent(event, nil)
+	event.Destroy()
 	info.DeviceDriver.DestroyFence(fence, nil)
 	info.DestroyCommandBuffer()
 	info.DestroyCommandPool()
@@ -8,12 +8,12 @@ import (
 	"github.com/vkngwrapper/core/v3"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 	"log"
 	"runtime/debug"
 	"time"
-	"unsafe"
 )
 
 //go:embed shaders images
@@ -77,11 +77,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Simple Immutable Sampler", debugOptions)
 	if err != nil {
@@ -169,7 +165,13 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBTextureData, binary.Size(utils.VBTextureData), int(unsafe.Sizeof(utils.VertexUV{})), true)
+	cube := mesh.Deduplicate(utils.VBTextureData)
+	err = info.InitVertexBuffers(cube.Vertices, binary.Size(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitIndexBuffer(cube.Indices, len(cube.Vertices))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -294,7 +296,7 @@ func main() {
 		log.Fatalln(err)
 	}
 	err = info.InitPresentableImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
@@ -303,7 +305,7 @@ func main() {
 	rpBegin := info.InitRenderPassBeginInfo()
 	rpBegin.ClearValues = clearValues
 
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, rpBegin)
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, rpBegin)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -312,12 +314,13 @@ func main() {
 	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, descriptorSets, nil)
 
 	info.DeviceDriver.CmdBindVertexBuffers(info.Cmd, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+	info.DeviceDriver.CmdBindIndexBuffer(info.Cmd, info.IndexBuffer.Buf, 0, info.IndexBuffer.Type)
 
 	info.InitViewports()
 	info.InitScissors()
 
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.DrawIndexed(info.Cmd, "Cube", info.IndexBuffer.Count, 1, 0, 0, 0)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
 		log.Fatalln(err)
@@ -329,10 +332,11 @@ func main() {
 	}
 	submitInfo := info.InitSubmitInfo(core1_0.PipelineStageColorAttachmentOutput)
 
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence, *submitInfo)
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence, *submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
 
 	/* Now present the image in the window */
 	present := info.InitPresentInfo()
@@ -340,6 +344,7 @@ func main() {
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -348,7 +353,7 @@ func main() {
 		}
 	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, present)
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, present)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -384,6 +389,7 @@ func main() {
 	info.DeviceDriver.DestroyDescriptorPool(descriptorPool, nil)
 
 	info.DestroyVertexBuffer()
+	info.DestroyIndexBuffer()
 	info.DestroyFramebuffers()
 	info.DestroyShaders()
 	info.DestroyRenderpass()
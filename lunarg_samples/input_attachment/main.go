@@ -69,11 +69,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Input Attachment Sample", debugOptions)
 	if err != nil {
@@ -391,7 +387,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
 		RenderPass:  info.RenderPass,
 		Framebuffer: info.Framebuffer[info.CurrentBuffer],
 		RenderArea: core1_0.Rect2D{
@@ -411,9 +407,9 @@ func main() {
 	info.InitViewports()
 	info.InitScissors()
 
-	info.DeviceDriver.CmdDraw(info.Cmd, 3, 1, 0, 0)
+	info.Draw(info.Cmd, "Fullscreen Triangle", 3, 1, 0, 0)
 
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
 		log.Fatalln(err)
@@ -433,6 +429,7 @@ func main() {
 
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -444,7 +441,7 @@ func main() {
 	info.DeviceDriver.DestroyFence(drawFence, nil)
 
 	err = info.ExecutePresentImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"embed"
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
@@ -9,9 +8,10 @@ import (
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/recorder"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/vertexinput"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
-	"golang.org/x/sync/errgroup"
 	"log"
 	"runtime/debug"
 	"time"
@@ -32,8 +32,8 @@ func logDebug(msgType ext_debug_utils.DebugUtilsMessageTypeFlags, severity ext_d
 }
 
 type Vertex struct {
-	PosX, PosY, PosZ, PosW float32 // Position Data
-	R, G, B, A             float32 // Color
+	PosX, PosY, PosZ, PosW float32 `vk:"location=0"` // Position Data
+	R, G, B, A             float32 `vk:"location=1"` // Color
 }
 
 var triData = []Vertex{
@@ -53,8 +53,6 @@ type vertexData struct {
 	Mem    core1_0.DeviceMemory
 }
 
-var commandPools [3]core1_0.CommandPool
-var commandBuffers [3]core1_0.CommandBuffer
 var vertexBuffers [3]vertexData
 
 /*
@@ -103,11 +101,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("MT Cmd Buffer Sample", debugOptions)
 	if err != nil {
@@ -211,25 +205,9 @@ func main() {
 
 	/* The binding and attributes should be the same for all 3 vertex buffers,
 	 * so init here */
-	info.VertexBinding = core1_0.VertexInputBindingDescription{
-		Binding:   0,
-		InputRate: core1_0.VertexInputRateVertex,
-		Stride:    int(unsafe.Sizeof(triData[0])),
-	}
-
-	info.VertexAttributes = []core1_0.VertexInputAttributeDescription{
-		{
-			Binding:  0,
-			Location: 0,
-			Format:   core1_0.FormatR32G32B32A32SignedFloat,
-			Offset:   0,
-		},
-		{
-			Binding:  0,
-			Location: 1,
-			Format:   core1_0.FormatR32G32B32A32SignedFloat,
-			Offset:   int(unsafe.Offsetof(triData[0].R)),
-		},
+	err = info.InitVertexLayout(vertexinput.PerVertex(0, Vertex{}))
+	if err != nil {
+		log.Fatalln(err)
 	}
 
 	err = info.InitPipelineCache()
@@ -275,7 +253,7 @@ func main() {
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &clearFence,
+	_, err = info.SyncGraphicsQueue.Submit(&clearFence,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{info.ImageAcquiredSemaphore},
 			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
@@ -285,9 +263,11 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, clearFence, info.Cmd)
 
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, clearFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -299,12 +279,32 @@ func main() {
 	info.DeviceDriver.DestroyFence(clearFence, nil)
 
 	/* VULKAN_KEY_START */
-	group, _ := errgroup.WithContext(context.Background())
 	for i := 0; i < 3; i++ {
-		idx := i
-		group.Go(func() error {
-			return perThreadCode(info, idx)
-		})
+		err = createVertexBuffer(info, i)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	/* The recorder owns a command pool for each of the three threads, and
+	 * splits the draw list between them */
+	rec, err := recorder.New(info.DeviceDriver, info.GraphicsQueueFamilyIndex, 1, 3)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	rec.Names = info.DebugNames
+	rec.Name = "Triangle Recorder"
+
+	err = rec.BeginFrame(0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	draws := make([]recorder.Draw, 3)
+	for i := 0; i < 3; i++ {
+		draws[i] = func(cmd core1_0.CommandBuffer) error {
+			return drawTriangle(info, cmd, i)
+		}
 	}
 
 	_, err = info.DeviceDriver.BeginCommandBuffer(info.Cmd, core1_0.CommandBufferBeginInfo{})
@@ -312,6 +312,31 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsSecondaryCommandBuffers, core1_0.RenderPassBeginInfo{
+		RenderPass:  info.RenderPass,
+		Framebuffer: info.Framebuffer[info.CurrentBuffer],
+		RenderArea: core1_0.Rect2D{
+			Offset: core1_0.Offset2D{0, 0},
+			Extent: core1_0.Extent2D{info.Width, info.Height},
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	/* Record one secondary command buffer per thread, and execute them
+	 * inside the render pass */
+	err = rec.Execute(info.Cmd, core1_0.CommandBufferInheritanceInfo{
+		RenderPass:  info.RenderPass,
+		Subpass:     0,
+		Framebuffer: info.Framebuffer[info.CurrentBuffer],
+	}, draws)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	info.EndRenderPass(info.Cmd, "Render Pass")
+
 	err = info.DeviceDriver.CmdPipelineBarrier(info.Cmd, core1_0.PipelineStageColorAttachmentOutput,
 		core1_0.PipelineStageBottomOfPipe,
 		0,
@@ -344,30 +369,27 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	/* Wait for all of the threads to finish */
-	err = group.Wait()
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	drawFence, _, err := info.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence,
 		core1_0.SubmitInfo{
-			CommandBuffers: []core1_0.CommandBuffer{commandBuffers[0], commandBuffers[1], commandBuffers[2], info.Cmd},
+			CommandBuffers: []core1_0.CommandBuffer{info.Cmd},
 		},
 	)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
+	rec.EndFrame(drawFence)
 
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -378,7 +400,7 @@ func main() {
 	}
 
 	err = info.ExecutePresentImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
@@ -398,9 +420,8 @@ func main() {
 	for i := 0; i < 3; i++ {
 		info.DeviceDriver.DestroyBuffer(vertexBuffers[i].Buffer, nil)
 		info.DeviceDriver.FreeMemory(vertexBuffers[i].Mem, nil)
-		info.DeviceDriver.FreeCommandBuffers(commandBuffers[i])
-		info.DeviceDriver.DestroyCommandPool(commandPools[i], nil)
 	}
+	rec.Destroy()
 	info.DeviceDriver.DestroySemaphore(info.ImageAcquiredSemaphore, nil)
 	info.DeviceDriver.DestroyFence(drawFence, nil)
 	info.DestroyPipeline()
@@ -427,30 +448,8 @@ func main() {
 	}
 }
 
-func perThreadCode(info *utils.SampleInfo, i int) error {
-	/* This code should be executed by each of the three threads.  It will  */
-	/* create a vertex buffer with position and color per vertex, then load */
-	/* commands into the thread's designated command buffer to draw the     */
-	/* triangle                                                             */
-	var err error
-
-	commandPools[i], _, err = info.DeviceDriver.CreateCommandPool(nil, core1_0.CommandPoolCreateInfo{
-		QueueFamilyIndex: info.GraphicsQueueFamilyIndex,
-	})
-	if err != nil {
-		return err
-	}
-
-	buffers, _, err := info.DeviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
-		CommandPool:        commandPools[i],
-		Level:              core1_0.CommandBufferLevelPrimary,
-		CommandBufferCount: 1,
-	})
-	if err != nil {
-		return err
-	}
-	commandBuffers[i] = buffers[0]
-
+func createVertexBuffer(info *utils.SampleInfo, i int) error {
+	/* Each triangle gets a vertex buffer with position and color per vertex */
 	vertexBuffer, _, err := info.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
 		Size:        3 * int(unsafe.Sizeof(triData[0])),
 		Usage:       core1_0.BufferUsageVertexBuffer,
@@ -492,45 +491,29 @@ func perThreadCode(info *utils.SampleInfo, i int) error {
 	info.DeviceDriver.UnmapMemory(vertexMem)
 
 	_, err = info.DeviceDriver.BindBufferMemory(vertexBuffer, vertexMem, 0)
-	if err != nil {
-		return err
-	}
-
-	_, err = info.DeviceDriver.BeginCommandBuffer(buffers[0], core1_0.CommandBufferBeginInfo{})
-	if err != nil {
-		return err
-	}
-
-	err = info.DeviceDriver.CmdBeginRenderPass(buffers[0], core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
-		RenderPass:  info.RenderPass,
-		Framebuffer: info.Framebuffer[info.CurrentBuffer],
-		RenderArea: core1_0.Rect2D{
-			Offset: core1_0.Offset2D{0, 0},
-			Extent: core1_0.Extent2D{info.Width, info.Height},
-		},
-	})
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	info.DeviceDriver.CmdBindPipeline(buffers[0], core1_0.PipelineBindPointGraphics, info.Pipeline)
-	info.DeviceDriver.CmdBindVertexBuffers(buffers[0], 0, []core1_0.Buffer{vertexBuffer}, []int{0})
-	info.DeviceDriver.CmdSetViewport(buffers[0],
+func drawTriangle(info *utils.SampleInfo, cmd core1_0.CommandBuffer, i int) error {
+	/* This code is executed by each of the three threads.  It loads   */
+	/* commands into the thread's secondary command buffer to draw the */
+	/* triangle.  Dynamic state isn't inherited from the primary, so   */
+	/* the viewport and scissor are set here                           */
+	info.DeviceDriver.CmdBindPipeline(cmd, core1_0.PipelineBindPointGraphics, info.Pipeline)
+	info.DeviceDriver.CmdBindVertexBuffers(cmd, 0, []core1_0.Buffer{vertexBuffers[i].Buffer}, []int{0})
+	info.DeviceDriver.CmdSetViewport(cmd,
 		core1_0.Viewport{
 			X: 0, Y: 0,
 			MinDepth: 0, MaxDepth: 1,
 			Width:  float32(info.Width),
 			Height: float32(info.Height),
 		})
-	info.DeviceDriver.CmdSetScissor(buffers[0],
+	info.DeviceDriver.CmdSetScissor(cmd,
 		core1_0.Rect2D{
 			Offset: core1_0.Offset2D{0, 0},
 			Extent: core1_0.Extent2D{info.Width, info.Height},
 		})
 
-	info.DeviceDriver.CmdDraw(buffers[0], 3, 1, 0, 0)
-	info.DeviceDriver.CmdEndRenderPass(buffers[0])
-
-	_, err = info.DeviceDriver.EndCommandBuffer(buffers[0])
-	return err
+	info.DeviceDriver.CmdDraw(cmd, 3, 1, 0, 0)
+	return nil
 }
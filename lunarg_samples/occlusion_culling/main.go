@@ -0,0 +1,760 @@
+package main
+
+import (
+	"embed"
+	"encoding/binary"
+	"log"
+	"math"
+	"runtime/debug"
+	"time"
+
+	"github.com/loov/hrtime"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/vkngwrapper/core/v3"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/conditional"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queries"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/uniformring"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/upload"
+	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
+	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
+	vkngmath "github.com/vkngwrapper/math"
+)
+
+//go:embed shaders
+var fileSystem embed.FS
+
+func logDebug(msgType ext_debug_utils.DebugUtilsMessageTypeFlags, severity ext_debug_utils.DebugUtilsMessageSeverityFlags, data *ext_debug_utils.DebugUtilsMessengerCallbackData) bool {
+	log.Printf("[%s %s] - %s", severity, msgType, data.Message)
+
+	if (severity & ext_debug_utils.SeverityError) != 0 {
+		debug.PrintStack()
+	}
+
+	return false
+}
+
+/*
+VULKAN_SAMPLE_SHORT_DESCRIPTION
+Cull a field of cubes with occlusion queries from the previous frame
+*/
+/* This sample builds upon occlusion_query. A field of cubes sits behind   */
+/* a wall while the camera circles it. Every frame, each cube's bounding   */
+/* box is drawn with an occlusion query, with color and depth writes off.  */
+/* The next frame skips every cube whose box passed no samples. Results   */
+/* are read on the host without waiting, so the CPU never stalls on the    */
+/* GPU; an object is drawn if its newest available result says it is      */
+/* visible.                                                                */
+/*                                                                         */
+/* Where VK_EXT_conditional_rendering is available, every draw is issued   */
+/* and the GPU discards the culled ones itself, reading the previous       */
+/* frame's results from a buffer they are copied into.                     */
+
+const (
+	gridSize       = 8
+	objectCount    = gridSize * gridSize
+	framesInFlight = 2
+	runTime        = 5 * time.Second
+
+	swapchainUsage = core1_0.ImageUsageColorAttachment | core1_0.ImageUsageTransferSrc
+)
+
+type frame struct {
+	cmd            core1_0.CommandBuffer
+	fence          core1_0.Fence
+	imageAcquired  core1_0.Semaphore
+	renderFinished core1_0.Semaphore
+	submitted      bool
+}
+
+func main() {
+	info := &utils.SampleInfo{}
+	err := info.ProcessCommandLineArgs()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitWindowSize(500, 500)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitWindow()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	info.GlobalDriver, err = core.CreateDriverFromProcAddr(sdl.VulkanGetVkGetInstanceProcAddr())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitGlobalLayerProperties()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitInstanceExtensionNames()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitDeviceExtensionNames()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
+	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
+	debugOptions := info.DebugMessengerOptions(logDebug)
+
+	// Vulkan 1.1 lets InitDevice query the conditional rendering features
+	info.DesiredAPIVersion = common.Vulkan1_1
+	err = info.InitInstance("Occlusion Culling", debugOptions)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	debugLoader := ext_debug_utils.CreateExtensionDriverFromCoreDriver(info.InstanceDriver)
+	debugMessenger, _, err := debugLoader.CreateDebugUtilsMessenger(nil, debugOptions)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	/* VULKAN_KEY_START */
+	// Conditional rendering is used when it is available, and culling falls back to the
+	// host when it isn't
+	info.DeviceRequirements = utils.DeviceRequirements{
+		Extended: []utils.ExtendedFeatures{
+			{
+				Optional:  conditional.Features{ConditionalRendering: true},
+				Extension: conditional.ExtensionName,
+			},
+		},
+	}
+	/* VULKAN_KEY_END */
+
+	err = info.InitEnumerateDevice()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitSwapchainExtension()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitDevice()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var conditionalFeatures conditional.Features
+	useConditional := info.ExtendedFeaturesEnabled(&conditionalFeatures) && conditionalFeatures.ConditionalRendering
+	if useConditional {
+		log.Println("Culling with VK_EXT_conditional_rendering")
+	} else {
+		log.Println("VK_EXT_conditional_rendering is not available, culling on the host")
+	}
+
+	err = info.InitCommandPool()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// The sample's own command buffer is only used by WritePNG
+	err = info.InitCommandBuffer()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitDeviceQueue()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitSwapchain(swapchainUsage)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitDepthBuffer()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitRenderPass(true, true, khr_swapchain.ImageLayoutPresentSrc, core1_0.ImageLayoutUndefined)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	vertShaderBytes, err := fileSystem.ReadFile("shaders/vert.spv")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fragShaderBytes, err := fileSystem.ReadFile("shaders/frag.spv")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitShaders(vertShaderBytes, fragShaderBytes)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitFramebuffers(true)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// The cubes are their own bounding boxes. A real scene would query with a box much
+	// simpler than the object it stands in for.
+	cube := mesh.Deduplicate(utils.VBSolidFaceColorsData)
+	err = info.InitVertexBuffers(cube.Vertices, binary.Size(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitIndexBuffer(cube.Indices, len(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	/* Each draw's matrix is pushed into a ring buffer and selected with a dynamic offset */
+	uniforms, err := uniformring.New(info.DeviceDriver, info.MemoryProperties, info.GpuProps.Limits, core1_0.BufferUsageUniformBuffer, 256*1024)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	mvpLayout, err := blocklayout.TypeOf(blocklayout.Std140, &info.MVP)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	info.UniformData.BufferInfo = uniforms.BufferInfo(mvpLayout.Size)
+
+	err = initDescriptors(info)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitPipelineCache()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitPipeline(true, true)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	/* VULKAN_KEY_START */
+	// Bounding boxes are depth tested against the scene, but change nothing in the
+	// framebuffer
+	proxyCreateInfo := info.GraphicsPipelineCreateInfo(true, true)
+	proxyCreateInfo.DepthStencilState.DepthWriteEnable = false
+	proxyCreateInfo.ColorBlendState.Attachments[0].ColorWriteMask = 0
+	proxyPipelines, _, err := info.DeviceDriver.CreateGraphicsPipelines(&info.PipelineCache, nil, proxyCreateInfo)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	proxyPipeline := proxyPipelines[0]
+
+	// Each frame in flight queries into its own range of the pool, so a frame can reset its
+	// queries while the results of the one before are still being read
+	occlusion, err := queries.NewOcclusion(info.DeviceDriver, &info.EnabledFeatures, queries.OcclusionOptions{
+		Options: queries.Options{
+			Count:        framesInFlight * objectCount,
+			Width:        queries.Width32,
+			Availability: true,
+		},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var conditionalDriver *conditional.Driver
+	var predicates predicateBuffer
+	if useConditional {
+		conditionalDriver, err = conditional.New(info.DeviceDriver)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		predicates, err = newPredicateBuffer(info, framesInFlight*objectCount*occlusion.Stride())
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	/* VULKAN_KEY_END */
+
+	cmds, _, err := info.DeviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
+		CommandPool:        info.CmdPool,
+		Level:              core1_0.CommandBufferLevelPrimary,
+		CommandBufferCount: framesInFlight,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	frames := make([]*frame, framesInFlight)
+	for frameIndex := range frames {
+		f := &frame{cmd: cmds[frameIndex]}
+		frames[frameIndex] = f
+
+		f.fence, _, err = info.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		f.imageAcquired, _, err = info.DeviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		f.renderFinished, _, err = info.DeviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	info.Projection.SetPerspective(math.Pi/4.0, 1, 0.1, 100)
+
+	// Until a cube's first result comes back, assume it can be seen
+	visible := make([]bool, objectCount)
+	for objectIndex := range visible {
+		visible[objectIndex] = true
+	}
+
+	/* VULKAN_KEY_START */
+	start := hrtime.Now()
+	var frameCount, totalIssued, totalCulled int
+	lastReport := start
+	for frameNumber := 0; hrtime.Since(start) < runTime; frameNumber++ {
+		sdl.PollEvent()
+
+		info.Printf.BeginFrame(uint64(frameNumber))
+		slot := frameNumber % framesInFlight
+		previous := (slot + framesInFlight - 1) % framesInFlight
+		f := frames[slot]
+		havePrevious := frames[previous].submitted
+
+		if f.submitted {
+			err = waitForFrame(info, f)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			// The ring is told about the fence before it's reset for this frame's submit
+			uniforms.Retire(f.fence)
+		}
+
+		// The previous frame may still be running; Poll only returns the results that are
+		// ready, and the rest keep their older value
+		if havePrevious {
+			results, err := occlusion.Poll(previous*objectCount, objectCount)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			for objectIndex, result := range results {
+				if result.Available {
+					visible[objectIndex] = result.Value.Visible()
+				}
+			}
+		}
+
+		var res common.VkResult
+		info.CurrentBuffer, res, err = info.SwapchainExtension.AcquireNextImage(info.Swapchain, common.NoTimeout, &f.imageAcquired, nil)
+		if res == khr_swapchain.VKErrorOutOfDate {
+			// No image was acquired, so skip the frame. Its fence is still signaled, since
+			// it's only reset right before a submit.
+			err = info.RecreateSwapchain(swapchainUsage, true)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			continue
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		// VK_SUBOPTIMAL_KHR still acquired an image; the swapchain is recreated after
+		// presenting it
+		suboptimal := res == khr_swapchain.VKSuboptimal
+
+		updateCamera(info, hrtime.Since(start))
+
+		_, err = info.DeviceDriver.BeginCommandBuffer(f.cmd, core1_0.CommandBufferBeginInfo{
+			Flags: core1_0.CommandBufferUsageOneTimeSubmit,
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		occlusion.Reset(f.cmd, slot*objectCount, objectCount)
+
+		err = info.DeviceDriver.CmdBeginRenderPass(f.cmd, core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
+			RenderPass:  info.RenderPass,
+			Framebuffer: info.Framebuffer[info.CurrentBuffer],
+			RenderArea: core1_0.Rect2D{
+				Offset: core1_0.Offset2D{0, 0},
+				Extent: core1_0.Extent2D{info.Width, info.Height},
+			},
+			ClearValues: info.InitClearColorAndDepth(),
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		info.DeviceDriver.CmdBindVertexBuffers(f.cmd, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+		info.DeviceDriver.CmdBindIndexBuffer(f.cmd, info.IndexBuffer.Buf, 0, info.IndexBuffer.Type)
+		info.DeviceDriver.CmdSetViewport(f.cmd, core1_0.Viewport{
+			Width:    float32(info.Width),
+			Height:   float32(info.Height),
+			MinDepth: 0,
+			MaxDepth: 1,
+		})
+		info.DeviceDriver.CmdSetScissor(f.cmd, core1_0.Rect2D{
+			Extent: core1_0.Extent2D{info.Width, info.Height},
+		})
+
+		info.DeviceDriver.CmdBindPipeline(f.cmd, core1_0.PipelineBindPointGraphics, info.Pipeline)
+
+		// The wall is the occluder, and is always drawn
+		err = drawCube(info, f.cmd, uniforms, wallTransform())
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		issued, culled := 0, 0
+		for objectIndex := 0; objectIndex < objectCount; objectIndex++ {
+			if !visible[objectIndex] {
+				culled++
+			}
+
+			if conditionalDriver != nil && havePrevious {
+				// The draw is always recorded, and the GPU discards it if the previous
+				// frame's query passed no samples
+				issued++
+				conditionalDriver.CmdBeginConditionalRendering(f.cmd, predicates.buffer, (previous*objectCount+objectIndex)*occlusion.Stride(), false)
+				err = drawCube(info, f.cmd, uniforms, objectTransform(objectIndex))
+				conditionalDriver.CmdEndConditionalRendering(f.cmd)
+			} else if visible[objectIndex] {
+				issued++
+				err = drawCube(info, f.cmd, uniforms, objectTransform(objectIndex))
+			}
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		// Every cube's bounding box is tested, culled or not, so that hidden cubes are
+		// noticed when they come back into view
+		info.DeviceDriver.CmdBindPipeline(f.cmd, core1_0.PipelineBindPointGraphics, proxyPipeline)
+		for objectIndex := 0; objectIndex < objectCount; objectIndex++ {
+			occlusion.Begin(f.cmd, slot*objectCount+objectIndex)
+			err = drawCube(info, f.cmd, uniforms, objectTransform(objectIndex))
+			occlusion.End(f.cmd, slot*objectCount+objectIndex)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		info.DeviceDriver.CmdEndRenderPass(f.cmd)
+
+		if conditionalDriver != nil {
+			err = predicates.copyResults(info, f.cmd, occlusion, slot)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		_, err = info.DeviceDriver.EndCommandBuffer(f.cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		_, err = info.DeviceDriver.ResetFences(f.fence)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		_, err = info.SyncGraphicsQueue.Submit(&f.fence,
+			core1_0.SubmitInfo{
+				WaitSemaphores:   []core1_0.Semaphore{f.imageAcquired},
+				WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
+				CommandBuffers:   []core1_0.CommandBuffer{f.cmd},
+				SignalSemaphores: []core1_0.Semaphore{f.renderFinished},
+			})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		uniforms.EndFrame(f.fence)
+		info.Printf.TrackCommandBuffers(uint64(frameNumber), f.cmd)
+		f.submitted = true
+
+		res, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
+			WaitSemaphores: []core1_0.Semaphore{f.renderFinished},
+			Swapchains:     []khr_swapchain.Swapchain{info.Swapchain},
+			ImageIndices:   []int{info.CurrentBuffer},
+		})
+		if suboptimal || res == khr_swapchain.VKErrorOutOfDate || res == khr_swapchain.VKSuboptimal {
+			err = info.RecreateSwapchain(swapchainUsage, true)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		frameCount++
+		totalIssued += issued
+		totalCulled += culled
+		if hrtime.Since(lastReport) >= time.Second {
+			log.Printf("Frame %d: %d draws issued, %d of %d cubes culled\n", frameNumber, issued, culled, objectCount)
+			lastReport = hrtime.Now()
+		}
+	}
+
+	log.Printf("%d frames: %.1f draws issued and %.1f cubes culled per frame\n", frameCount,
+		float64(totalIssued)/float64(frameCount), float64(totalCulled)/float64(frameCount))
+	/* VULKAN_KEY_END */
+
+	_, err = info.DeviceDriver.DeviceWaitIdle()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if info.SaveImages {
+		err = info.WritePNG("occlusion_culling")
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	for _, f := range frames {
+		info.DeviceDriver.DestroyFence(f.fence, nil)
+		info.DeviceDriver.DestroySemaphore(f.imageAcquired, nil)
+		info.DeviceDriver.DestroySemaphore(f.renderFinished, nil)
+	}
+	info.DeviceDriver.FreeCommandBuffers(cmds...)
+	if conditionalDriver != nil {
+		predicates.destroy(info)
+	}
+	occlusion.Destroy()
+	info.DeviceDriver.DestroyPipeline(proxyPipeline, nil)
+	info.DestroyPipeline()
+	info.DestroyPipelineCache()
+	info.DestroyDescriptorPool()
+	info.DestroyVertexBuffer()
+	info.DestroyIndexBuffer()
+	info.DestroyFramebuffers()
+	info.DestroyShaders()
+	info.DestroyRenderpass()
+	info.DestroyDescriptorAndPipelineLayouts()
+	uniforms.Destroy()
+	info.DestroyDepthBuffer()
+	info.DestroySwapchain()
+	info.DestroyCommandBuffer()
+	info.DestroyCommandPool()
+
+	err = info.DestroyDevice()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	info.SurfaceDriver.DestroySurface(info.Surface, nil)
+	debugLoader.DestroyDebugUtilsMessenger(debugMessenger, nil)
+	info.DestroyInstance()
+	info.Window.Destroy()
+}
+
+func initDescriptors(info *utils.SampleInfo) error {
+	descLayout, _, err := info.DeviceDriver.CreateDescriptorSetLayout(nil, core1_0.DescriptorSetLayoutCreateInfo{
+		Bindings: []core1_0.DescriptorSetLayoutBinding{
+			{
+				Binding:         0,
+				DescriptorType:  core1_0.DescriptorTypeUniformBufferDynamic,
+				DescriptorCount: 1,
+				StageFlags:      core1_0.StageVertex,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	info.DescLayout = []core1_0.DescriptorSetLayout{descLayout}
+
+	info.PipelineLayout, _, err = info.DeviceDriver.CreatePipelineLayout(nil, core1_0.PipelineLayoutCreateInfo{
+		SetLayouts: info.DescLayout,
+	})
+	if err != nil {
+		return err
+	}
+
+	info.DescPool, _, err = info.DeviceDriver.CreateDescriptorPool(nil, core1_0.DescriptorPoolCreateInfo{
+		MaxSets: 1,
+		PoolSizes: []core1_0.DescriptorPoolSize{
+			{
+				Type:            core1_0.DescriptorTypeUniformBufferDynamic,
+				DescriptorCount: 1,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	info.DescSet, _, err = info.DeviceDriver.AllocateDescriptorSets(core1_0.DescriptorSetAllocateInfo{
+		DescriptorPool: info.DescPool,
+		SetLayouts:     info.DescLayout,
+	})
+	if err != nil {
+		return err
+	}
+
+	return info.DeviceDriver.UpdateDescriptorSets([]core1_0.WriteDescriptorSet{
+		{
+			DstSet:          info.DescSet[0],
+			DstBinding:      0,
+			DstArrayElement: 0,
+			DescriptorType:  core1_0.DescriptorTypeUniformBufferDynamic,
+
+			BufferInfo: []core1_0.DescriptorBufferInfo{info.UniformData.BufferInfo},
+		},
+	}, nil)
+}
+
+// waitForFrame waits for f's last submit to finish. The fence is left signaled, and is
+// reset right before f is submitted again.
+func waitForFrame(info *utils.SampleInfo, f *frame) error {
+	for {
+		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, f.fence)
+		info.Breadcrumbs.Check(res)
+		if err != nil {
+			return err
+		}
+
+		if res != core1_0.VKTimeout {
+			return nil
+		}
+	}
+}
+
+// updateCamera circles the camera around the field, so cubes move in and out of the
+// wall's shadow
+func updateCamera(info *utils.SampleInfo, elapsed time.Duration) {
+	angle := elapsed.Seconds() * 0.6
+	info.View.SetLookAt(
+		&vkngmath.Vec3[float32]{X: float32(8 * math.Sin(angle)), Y: 2, Z: float32(-14 + 4*math.Cos(angle))},
+		&vkngmath.Vec3[float32]{X: 0, Y: 0, Z: 6},
+		&vkngmath.Vec3[float32]{X: 0, Y: -1, Z: 0},
+	)
+}
+
+func wallTransform() vkngmath.Mat4x4[float32] {
+	var model vkngmath.Mat4x4[float32]
+	model.SetScale(5, 3, 0.2)
+	model.Translate(0, 0, -4)
+	return model
+}
+
+func objectTransform(objectIndex int) vkngmath.Mat4x4[float32] {
+	column := objectIndex % gridSize
+	row := objectIndex / gridSize
+
+	var model vkngmath.Mat4x4[float32]
+	model.SetScale(0.5, 0.5, 0.5)
+	model.Translate(float32(column)*2-gridSize+1, 0, float32(row)*2)
+	return model
+}
+
+// drawCube pushes the cube's matrix into the ring and draws it with the bound pipeline
+func drawCube(info *utils.SampleInfo, cmd core1_0.CommandBuffer, uniforms *uniformring.Ring, model vkngmath.Mat4x4[float32]) error {
+	var mvp vkngmath.Mat4x4[float32]
+	mvp.SetApplyTransform(&model, &info.View)
+	mvp.ApplyTransform(&info.Projection)
+
+	allocation, err := uniforms.Push(mvp)
+	if err != nil {
+		return err
+	}
+
+	info.DeviceDriver.CmdBindDescriptorSets(cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, []int{allocation.Offset})
+	info.DeviceDriver.CmdDrawIndexed(cmd, info.IndexBuffer.Count, 1, 0, 0, 0)
+	return nil
+}
+
+// predicateBuffer holds the query results that conditional rendering reads, in one range
+// per frame in flight laid out like the query pool
+type predicateBuffer struct {
+	buffer core1_0.Buffer
+	memory core1_0.DeviceMemory
+}
+
+func newPredicateBuffer(info *utils.SampleInfo, size int) (predicateBuffer, error) {
+	var predicates predicateBuffer
+	var err error
+
+	predicates.buffer, _, err = info.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+		Size:        size,
+		Usage:       conditional.BufferUsageConditionalRendering | core1_0.BufferUsageTransferDst,
+		SharingMode: core1_0.SharingModeExclusive,
+	})
+	if err != nil {
+		return predicates, err
+	}
+
+	memReqs := info.DeviceDriver.GetBufferMemoryRequirements(predicates.buffer)
+	memoryTypeIndex, err := info.MemoryTypeFromProperties(memReqs.MemoryTypeBits, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+	if err != nil {
+		return predicates, err
+	}
+
+	predicates.memory, _, err = info.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+		AllocationSize:  memReqs.Size,
+		MemoryTypeIndex: memoryTypeIndex,
+	})
+	if err != nil {
+		return predicates, err
+	}
+
+	_, err = info.DeviceDriver.BindBufferMemory(predicates.buffer, predicates.memory, 0)
+	if err != nil {
+		return predicates, err
+	}
+
+	// Start with everything visible
+	initial := make([]uint32, size/4)
+	for index := range initial {
+		initial[index] = 1
+	}
+	return predicates, upload.Write(info.DeviceDriver, predicates.memory, 0, initial)
+}
+
+// copyResults records a copy of slot's query results into its range of the buffer, for
+// the next frame's conditional rendering to read
+func (p predicateBuffer) copyResults(info *utils.SampleInfo, cmd core1_0.CommandBuffer, occlusion *queries.Pool[queries.Occlusion], slot int) error {
+	// The frame before this one may still be reading this range
+	err := info.DeviceDriver.CmdPipelineBarrier(cmd, conditional.PipelineStageConditionalRendering, core1_0.PipelineStageTransfer, 0, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// Waiting here holds up the GPU, not the host, until the queries are done
+	occlusion.Copy(cmd, slot*objectCount, objectCount, p.buffer, slot*objectCount*occlusion.Stride(), true)
+
+	return info.DeviceDriver.CmdPipelineBarrier(cmd, core1_0.PipelineStageTransfer, conditional.PipelineStageConditionalRendering, 0,
+		[]core1_0.MemoryBarrier{
+			{
+				SrcAccessMask: core1_0.AccessTransferWrite,
+				DstAccessMask: conditional.AccessConditionalRenderingRead,
+			},
+		}, nil, nil)
+}
+
+func (p predicateBuffer) destroy(info *utils.SampleInfo) {
+	info.DeviceDriver.DestroyBuffer(p.buffer, nil)
+	info.DeviceDriver.FreeMemory(p.memory, nil)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"embed"
 	"encoding/binary"
 	"fmt"
@@ -11,6 +10,7 @@ import (
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queries"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 	"log"
@@ -88,11 +88,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Occlusion Query", debugOptions)
 	if err != nil {
@@ -185,7 +181,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBSolidFaceColorsData, binary.Size(utils.VBSolidFaceColorsData), int(unsafe.Sizeof(utils.Vertex{})), false)
+	err = info.InitVertexBuffers(utils.VBSolidFaceColorsData, binary.Size(utils.VBSolidFaceColorsData))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -228,9 +224,18 @@ func main() {
 		log.Fatalln(err)
 	}
 
+	// The sample's device is created without occlusionQueryPrecise, so these are
+	// binary queries: only whether the count is zero is guaranteed
+	queryPool, err := queries.NewOcclusion(info.DeviceDriver, nil, queries.OcclusionOptions{
+		Options: queries.Options{Count: 2},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	/* Allocate a uniform buffer that will take query results. */
 	queryResultBuf, _, err := info.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
-		Size:        4 * int(unsafe.Sizeof(uint64(0))),
+		Size:        queryPool.Count() * queryPool.Stride(),
 		Usage:       core1_0.BufferUsageUniformBuffer | core1_0.BufferUsageTransferDst,
 		SharingMode: core1_0.SharingModeExclusive,
 	})
@@ -258,17 +263,9 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	queryPool, _, err := info.DeviceDriver.CreateQueryPool(nil, core1_0.QueryPoolCreateInfo{
-		QueryType:  core1_0.QueryTypeOcclusion,
-		QueryCount: 2,
-	})
-	if err != nil {
-		log.Fatalln(err)
-	}
+	queryPool.ResetAll(info.Cmd)
 
-	info.DeviceDriver.CmdResetQueryPool(info.Cmd, queryPool, 0, 2)
-
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
 		RenderPass:  info.RenderPass,
 		Framebuffer: info.Framebuffer[info.CurrentBuffer],
 		RenderArea: core1_0.Rect2D{
@@ -300,14 +297,14 @@ func main() {
 			Extent: core1_0.Extent2D{info.Width, info.Height},
 		})
 
-	info.DeviceDriver.CmdBeginQuery(info.Cmd, queryPool, 0, 0)
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
-	info.DeviceDriver.CmdEndQuery(info.Cmd, queryPool, 0)
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	queryPool.Begin(info.Cmd, 0)
+	info.Draw(info.Cmd, "Cube", 36, 1, 0, 0)
+	queryPool.End(info.Cmd, 0)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	
-	info.DeviceDriver.CmdBeginQuery(info.Cmd, queryPool, 1, 0)
-	info.DeviceDriver.CmdEndQuery(info.Cmd, queryPool, 1)
-	info.DeviceDriver.CmdCopyQueryPoolResults(info.Cmd, queryPool, 0, 2, queryResultBuf, 0, int(unsafe.Sizeof(uint64(0))), core1_0.QueryResult64Bit|core1_0.QueryResultWait)
+	queryPool.Begin(info.Cmd, 1)
+	queryPool.End(info.Cmd, 1)
+	queryPool.Copy(info.Cmd, 0, 2, queryResultBuf, 0, true)
 
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
@@ -320,7 +317,7 @@ func main() {
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
 			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
@@ -329,39 +326,32 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
 
 	_, err = info.DeviceDriver.DeviceWaitIdle()
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	resultsData := make([]byte, 32)
-	_, err = info.DeviceDriver.GetQueryPoolResults(queryPool, 0, 2, resultsData, 8, core1_0.QueryResult64Bit|core1_0.QueryResultWait)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	resultReader := bytes.NewBuffer(resultsData)
-	samplesPassed := []uint64{0, 0, 0, 0}
-	err = binary.Read(resultReader, common.ByteOrder, samplesPassed)
+	samplesPassed, err := queryPool.Wait(0, 2)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	fmt.Println("vkGetQueryPoolResults data")
-	fmt.Printf("samplesPassed[0] = %d\n", samplesPassed[0])
-	fmt.Printf("samplesPassed[1] = %d\n", samplesPassed[1])
+	fmt.Printf("samplesPassed[0] = %d\n", samplesPassed[0].Value.SamplesPassed)
+	fmt.Printf("samplesPassed[1] = %d\n", samplesPassed[1].Value.SamplesPassed)
 
 	/* Read back query result from buffer */
 	samplesPassedPtr, _, err := info.DeviceDriver.MapMemory(queryResultMem, 0, memReqs.Size, 0)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	samplesPassedBuffer := ([]uint64)(unsafe.Slice((*uint64)(samplesPassedPtr), 4))
+	samplesPassedBuffer := queryPool.Decode(unsafe.Slice((*byte)(samplesPassedPtr), queryPool.Count()*queryPool.Stride()))
 
 	fmt.Println("vkCmdCopyQueryPoolResults  data")
-	fmt.Printf("samplesPassed[0] = %d\n", samplesPassedBuffer[0])
-	fmt.Printf("samplesPassed[1] = %d\n", samplesPassedBuffer[1])
+	fmt.Printf("samplesPassed[0] = %d\n", samplesPassedBuffer[0].Value.SamplesPassed)
+	fmt.Printf("samplesPassed[1] = %d\n", samplesPassedBuffer[1].Value.SamplesPassed)
 
 	info.DeviceDriver.UnmapMemory(queryResultMem)
 
@@ -370,6 +360,7 @@ func main() {
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -379,12 +370,12 @@ func main() {
 		}
 	}
 
-	_, err = info.DeviceDriver.QueueWaitIdle(info.PresentQueue)
+	_, err = info.SyncPresentQueue.WaitIdle()
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, khr_swapchain.PresentInfo{
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
 		Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
 		ImageIndices: []int{info.CurrentBuffer},
 	})
@@ -408,7 +399,7 @@ func main() {
 	info.DeviceDriver.DestroyBuffer(queryResultBuf, nil)
 	info.DeviceDriver.FreeMemory(queryResultMem, nil)
 	info.DeviceDriver.DestroySemaphore(imageAcquiredSemaphore, nil)
-	info.DeviceDriver.DestroyQueryPool(queryPool, nil)
+	queryPool.Destroy()
 	info.DeviceDriver.DestroyFence(drawFence, nil)
 	info.DestroyPipeline()
 	info.DestroyPipelineCache()
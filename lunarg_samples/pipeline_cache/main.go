@@ -1,25 +1,23 @@
 package main
 
 import (
-	"bytes"
 	"embed"
 	"encoding/binary"
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/vkngwrapper/core/v3"
-	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/pipelinecache"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/pipelinecompiler"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
-	"io/ioutil"
 	"log"
-	"os"
 	"runtime/debug"
+	"sync"
 	"time"
-	"unsafe"
 )
 
 //go:embed shaders images
@@ -85,11 +83,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Pipeline Cache", debugOptions)
 	if err != nil {
@@ -191,7 +185,13 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBTextureData, binary.Size(utils.VBTextureData), int(unsafe.Sizeof(utils.VertexUV{})), true)
+	cube := mesh.Deduplicate(utils.VBTextureData)
+	err = info.InitVertexBuffers(cube.Vertices, binary.Size(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitIndexBuffer(cube.Indices, len(cube.Vertices))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -208,120 +208,17 @@ func main() {
 
 	/* VULKAN_KEY_START */
 
-	// Check disk for existing cache data
-	fileName := "pipeline_cache_data.bin"
-	pipelineData, fileReadErr := ioutil.ReadFile(fileName)
-	if os.IsNotExist(fileReadErr) {
-		fmt.Println("  Pipeline cache miss!")
-	} else if fileReadErr != nil {
-		log.Fatalln(fileReadErr)
-	}
-
-	if pipelineData != nil {
-		//
-		// Check for cache validity
-		//
-		// TODO: Update this as the spec evolves. The fields are not defined by the header.
-		//
-		// The code below supports SDK 0.10 Vulkan spec, which contains the following table:
-		//
-		// Offset	 Size            Meaning
-		// ------    ------------    ------------------------------------------------------------------
-		//      0               4    a device ID equal to VkPhysicalDeviceProperties::DeviceId written
-		//                           as a stream of bytes, with the least significant byte first
-		//
-		//      4    VK_UUID_SIZE    a pipeline cache ID equal to VkPhysicalDeviceProperties::pipelineCacheUUID
-		//
-		//
-		// The code must be updated for latest Vulkan spec, which contains the following table:
-		//
-		// Offset	 Size            Meaning
-		// ------    ------------    ------------------------------------------------------------------
-		//      0               4    length in bytes of the entire pipeline cache header written as a
-		//                           stream of bytes, with the least significant byte first
-		//      4               4    a VkPipelineCacheHeaderVersion value written as a stream of bytes,
-		//                           with the least significant byte first
-		//      8               4    a vendor ID equal to VkPhysicalDeviceProperties::vendorID written
-		//                           as a stream of bytes, with the least significant byte first
-		//     12               4    a device ID equal to VkPhysicalDeviceProperties::deviceID written
-		//                           as a stream of bytes, with the least significant byte first
-		//     16    VK_UUID_SIZE    a pipeline cache ID equal to VkPhysicalDeviceProperties::pipelineCacheUUID
-		//
-
-		var headerLength, vendorID, deviceID uint32
-		var cacheHeaderVersion core1_0.PipelineCacheHeaderVersion
-		pipelineReader := bytes.NewReader(pipelineData)
-
-		err = binary.Read(pipelineReader, common.ByteOrder, &headerLength)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		err = binary.Read(pipelineReader, common.ByteOrder, &cacheHeaderVersion)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		err = binary.Read(pipelineReader, common.ByteOrder, &vendorID)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		err = binary.Read(pipelineReader, common.ByteOrder, &deviceID)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		var cacheUUID uuid.UUID
-		err = binary.Read(pipelineReader, common.ByteOrder, &cacheUUID)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		var badCache bool
-
-		if headerLength <= 0 {
-			badCache = true
-			fmt.Printf("  Bad header length in %s.\n", fileName)
-			fmt.Printf("    Cache contains: 0x%x", headerLength)
-		}
-
-		if cacheHeaderVersion != core1_0.PipelineCacheHeaderVersionOne {
-			badCache = true
-			fmt.Printf("  Unsupported cache header version in %s.\n", fileName)
-			fmt.Printf("    Cache contains: 0x%x", cacheHeaderVersion)
-		}
-
-		if vendorID != info.GpuProps.VendorID {
-			badCache = true
-			fmt.Printf("  Vendor ID mismatch in %s\n", fileName)
-			fmt.Printf("    Cache contains: 0x%x", vendorID)
-			fmt.Printf("    Driver expects: 0x%x", info.GpuProps.VendorID)
-		}
-
-		if deviceID != info.GpuProps.DeviceID {
-			badCache = true
-			fmt.Printf("  Device ID mismatch in %s.\n", fileName)
-			fmt.Printf("    Cache contains: 0x%x", deviceID)
-			fmt.Printf("    Driver Expects: 0x%x", info.GpuProps.DeviceID)
-		}
-
-		if cacheUUID != info.GpuProps.PipelineCacheUUID {
-			badCache = true
-			fmt.Printf("  UUID mismatch in %s.\n", fileName)
-			fmt.Printf("    Cache contains: %s\n", cacheUUID.String())
-			fmt.Printf("    Driver expects: %s\n", info.GpuProps.PipelineCacheUUID.String())
-		}
-
-		if badCache {
-			// Don't submit initial cache data if any version info is incorrect
-			pipelineData = nil
+	// Check disk for existing cache data. The store discards any cache file whose
+	// header doesn't match this device and driver rather than handing it to
+	// vkCreatePipelineCache.
+	cacheStore := pipelinecache.NewStore("pipeline_cache_data", info.DeviceDriver, info.GpuProps)
+	pipelineData, err := cacheStore.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-			// And clear out the old cache file for use in next run
-			fmt.Printf("  Deleting cache entry %s to repopulate\n", fileName)
-			// not important if this fails
-			_ = os.Remove(fileName)
-		}
+	if pipelineData == nil {
+		fmt.Println("  Pipeline cache miss!")
 	}
 
 	// Feed the initial cache data into cache creation
@@ -332,25 +229,48 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	// Time (roughly) taken to create the graphics pipeline
-	start := hrtime.Now()
-	err = info.InitPipeline(true, true)
+	// A real renderer has dozens of pipeline variants, which is where the cache and
+	// compiling in parallel pay off.  Build a few from the sample pipeline; the first
+	// one is the pipeline InitPipeline would have created.
+	var requests []pipelinecompiler.Request
+	for _, cullMode := range []core1_0.CullModeFlags{core1_0.CullModeBack, core1_0.CullModeFront, 0} {
+		for _, frontFace := range []core1_0.FrontFace{core1_0.FrontFaceClockwise, core1_0.FrontFaceCounterClockwise} {
+			for _, depthCompare := range []core1_0.CompareOp{core1_0.CompareOpLessOrEqual, core1_0.CompareOpLess} {
+				createInfo := info.GraphicsPipelineCreateInfo(true, true)
+				createInfo.RasterizationState.CullMode = cullMode
+				createInfo.RasterizationState.FrontFace = frontFace
+				createInfo.DepthStencilState.DepthCompareOp = depthCompare
+
+				requests = append(requests, pipelinecompiler.Request{
+					Name:     fmt.Sprintf("Pipeline Variant %d", len(requests)),
+					Graphics: &createInfo,
+				})
+			}
+		}
+	}
+
+	// Time taken to create the graphics pipelines, with the cache and without
+	compiler := pipelinecompiler.New(info.DeviceDriver, &info.PipelineCache, 0)
+	compiler.Names = info.DebugNames
+	variants, report, err := compiler.Warmup(requests, true)
+	compiler.Close()
 	if err != nil {
 		log.Fatalln(err)
 	}
-	elapsed := hrtime.Now() - start
-	fmt.Printf("  vkCreateGraphicsPipeline: %s\n", elapsed)
+	fmt.Printf("  vkCreateGraphicsPipelines: %s\n", report)
+
+	info.Pipeline = variants[0]
 
 	// Begin standard draw stuff
 	err = info.InitPresentableImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
 	clearValues := info.InitClearColorAndDepth()
 	rpBegin := info.InitRenderPassBeginInfo()
 	rpBegin.ClearValues = clearValues
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, rpBegin)
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, rpBegin)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -358,10 +278,11 @@ func main() {
 	info.DeviceDriver.CmdBindPipeline(info.Cmd, core1_0.PipelineBindPointGraphics, info.Pipeline)
 	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet, nil)
 	info.DeviceDriver.CmdBindVertexBuffers(info.Cmd, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+	info.DeviceDriver.CmdBindIndexBuffer(info.Cmd, info.IndexBuffer.Buf, 0, info.IndexBuffer.Type)
 	info.InitViewports()
 	info.InitScissors()
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.DrawIndexed(info.Cmd, "Cube", info.IndexBuffer.Count, 1, 0, 0, 0)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
 		log.Fatalln(err)
@@ -374,10 +295,11 @@ func main() {
 	submitInfo := info.InitSubmitInfo(core1_0.PipelineStageColorAttachmentOutput)
 
 	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence, *submitInfo)
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence, *submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
 
 	/* Now present the image in the window */
 	present := info.InitPresentInfo()
@@ -385,6 +307,7 @@ func main() {
 	/* Make sure command buffer is finished before presenting */
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -394,7 +317,7 @@ func main() {
 		}
 	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, present)
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, present)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -413,33 +336,48 @@ func main() {
 
 	// End standard draw stuff
 
-	// TODO: Create another pipeline cache, preferably different from the first
-	// one and merge it here.  Then store the merged one.
-
-	// Store away the cache that we've populated.  This could conceivably happen
-	// earlier, depends on when the pipeline cache stops being populated
-	// internally.
-
-	endCacheData, _, err := info.DeviceDriver.GetPipelineCacheData(info.PipelineCache)
+	// Pipelines compiled on other goroutines would each use their own cache, which
+	// is merged back into the main one when they finish.  This second cache is empty
+	// but demonstrates the merge.
+	workerCache, _, err := info.DeviceDriver.CreatePipelineCache(nil, core1_0.PipelineCacheCreateInfo{})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	err = os.WriteFile(fileName, endCacheData, 0666)
+	var mergeWait sync.WaitGroup
+	var mergeErr error
+	mergeWait.Add(1)
+	go func() {
+		defer mergeWait.Done()
+		mergeErr = cacheStore.Merge(info.PipelineCache, workerCache)
+	}()
+	mergeWait.Wait()
+	if mergeErr != nil {
+		log.Fatalln(mergeErr)
+	}
+	info.DeviceDriver.DestroyPipelineCache(workerCache, nil)
+
+	// Store away the cache that we've populated.  This could conceivably happen
+	// earlier, depends on when the pipeline cache stops being populated
+	// internally.
+	err = cacheStore.Save(info.PipelineCache)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	fmt.Printf("  cacheData written to %s\n", fileName)
+	fmt.Printf("  cacheData written to %s\n", cacheStore.Path())
 
 	/* VULKAN_KEY_END */
 
 	info.DeviceDriver.DestroyFence(drawFence, nil)
 	info.DeviceDriver.DestroySemaphore(info.ImageAcquiredSemaphore, nil)
-	info.DestroyPipeline()
+	for _, variant := range variants {
+		info.DeviceDriver.DestroyPipeline(variant, nil)
+	}
 	info.DestroyPipelineCache()
 	info.DestroyTextures()
 	info.DestroyDescriptorPool()
 	info.DestroyVertexBuffer()
+	info.DestroyIndexBuffer()
 	info.DestroyFramebuffers()
 	info.DestroyShaders()
 	info.DestroyRenderpass()
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"embed"
 	"encoding/binary"
 	"log"
@@ -12,9 +11,10 @@ import (
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/vkngwrapper/core/v3"
-	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/upload"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 )
@@ -80,11 +80,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Simple Push Constants", debugOptions)
 	if err != nil {
@@ -172,7 +168,13 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBTextureData, binary.Size(utils.VBTextureData), int(unsafe.Sizeof(utils.VertexUV{})), true)
+	cube := mesh.Deduplicate(utils.VBTextureData)
+	err = info.InitVertexBuffers(cube.Vertices, binary.Size(cube.Vertices))
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = info.InitIndexBuffer(cube.Indices, len(cube.Vertices))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -264,13 +266,13 @@ func main() {
 		log.Fatalln("Too many push constants")
 	}
 
-	pushWriter := bytes.NewBuffer(make([]byte, 0, pushConstantsSize))
-	err = binary.Write(pushWriter, common.ByteOrder, pushConstants)
+	pushData := make([]byte, pushConstantsSize)
+	_, err = upload.CopyValue(pushData, pushConstants)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	info.DeviceDriver.CmdPushConstants(info.Cmd, info.PipelineLayout, core1_0.StageFragment, 0, pushWriter.Bytes())
+	info.DeviceDriver.CmdPushConstants(info.Cmd, info.PipelineLayout, core1_0.StageFragment, 0, pushData)
 
 	/* VULKAN_KEY_END */
 
@@ -285,7 +287,7 @@ func main() {
 	}
 
 	err = info.InitPresentableImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
@@ -293,7 +295,7 @@ func main() {
 	rpBegin := info.InitRenderPassBeginInfo()
 	rpBegin.ClearValues = clearValues
 
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, rpBegin)
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, rpBegin)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -301,10 +303,11 @@ func main() {
 	info.DeviceDriver.CmdBindPipeline(info.Cmd, core1_0.PipelineBindPointGraphics, info.Pipeline)
 	info.DeviceDriver.CmdBindDescriptorSets(info.Cmd, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, descriptorSets, nil)
 	info.DeviceDriver.CmdBindVertexBuffers(info.Cmd, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+	info.DeviceDriver.CmdBindIndexBuffer(info.Cmd, info.IndexBuffer.Buf, 0, info.IndexBuffer.Type)
 	info.InitViewports()
 	info.InitScissors()
-	info.DeviceDriver.CmdDraw(info.Cmd, 36, 1, 0, 0)
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.DrawIndexed(info.Cmd, "Cube", info.IndexBuffer.Count, 1, 0, 0, 0)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
 		log.Fatalln(err)
@@ -317,14 +320,16 @@ func main() {
 
 	submitInfo := info.InitSubmitInfo(core1_0.PipelineStageColorAttachmentOutput)
 
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence, *submitInfo)
+	_, err = info.SyncGraphicsQueue.Submit(&drawFence, *submitInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	info.Breadcrumbs.Submitted(info.GraphicsQueue, drawFence, info.Cmd)
 
 	presentInfo := info.InitPresentInfo()
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -334,7 +339,7 @@ func main() {
 		}
 	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, presentInfo)
+	_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, presentInfo)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -358,6 +363,7 @@ func main() {
 
 	info.DeviceDriver.DestroyDescriptorPool(descriptorPool, nil)
 	info.DestroyVertexBuffer()
+	info.DestroyIndexBuffer()
 	info.DestroyFramebuffers()
 	info.DestroyShaders()
 	info.DestroyRenderpass()
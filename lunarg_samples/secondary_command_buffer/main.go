@@ -1,20 +1,22 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/binary"
+	"fmt"
 	"github.com/loov/hrtime"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/vkngwrapper/core/v3"
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/secondarycache"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 	"log"
 	"runtime/debug"
 	"time"
-	"unsafe"
 )
 
 //go:embed shaders images
@@ -74,11 +76,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Secondary Command Buffers", debugOptions)
 	if err != nil {
@@ -171,7 +169,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.InitVertexBuffers(utils.VBTextureData, binary.Size(utils.VBTextureData), int(unsafe.Sizeof(utils.VertexUV{})), true)
+	err = info.InitVertexBuffers(utils.VBTextureData, binary.Size(utils.VBTextureData))
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -270,34 +268,23 @@ func main() {
 
 	/* VULKAN_KEY_START */
 
-	// create four secondary command buffers, for each quadrant of the screen
-	secondaryCmds, _, err := info.DeviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
-		CommandPool:        info.CmdPool,
-		Level:              core1_0.CommandBufferLevelSecondary,
-		CommandBufferCount: 4,
-	})
+	// the four secondary command buffers, one for each quadrant of the screen, are
+	// recorded once and replayed every frame for as long as the render pass,
+	// framebuffer and everything they bind stay the same
+	cache, err := secondarycache.New(info.DeviceDriver, info.Frames, info.GraphicsQueueFamilyIndex)
 	if err != nil {
 		log.Fatalln(err)
 	}
+	cache.Names = info.DebugNames
+	// recordings are dropped when info destroys the framebuffers, render pass or
+	// pipeline they use
+	cache.Watch(info)
 
 	imageAcquiredSemaphore, _, err := info.DeviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	// Get the index of the next available swapchain image:
-	info.CurrentBuffer, _, err = info.SwapchainExtension.AcquireNextImage(info.Swapchain, common.NoTimeout, &imageAcquiredSemaphore, nil)
-	// TODO: Deal with the VK_SUBOPTIMAL_KHR and VK_ERROR_OUT_OF_DATE_KHR
-	// return codes
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	err = info.SetImageLayout(info.Buffers[info.CurrentBuffer].Image, core1_0.ImageAspectColor, core1_0.ImageLayoutUndefined, core1_0.ImageLayoutColorAttachmentOptimal, core1_0.PipelineStageTopOfPipe, core1_0.PipelineStageColorAttachmentOutput)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	viewport := core1_0.Viewport{
 		X: 0, Y: 0,
 		MinDepth: 0, MaxDepth: 1,
@@ -309,117 +296,170 @@ func main() {
 		Extent: core1_0.Extent2D{info.Width, info.Height},
 	}
 
-	// now we record four separate command buffers, one for each quadrant of the
-	// screen
-	inheritanceInfo := &core1_0.CommandBufferInheritanceInfo{
-		Framebuffer: info.Framebuffer[info.CurrentBuffer],
-		RenderPass:  info.RenderPass,
-		Subpass:     0,
-	}
-	secondaryBegin := core1_0.CommandBufferBeginInfo{
-		Flags:           core1_0.CommandBufferUsageOneTimeSubmit | core1_0.CommandBufferUsageRenderPassContinue,
-		InheritanceInfo: inheritanceInfo,
-	}
+	// Updating a descriptor set invalidates recordings that bind it, so each update bumps
+	// the set's version and drops those recordings
+	descSetVersions := make([]uint64, len(info.DescSet))
+	secondTextures := []core1_0.DescriptorImageInfo{lunargTex, greenTex}
 
-	for i := 0; i < 4; i++ {
-		_, err = info.DeviceDriver.BeginCommandBuffer(secondaryCmds[i], secondaryBegin)
-		if err != nil {
-			log.Fatalln(err)
+	start := hrtime.Now()
+	lastSwap := start
+	for frameIndex := 0; hrtime.Since(start) < 5*time.Second; frameIndex++ {
+		sdl.PollEvent()
+		// Output that can't be traced to a command buffer goes to the frame about to be
+		// submitted
+		info.Printf.BeginFrame(info.Frames.Submitted() + 1)
+
+		// every second, the quadrants drawn with the second descriptor set switch texture
+		if hrtime.Since(lastSwap) >= time.Second {
+			lastSwap = hrtime.Now()
+			secondTextures[0], secondTextures[1] = secondTextures[1], secondTextures[0]
+			writes[1].ImageInfo[0] = secondTextures[0]
+			err = info.DeviceDriver.UpdateDescriptorSets(writes[1:], nil)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			descSetVersions[1]++
+			err = cache.Invalidate(info.DescSet[1])
+			if err != nil {
+				log.Fatalln(err)
+			}
 		}
 
-		info.DeviceDriver.CmdBindPipeline(secondaryCmds[i], core1_0.PipelineBindPointGraphics, info.Pipeline)
-		firstIndex := 0
-		secondIndex := 1
-
-		if i == 0 || i == 3 {
-			firstIndex = 1
-			secondIndex = 2
+		// The first frame records into the command buffer the setup began, which already
+		// holds the texture uploads
+		if frameIndex > 0 {
+			err = info.ExecuteBeginCommandBuffer()
+			if err != nil {
+				log.Fatalln(err)
+			}
 		}
-		info.DeviceDriver.CmdBindDescriptorSets(secondaryCmds[i], core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet[firstIndex:secondIndex], nil)
-		info.DeviceDriver.CmdBindVertexBuffers(secondaryCmds[i], 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
 
-		viewport.X = 25.0 + 250.0*float32(i%2)
-		viewport.Y = 25.0 + 250.0*float32(i/2)
-		info.DeviceDriver.CmdSetViewport(secondaryCmds[i], viewport)
-		info.DeviceDriver.CmdSetScissor(secondaryCmds[i], scissor)
+		// Get the index of the next available swapchain image:
+		info.CurrentBuffer, _, err = info.SwapchainExtension.AcquireNextImage(info.Swapchain, common.NoTimeout, &imageAcquiredSemaphore, nil)
+		// TODO: Deal with the VK_SUBOPTIMAL_KHR and VK_ERROR_OUT_OF_DATE_KHR
+		// return codes
+		if err != nil {
+			log.Fatalln(err)
+		}
 
-		info.DeviceDriver.CmdDraw(secondaryCmds[i], 36, 1, 0, 0)
-		_, err = info.DeviceDriver.EndCommandBuffer(secondaryCmds[i])
+		err = info.SetImageLayout(info.Buffers[info.CurrentBuffer].Image, core1_0.ImageAspectColor, core1_0.ImageLayoutUndefined, core1_0.ImageLayoutColorAttachmentOptimal, core1_0.PipelineStageTopOfPipe, core1_0.PipelineStageColorAttachmentOutput)
 		if err != nil {
 			log.Fatalln(err)
 		}
-	}
 
-	// specifying VK_SUBPASS_CONTENTS_SECONDARY_COMMAND_BUFFERS means this
-	// render pass may
-	// ONLY call vkCmdExecuteCommands
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsSecondaryCommandBuffers, core1_0.RenderPassBeginInfo{
-		RenderPass:  info.RenderPass,
-		Framebuffer: info.Framebuffer[info.CurrentBuffer],
-		RenderArea: core1_0.Rect2D{
-			Offset: core1_0.Offset2D{0, 0},
-			Extent: core1_0.Extent2D{info.Width, info.Height},
-		},
-		ClearValues: []core1_0.ClearValue{
-			core1_0.ClearValueFloat{0.2, 0.2, 0.2, 0.2},
-			core1_0.ClearValueDepthStencil{Depth: 1, Stencil: 0},
-		},
-	})
-	if err != nil {
-		log.Fatalln(err)
-	}
+		// now we record four separate command buffers, one for each quadrant of the
+		// screen
+		key := secondarycache.Key{
+			Framebuffer: info.Framebuffer[info.CurrentBuffer],
+			RenderPass:  info.RenderPass,
+			Subpass:     0,
+		}
 
-	info.DeviceDriver.CmdExecuteCommands(info.Cmd, secondaryCmds...)
+		var secondaryCmds []core1_0.CommandBuffer
+		for i := 0; i < 4; i++ {
+			firstIndex := 0
+			secondIndex := 1
+
+			if i == 0 || i == 3 {
+				firstIndex = 1
+				secondIndex = 2
+			}
+
+			key.Name = fmt.Sprintf("Quadrant %d", i)
+			dependencies := []secondarycache.Dependency{
+				{Object: info.Pipeline},
+				{Object: info.PipelineLayout},
+				{Object: info.DescSet[firstIndex], Version: descSetVersions[firstIndex]},
+				{Object: info.VertexBuffer.Buf},
+			}
+			secondaryCmd, err := cache.Get(key, dependencies, func(commandBuffer core1_0.CommandBuffer) error {
+				info.DeviceDriver.CmdBindPipeline(commandBuffer, core1_0.PipelineBindPointGraphics, info.Pipeline)
+				info.DeviceDriver.CmdBindDescriptorSets(commandBuffer, core1_0.PipelineBindPointGraphics, info.PipelineLayout, 0, info.DescSet[firstIndex:secondIndex], nil)
+				info.DeviceDriver.CmdBindVertexBuffers(commandBuffer, 0, []core1_0.Buffer{info.VertexBuffer.Buf}, []int{0})
+
+				viewport.X = 25.0 + 250.0*float32(i%2)
+				viewport.Y = 25.0 + 250.0*float32(i/2)
+				info.DeviceDriver.CmdSetViewport(commandBuffer, viewport)
+				info.DeviceDriver.CmdSetScissor(commandBuffer, scissor)
+
+				info.DeviceDriver.CmdDraw(commandBuffer, 36, 1, 0, 0)
+				return nil
+			})
+			if err != nil {
+				log.Fatalln(err)
+			}
+			secondaryCmds = append(secondaryCmds, secondaryCmd)
+		}
 
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+		// specifying VK_SUBPASS_CONTENTS_SECONDARY_COMMAND_BUFFERS means this
+		// render pass may
+		// ONLY call vkCmdExecuteCommands
+		err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsSecondaryCommandBuffers, core1_0.RenderPassBeginInfo{
+			RenderPass:  info.RenderPass,
+			Framebuffer: info.Framebuffer[info.CurrentBuffer],
+			RenderArea: core1_0.Rect2D{
+				Offset: core1_0.Offset2D{0, 0},
+				Extent: core1_0.Extent2D{info.Width, info.Height},
+			},
+			ClearValues: []core1_0.ClearValue{
+				core1_0.ClearValueFloat{0.2, 0.2, 0.2, 0.2},
+				core1_0.ClearValueDepthStencil{Depth: 1, Stencil: 0},
+			},
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
 
-	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
-	if err != nil {
-		log.Fatalln(err)
-	}
+		info.DeviceDriver.CmdExecuteCommands(info.Cmd, secondaryCmds...)
 
-	drawFence, _, err := info.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
-	if err != nil {
-		log.Fatalln(err)
-	}
+		info.EndRenderPass(info.Cmd, "Render Pass")
 
-	/* Queue the command buffer for execution */
-	_, err = info.DeviceDriver.QueueSubmit(info.GraphicsQueue, &drawFence,
-		core1_0.SubmitInfo{
-			CommandBuffers:   []core1_0.CommandBuffer{info.Cmd},
-			WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
-			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
-		})
-	if err != nil {
-		log.Fatalln(err)
-	}
+		_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
 
-	/* Now present the image in the window */
+		/* Queue the command buffer for execution */
+		frame, res, err := info.Frames.Submit(info.SyncGraphicsQueue,
+			core1_0.SubmitInfo{
+				CommandBuffers:   []core1_0.CommandBuffer{info.Cmd},
+				WaitSemaphores:   []core1_0.Semaphore{imageAcquiredSemaphore},
+				WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
+			})
+		info.Breadcrumbs.Check(res)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		crumbs := info.Breadcrumbs.Submitted(info.GraphicsQueue, core1_0.Fence{}, info.Cmd)
+		info.Printf.TrackCommandBuffers(frame, info.Cmd)
 
-	/* Make sure command buffer is finished before presenting */
-	for {
-		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		/* Make sure command buffer is finished before presenting, and before it is
+		   recorded again next frame */
+		res, err = info.Frames.Wait(context.Background(), frame)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
+		info.Breadcrumbs.CompleteSubmission(crumbs)
 
-		if res != core1_0.VKTimeout {
-			break
+		err = cache.EndFrame()
+		if err != nil {
+			log.Fatalln(err)
 		}
-	}
 
-	_, err = info.SwapchainExtension.QueuePresent(info.PresentQueue, khr_swapchain.PresentInfo{
-		Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
-		ImageIndices: []int{info.CurrentBuffer},
-	})
-	if err != nil {
-		log.Fatalln(err)
+		/* Now present the image in the window */
+		_, err = info.SyncPresentQueue.Present(info.SwapchainExtension, khr_swapchain.PresentInfo{
+			Swapchains:   []khr_swapchain.Swapchain{info.Swapchain},
+			ImageIndices: []int{info.CurrentBuffer},
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
 	}
 
-	start := hrtime.Now()
-	for hrtime.Since(start) < 5*time.Second {
-		sdl.PollEvent()
-	}
+	hits, misses := cache.Stats()
+	log.Printf("Secondary command buffer cache: %d hits, %d misses\n", hits, misses)
 
 	if info.SaveImages {
 		err = info.WritePNG("secondary_command_buffer")
@@ -428,11 +468,10 @@ func main() {
 		}
 	}
 
-	info.DeviceDriver.FreeCommandBuffers(secondaryCmds...)
+	cache.Destroy()
 
 	/* VULKAN_KEY_END */
 
-	info.DeviceDriver.DestroyFence(drawFence, nil)
 	info.DeviceDriver.DestroySemaphore(imageAcquiredSemaphore, nil)
 	info.DestroyPipeline()
 	info.DestroyPipelineCache()
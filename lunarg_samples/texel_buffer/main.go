@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"embed"
 	"encoding/binary"
 	"github.com/loov/hrtime"
@@ -10,6 +9,7 @@ import (
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/upload"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 	"log"
@@ -77,11 +77,7 @@ func main() {
 
 	info.InstanceExtensionNames = append(info.InstanceExtensionNames, ext_debug_utils.ExtensionName)
 	info.InstanceLayerNames = append(info.InstanceLayerNames, "VK_LAYER_KHRONOS_validation")
-	debugOptions := ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
-		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    logDebug,
-	}
+	debugOptions := info.DebugMessengerOptions(logDebug)
 
 	err = info.InitInstance("Texel Buffer Sample", debugOptions)
 	if err != nil {
@@ -178,12 +174,10 @@ func main() {
 	}
 
 	memoryBytes := ([]byte)(unsafe.Slice((*byte)(pData), texelSize))
-	writer := &bytes.Buffer{}
-	err = binary.Write(writer, common.ByteOrder, texels)
+	_, err = upload.CopyValue(memoryBytes, texels)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	copy(memoryBytes, writer.Bytes())
 
 	info.DeviceDriver.UnmapMemory(texelMem)
 
@@ -316,7 +310,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	err = info.DeviceDriver.CmdBeginRenderPass(info.Cmd, core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
+	err = info.BeginRenderPass(info.Cmd, "Render Pass", core1_0.SubpassContentsInline, core1_0.RenderPassBeginInfo{
 		RenderPass:  info.RenderPass,
 		Framebuffer: info.Framebuffer[info.CurrentBuffer],
 		RenderArea: core1_0.Rect2D{
@@ -337,9 +331,9 @@ func main() {
 	info.InitViewports()
 	info.InitScissors()
 
-	info.DeviceDriver.CmdDraw(info.Cmd, 3, 1, 0, 0)
+	info.Draw(info.Cmd, "Triangle", 3, 1, 0, 0)
 
-	info.DeviceDriver.CmdEndRenderPass(info.Cmd)
+	info.EndRenderPass(info.Cmd, "Render Pass")
 
 	_, err = info.DeviceDriver.EndCommandBuffer(info.Cmd)
 	if err != nil {
@@ -358,6 +352,7 @@ func main() {
 
 	for {
 		res, err := info.DeviceDriver.WaitForFences(true, utils.FenceTimeout, drawFence)
+		info.Breadcrumbs.Check(res)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -370,7 +365,7 @@ func main() {
 	info.DeviceDriver.DestroyFence(drawFence, nil)
 
 	err = info.ExecutePresentImage()
-	if err != nil {
+	if err != nil && !utils.IsStatus(err) {
 		log.Fatalln(err)
 	}
 
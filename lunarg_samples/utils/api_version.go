@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// knownVersions are the API versions vkngwrapper has drivers for, newest first
+var knownVersions = []common.APIVersion{common.Vulkan1_2, common.Vulkan1_1, common.Vulkan1_0}
+
+// negotiateVersion returns the newest known API version that is no newer than desired
+// and that available supports, or an error if that is older than minimum. The patch
+// number is dropped, since drivers are chosen by major and minor version alone.
+func negotiateVersion(desired, minimum, available common.APIVersion) (common.APIVersion, error) {
+	if desired == 0 {
+		desired = common.Vulkan1_0
+	}
+	if minimum == 0 {
+		minimum = common.Vulkan1_0
+	}
+	if !desired.IsAtLeast(minimum) {
+		return 0, errors.Errorf("desired Vulkan %s is older than the minimum Vulkan %s", desired, minimum)
+	}
+
+	for _, version := range knownVersions {
+		if desired.IsAtLeast(version) && available.IsAtLeast(version) {
+			if !version.IsAtLeast(minimum) {
+				break
+			}
+			return version, nil
+		}
+	}
+
+	return 0, errors.Errorf("Vulkan %s is available, but at least Vulkan %s is required", available, minimum)
+}
+
+// chooseDevice moves the first of gpus that is suitable and supports the newest
+// negotiated API version to the front, so samples that use gpus[0] get it, and returns
+// that version. suitable is called with each candidate and the version negotiated with
+// it, and returns an error if the candidate can't be used. The order of the others is
+// kept.
+func chooseDevice(gpus []core1_0.PhysicalDevice, desired, minimum common.APIVersion, suitable func(gpu core1_0.PhysicalDevice, version common.APIVersion) error) (common.APIVersion, error) {
+	best := -1
+	var bestVersion common.APIVersion
+	var lastErr error
+	for index, gpu := range gpus {
+		version, err := negotiateVersion(desired, minimum, gpu.DeviceAPIVersion())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if best >= 0 && version <= bestVersion {
+			continue
+		}
+
+		err = suitable(gpu, version)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if best < 0 || version > bestVersion {
+			best = index
+			bestVersion = version
+		}
+	}
+
+	if best < 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no physical devices")
+		}
+		return 0, lastErr
+	}
+
+	chosen := gpus[best]
+	copy(gpus[1:best+1], gpus[:best])
+	gpus[0] = chosen
+	return bestVersion, nil
+}
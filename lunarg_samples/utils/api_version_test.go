@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+// vulkan1_3 is newer than any version vkngwrapper has drivers for
+const vulkan1_3 = common.APIVersion(1<<22 | 3<<12)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name                        string
+		desired, minimum, available common.APIVersion
+		want                        common.APIVersion
+		fails                       bool
+	}{
+		{name: "defaults", available: common.Vulkan1_2, want: common.Vulkan1_0},
+		{name: "desired available", desired: common.Vulkan1_1, available: common.Vulkan1_2, want: common.Vulkan1_1},
+		{name: "limited by available", desired: common.Vulkan1_2, available: common.Vulkan1_1, want: common.Vulkan1_1},
+		{name: "unknown version", desired: vulkan1_3, available: vulkan1_3, want: common.Vulkan1_2},
+		{name: "patch dropped", desired: common.Vulkan1_2, available: common.Vulkan1_2 + 189, want: common.Vulkan1_2},
+		{name: "minimum met", desired: common.Vulkan1_2, minimum: common.Vulkan1_1, available: common.Vulkan1_1, want: common.Vulkan1_1},
+		{name: "minimum unavailable", desired: common.Vulkan1_2, minimum: common.Vulkan1_1, available: common.Vulkan1_0, fails: true},
+		{name: "desired below minimum", desired: common.Vulkan1_0, minimum: common.Vulkan1_1, available: common.Vulkan1_2, fails: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			version, err := negotiateVersion(test.desired, test.minimum, test.available)
+			if test.fails {
+				if err == nil {
+					t.Errorf("negotiated %s, want an error", version)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != test.want {
+				t.Errorf("negotiated %s, want %s", version, test.want)
+			}
+		})
+	}
+}
+
+func TestChooseDevice(t *testing.T) {
+	errUnsuitable := errors.New("unsuitable")
+
+	tests := []struct {
+		name     string
+		versions []common.APIVersion
+		// unsuitable are the handles that fail the suitability check
+		unsuitable []int
+		minimum    common.APIVersion
+		want       common.APIVersion
+		order      []loader.VkPhysicalDevice
+		err        error
+	}{
+		{
+			name:     "newest moved to front",
+			versions: []common.APIVersion{common.Vulkan1_0, common.Vulkan1_1, common.Vulkan1_2},
+			want:     common.Vulkan1_2,
+			order:    []loader.VkPhysicalDevice{3, 1, 2},
+		},
+		{
+			name:     "first of equals kept",
+			versions: []common.APIVersion{common.Vulkan1_1, common.Vulkan1_2, common.Vulkan1_2},
+			want:     common.Vulkan1_2,
+			order:    []loader.VkPhysicalDevice{2, 1, 3},
+		},
+		{
+			name:       "unsuitable skipped",
+			versions:   []common.APIVersion{common.Vulkan1_2, common.Vulkan1_0, common.Vulkan1_1},
+			unsuitable: []int{1},
+			want:       common.Vulkan1_1,
+			order:      []loader.VkPhysicalDevice{3, 1, 2},
+		},
+		{
+			name:       "older suitable device",
+			versions:   []common.APIVersion{common.Vulkan1_2, common.Vulkan1_0},
+			unsuitable: []int{1},
+			want:       common.Vulkan1_0,
+			order:      []loader.VkPhysicalDevice{2, 1},
+		},
+		{
+			name:       "none suitable",
+			versions:   []common.APIVersion{common.Vulkan1_2, common.Vulkan1_1},
+			unsuitable: []int{1, 2},
+			err:        errUnsuitable,
+		},
+		{
+			name:     "below minimum",
+			versions: []common.APIVersion{common.Vulkan1_0},
+			minimum:  common.Vulkan1_1,
+		},
+		{
+			name: "no devices",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gpus []core1_0.PhysicalDevice
+			for index, version := range test.versions {
+				gpus = append(gpus, fakeGpu(index+1, version))
+			}
+
+			suitable := func(gpu core1_0.PhysicalDevice, version common.APIVersion) error {
+				for _, handle := range test.unsuitable {
+					if gpu.Handle() == loader.VkPhysicalDevice(handle) {
+						return errUnsuitable
+					}
+				}
+				return nil
+			}
+
+			version, err := chooseDevice(gpus, common.Vulkan1_2, test.minimum, suitable)
+			if test.order == nil {
+				if err == nil {
+					t.Fatalf("chose Vulkan %s, want an error", version)
+				}
+				if test.err != nil && !errors.Is(err, test.err) {
+					t.Errorf("got %v, want %v", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != test.want {
+				t.Errorf("chose Vulkan %s, want %s", version, test.want)
+			}
+			if order := handles(gpus); !slices.Equal(order, test.order) {
+				t.Errorf("devices ordered %v, want %v", order, test.order)
+			}
+		})
+	}
+}
+
+func TestChooseDeviceSkipsOlderCandidates(t *testing.T) {
+	gpus := []core1_0.PhysicalDevice{fakeGpu(1, common.Vulkan1_2), fakeGpu(2, common.Vulkan1_1)}
+
+	var checked []loader.VkPhysicalDevice
+	_, err := chooseDevice(gpus, common.Vulkan1_2, 0, func(gpu core1_0.PhysicalDevice, version common.APIVersion) error {
+		checked = append(checked, gpu.Handle())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(checked, []loader.VkPhysicalDevice{1}) {
+		t.Errorf("checked %v, but only the first device can be chosen", checked)
+	}
+}
@@ -0,0 +1,74 @@
+package blocklayout
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Check compares t, which must be a struct, against the layout a shader declared for
+// shaderStruct, and returns an error describing the first difference. Members are matched
+// by position, since Go and GLSL names rarely agree.
+func (t *Type) Check(shaderStruct *ShaderStruct) error {
+	if t.Kind != KindStruct {
+		return errors.Errorf("%s is not a struct", t.GoType)
+	}
+
+	if len(t.Members) != len(shaderStruct.Members) {
+		return errors.Errorf("%s has %d fields, but shader struct %s has %d members",
+			t.GoType, len(t.Members), shaderStruct.Name, len(shaderStruct.Members))
+	}
+
+	for memberIndex, member := range t.Members {
+		shaderMember := shaderStruct.Members[memberIndex]
+		err := member.check(shaderMember)
+		if err != nil {
+			return errors.Wrapf(err, "%s.%s (shader member %s.%s)", t.GoType, member.Name, shaderStruct.Name, shaderMember.Name)
+		}
+	}
+
+	return nil
+}
+
+// CheckBlock finds the block named blockName in a SPIR-V module and checks t against it
+func (t *Type) CheckBlock(code []uint32, blockName string) error {
+	block, err := FindBlock(code, blockName)
+	if err != nil {
+		return err
+	}
+
+	return t.Check(block.Struct)
+}
+
+func (m Member) check(shaderMember ShaderMember) error {
+	if m.Offset != shaderMember.Offset {
+		return errors.Errorf("offset is %d, shader expects %d", m.Offset, shaderMember.Offset)
+	}
+
+	memberType := m.Type
+	if shaderMember.ArrayStride != 0 {
+		if memberType.Kind != KindArray {
+			return errors.Errorf("shader expects an array")
+		}
+		if memberType.Stride != shaderMember.ArrayStride {
+			return errors.Errorf("array stride is %d, shader expects %d", memberType.Stride, shaderMember.ArrayStride)
+		}
+
+		for memberType.Kind == KindArray {
+			memberType = memberType.Elem
+		}
+	}
+
+	if shaderMember.MatrixStride != 0 {
+		if memberType.Kind != KindMatrix {
+			return errors.Errorf("shader expects a matrix")
+		}
+		if memberType.Stride != shaderMember.MatrixStride {
+			return errors.Errorf("matrix stride is %d, shader expects %d", memberType.Stride, shaderMember.MatrixStride)
+		}
+	}
+
+	if shaderMember.Struct != nil {
+		return memberType.Check(shaderMember.Struct)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package blocklayout
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/internal/spirv"
+	vkngmath "github.com/vkngwrapper/math"
+)
+
+// instruction encodes a SPIR-V instruction
+func instruction(opcode int, operands ...uint32) []uint32 {
+	return append([]uint32{uint32(len(operands)+1)<<16 | uint32(opcode)}, operands...)
+}
+
+// literal encodes a nul-terminated literal string operand
+func literal(value string) []uint32 {
+	words := make([]uint32, len(value)/4+1)
+	for index := 0; index < len(value); index++ {
+		words[index/4] |= uint32(value[index]) << (8 * (index % 4))
+	}
+	return words
+}
+
+// paramsModule is the SPIR-V for this std140 uniform block:
+//
+//	layout(set = 0, binding = 0) uniform Params {
+//	    vec3 position;
+//	    float scale;
+//	    mat4 transform;
+//	    float weights[2];
+//	} params;
+func paramsModule() []uint32 {
+	const (
+		floatType = iota + 1
+		vec3Type
+		vec4Type
+		mat4Type
+		uintType
+		two
+		weightsType
+		paramsType
+		pointerType
+		paramsVariable
+		bound
+	)
+
+	code := []uint32{spirv.Magic, 0x00010000, 0, bound, 0}
+	for _, inst := range [][]uint32{
+		instruction(spirv.OpName, append([]uint32{paramsType}, literal("Params")...)...),
+		instruction(spirv.OpMemberName, append([]uint32{paramsType, 0}, literal("position")...)...),
+		instruction(spirv.OpMemberName, append([]uint32{paramsType, 1}, literal("scale")...)...),
+		instruction(spirv.OpMemberName, append([]uint32{paramsType, 2}, literal("transform")...)...),
+		instruction(spirv.OpMemberName, append([]uint32{paramsType, 3}, literal("weights")...)...),
+		instruction(spirv.OpName, append([]uint32{paramsVariable}, literal("params")...)...),
+		instruction(spirv.OpDecorate, weightsType, spirv.DecorationArrayStride, 16),
+		instruction(spirv.OpMemberDecorate, paramsType, 0, spirv.DecorationOffset, 0),
+		instruction(spirv.OpMemberDecorate, paramsType, 1, spirv.DecorationOffset, 12),
+		instruction(spirv.OpMemberDecorate, paramsType, 2, spirv.DecorationOffset, 16),
+		instruction(spirv.OpMemberDecorate, paramsType, 2, spirv.DecorationMatrixStride, 16),
+		instruction(spirv.OpMemberDecorate, paramsType, 3, spirv.DecorationOffset, 80),
+		instruction(spirv.OpDecorate, paramsType, spirv.DecorationBlock),
+		instruction(spirv.OpDecorate, paramsVariable, spirv.DecorationDescriptorSet, 0),
+		instruction(spirv.OpDecorate, paramsVariable, spirv.DecorationBinding, 0),
+		instruction(spirv.OpTypeFloat, floatType, 32),
+		instruction(spirv.OpTypeVector, vec3Type, floatType, 3),
+		instruction(spirv.OpTypeVector, vec4Type, floatType, 4),
+		instruction(spirv.OpTypeMatrix, mat4Type, vec4Type, 4),
+		instruction(spirv.OpTypeInt, uintType, 32, 0),
+		instruction(spirv.OpConstant, uintType, two, 2),
+		instruction(spirv.OpTypeArray, weightsType, floatType, two),
+		instruction(spirv.OpTypeStruct, paramsType, vec3Type, floatType, mat4Type, weightsType),
+		instruction(spirv.OpTypePointer, pointerType, spirv.StorageClassUniform, paramsType),
+		instruction(spirv.OpVariable, pointerType, paramsVariable, spirv.StorageClassUniform),
+	} {
+		code = append(code, inst...)
+	}
+
+	return code
+}
+
+type params struct {
+	Position  vkngmath.Vec3[float32]
+	Scale     float32
+	Transform vkngmath.Mat4x4[float32]
+	Weights   [2]float32
+}
+
+type arrayPositionParams struct {
+	Position  [3]float32
+	Scale     float32
+	Transform vkngmath.Mat4x4[float32]
+	Weights   [2]float32
+}
+
+type shortParams struct {
+	Position  vkngmath.Vec3[float32]
+	Scale     float32
+	Transform vkngmath.Mat4x4[float32]
+}
+
+type vectorWeightsParams struct {
+	Position  vkngmath.Vec3[float32]
+	Scale     float32
+	Transform vkngmath.Mat4x4[float32]
+	Weights   vkngmath.Vec2[float32]
+}
+
+func TestCheckBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Rules
+		goType reflect.Type
+		// err is part of the error expected, or empty if the layouts should match
+		err string
+	}{
+		{name: "matching", rules: Std140, goType: reflect.TypeFor[params]()},
+		{name: "std430 array stride", rules: Std430, goType: reflect.TypeFor[params](), err: "array stride is 4, shader expects 16"},
+		{name: "array for vector", rules: Std140, goType: reflect.TypeFor[arrayPositionParams](), err: "offset is 48, shader expects 12"},
+		{name: "missing member", rules: Std140, goType: reflect.TypeFor[shortParams](), err: "has 3 fields, but shader struct Params has 4 members"},
+		{name: "vector for array", rules: Std140, goType: reflect.TypeFor[vectorWeightsParams](), err: "shader expects an array"},
+	}
+
+	code := paramsModule()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout, err := Of(test.rules, test.goType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = layout.CheckBlock(code, "params")
+			if test.err == "" {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("%s matched the shader block", layout)
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %q, want it to mention %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestFindBlock(t *testing.T) {
+	block, err := FindBlock(paramsModule(), "Params")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Name != "params" || block.PushConstants {
+		t.Errorf("found %+v, want the params uniform block", block)
+	}
+	if len(block.Struct.Members) != 4 || block.Struct.Members[2].MatrixStride != 16 || block.Struct.Members[3].ArrayStride != 16 {
+		t.Errorf("reflected %+v", block.Struct.Members)
+	}
+
+	_, err = FindBlock(paramsModule(), "Missing")
+	if err == nil {
+		t.Error("found a block the shader doesn't declare")
+	}
+}
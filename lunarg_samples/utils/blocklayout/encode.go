@@ -0,0 +1,115 @@
+package blocklayout
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+)
+
+// copyOp copies size bytes from src in the Go value to dst in the encoded block. Go
+// stores scalars in the platform's byte order, which is also what the GPU expects, so
+// everything but bools can be copied directly.
+type copyOp struct {
+	src    uintptr
+	dst    int
+	size   int
+	isBool bool
+}
+
+// compile flattens t into the copies needed to encode a value of it stored at src to dst,
+// merging copies that are contiguous on both sides
+func (t *Type) compile(src uintptr, dst int, ops []copyOp) []copyOp {
+	switch t.Kind {
+	case KindScalar:
+		op := copyOp{src: src, dst: dst, size: t.Size, isBool: t.GoType.Kind() == reflect.Bool}
+		if len(ops) > 0 && !op.isBool {
+			last := &ops[len(ops)-1]
+			if !last.isBool && last.src+uintptr(last.size) == src && last.dst+last.size == dst {
+				last.size += op.size
+				return ops
+			}
+		}
+		return append(ops, op)
+	case KindStruct:
+		for _, member := range t.Members {
+			ops = member.Type.compile(src+member.goOffset, dst+member.Offset, ops)
+		}
+		return ops
+	}
+
+	// Vectors, matrices and arrays are all runs of evenly spaced elements
+	goStride := t.Elem.GoType.Size()
+	for index := 0; index < t.Len; index++ {
+		ops = t.Elem.compile(src+uintptr(index)*goStride, dst+index*t.Stride, ops)
+	}
+	return ops
+}
+
+// Encode lays out value, which must be of t's Go type or a pointer to it
+func (t *Type) Encode(value any) ([]byte, error) {
+	dst := make([]byte, t.Size)
+	return dst, t.EncodeInto(dst, value)
+}
+
+// EncodeInto lays out value into dst, which is usually mapped buffer memory. Padding
+// bytes in dst are left untouched.
+func (t *Type) EncodeInto(dst []byte, value any) error {
+	if len(dst) < t.Size {
+		return errors.Errorf("%d bytes is too small to hold %s", len(dst), t)
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() == reflect.Pointer && reflected.Type().Elem() == t.GoType {
+		if reflected.IsNil() {
+			return errors.Errorf("cannot encode nil %s", reflected.Type())
+		}
+		reflected = reflected.Elem()
+	} else if reflected.Type() != t.GoType {
+		return errors.Errorf("cannot encode %s as %s", reflected.Type(), t)
+	}
+
+	if !reflected.CanAddr() {
+		// Copy the value somewhere it can be read from directly
+		addressable := reflect.New(t.GoType).Elem()
+		addressable.Set(reflected)
+		reflected = addressable
+	}
+
+	base := reflected.Addr().UnsafePointer()
+	for _, op := range t.ops {
+		if op.isBool {
+			var encoded uint32
+			if *(*bool)(unsafe.Add(base, op.src)) {
+				encoded = 1
+			}
+			common.ByteOrder.PutUint32(dst[op.dst:], encoded)
+			continue
+		}
+
+		copy(dst[op.dst:op.dst+op.size], unsafe.Slice((*byte)(unsafe.Add(base, op.src)), op.size))
+	}
+
+	return nil
+}
+
+// Encode lays out value under rules
+func Encode(rules Rules, value any) ([]byte, error) {
+	layout, err := TypeOf(rules, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return layout.Encode(value)
+}
+
+// EncodeInto lays out value under rules into dst
+func EncodeInto(rules Rules, dst []byte, value any) error {
+	layout, err := TypeOf(rules, value)
+	if err != nil {
+		return err
+	}
+
+	return layout.EncodeInto(dst, value)
+}
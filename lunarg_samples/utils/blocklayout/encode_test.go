@@ -0,0 +1,132 @@
+package blocklayout
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/vkngwrapper/core/v3/common"
+	vkngmath "github.com/vkngwrapper/math"
+)
+
+type material struct {
+	Transform vkngmath.Mat3x3[float32]
+	Tint      vkngmath.Vec3[float32]
+	Lit       bool
+	Weights   [3]float32
+	Inner     inner
+	Seed      uint64
+	Layer     int32
+}
+
+// decode reads a value of t's Go type back out of data
+func decode(t *Type, data []byte) reflect.Value {
+	value := reflect.New(t.GoType).Elem()
+
+	switch t.Kind {
+	case KindScalar:
+		switch t.GoType.Kind() {
+		case reflect.Bool:
+			value.SetBool(common.ByteOrder.Uint32(data) != 0)
+		case reflect.Int32:
+			value.SetInt(int64(int32(common.ByteOrder.Uint32(data))))
+		case reflect.Uint32:
+			value.SetUint(uint64(common.ByteOrder.Uint32(data)))
+		case reflect.Float32:
+			value.SetFloat(float64(math.Float32frombits(common.ByteOrder.Uint32(data))))
+		case reflect.Int64:
+			value.SetInt(int64(common.ByteOrder.Uint64(data)))
+		case reflect.Uint64:
+			value.SetUint(common.ByteOrder.Uint64(data))
+		case reflect.Float64:
+			value.SetFloat(math.Float64frombits(common.ByteOrder.Uint64(data)))
+		}
+	case KindStruct:
+		for _, member := range t.Members {
+			value.FieldByName(member.Name).Set(decode(member.Type, data[member.Offset:]))
+		}
+	default:
+		// Vectors are vkngmath structs, except for matrix columns, which are arrays
+		for index := 0; index < t.Len; index++ {
+			elem := decode(t.Elem, data[index*t.Stride:])
+			if t.GoType.Kind() == reflect.Struct {
+				value.Field(index).Set(elem)
+			} else {
+				value.Index(index).Set(elem)
+			}
+		}
+	}
+
+	return value
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	value := material{
+		Transform: vkngmath.Mat3x3[float32]{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+		Tint:      vkngmath.Vec3[float32]{X: 0.25, Y: 0.5, Z: 0.75},
+		Lit:       true,
+		Weights:   [3]float32{10, 11, 12},
+		Inner:     inner{Scale: 2, Offset: vkngmath.Vec2[float32]{X: -1, Y: 1}},
+		Seed:      0x0123456789abcdef,
+		Layer:     -3,
+	}
+
+	for _, rules := range []Rules{Std140, Std430, Scalar} {
+		t.Run(rules.String(), func(t *testing.T) {
+			layout, err := TypeOf(rules, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encoded, err := layout.Encode(&value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(encoded) != layout.Size {
+				t.Errorf("encoded %d bytes, want %d", len(encoded), layout.Size)
+			}
+
+			decoded := decode(layout, encoded).Interface()
+			if decoded != value {
+				t.Errorf("decoded %+v, want %+v", decoded, value)
+			}
+		})
+	}
+}
+
+func TestEncodeIntoLeavesPadding(t *testing.T) {
+	layout, err := Of(Std140, reflect.TypeFor[floatArray]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := make([]byte, layout.Size)
+	for index := range dst {
+		dst[index] = 0xff
+	}
+
+	err = layout.EncodeInto(dst, floatArray{Weights: [4]float32{1, 2, 3, 4}, Scale: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each float in the array is padded out to 16 bytes
+	for index := 0; index < 4; index++ {
+		if got := math.Float32frombits(common.ByteOrder.Uint32(dst[index*16:])); got != float32(index+1) {
+			t.Errorf("weight %d is %v, want %d", index, got, index+1)
+		}
+		if dst[index*16+4] != 0xff {
+			t.Errorf("padding after weight %d was overwritten", index)
+		}
+	}
+
+	err = layout.EncodeInto(dst[:layout.Size-1], floatArray{})
+	if err == nil {
+		t.Error("encoded into a buffer too small to hold the block")
+	}
+
+	err = layout.EncodeInto(dst, vec3Float{})
+	if err == nil {
+		t.Error("encoded a value of the wrong type")
+	}
+}
@@ -0,0 +1,266 @@
+package blocklayout
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Rules is a set of block layout rules from the Vulkan specification
+type Rules int
+
+const (
+	// Std140 is the layout of uniform blocks unless the shader asks otherwise
+	Std140 Rules = iota
+	// Std430 is the layout of storage blocks and push constants
+	Std430
+	// Scalar is the layout from VK_EXT_scalar_block_layout, where every type is aligned to
+	// the size of its scalar components
+	Scalar
+)
+
+func (r Rules) String() string {
+	switch r {
+	case Std140:
+		return "std140"
+	case Std430:
+		return "std430"
+	}
+
+	return "scalar"
+}
+
+const mathPackage = "github.com/vkngwrapper/math"
+
+// Kind is the GLSL shape of a laid-out type
+type Kind int
+
+const (
+	KindScalar Kind = iota
+	KindVector
+	KindMatrix
+	KindArray
+	KindStruct
+)
+
+// Member is a single field of a struct
+type Member struct {
+	Name   string
+	Offset int
+	Type   *Type
+
+	goOffset uintptr
+}
+
+// Type is the layout of a Go type under one set of rules. Go types are mapped to GLSL
+// types as follows:
+//
+//   - bool is a 4-byte GLSL bool; int32, uint32, float32, int64, uint64 and float64 are
+//     the matching scalars
+//   - vkngmath vectors are vectors, and quaternions are vec4s
+//   - vkngmath matrices are column-major matrices, with each row type holding a column
+//   - arrays are arrays, and structs are structs
+type Type struct {
+	GoType reflect.Type
+	Rules  Rules
+	Kind   Kind
+	Size   int
+	Align  int
+
+	// Members are the fields of a struct
+	Members []Member
+	// Elem is the element type of an array, or the column type of a matrix
+	Elem *Type
+	// Len is the number of elements in an array or vector, or columns in a matrix
+	Len int
+	// Stride is the distance between elements of an array or columns of a matrix
+	Stride int
+
+	// ops copies a Go value into its encoded form
+	ops []copyOp
+}
+
+func (t *Type) String() string {
+	return fmt.Sprintf("%s (%s, %d bytes)", t.GoType, t.Rules, t.Size)
+}
+
+type layoutKey struct {
+	goType reflect.Type
+	rules  Rules
+}
+
+var layouts sync.Map
+
+// Of returns the layout of goType under rules. Layouts are computed once per type and
+// cached, so this is cheap to call repeatedly.
+func Of(rules Rules, goType reflect.Type) (*Type, error) {
+	key := layoutKey{goType: goType, rules: rules}
+	cached, found := layouts.Load(key)
+	if found {
+		return cached.(*Type), nil
+	}
+
+	layout, err := build(rules, goType)
+	if err != nil {
+		return nil, err
+	}
+	layout.ops = layout.compile(0, 0, nil)
+
+	cached, _ = layouts.LoadOrStore(key, layout)
+	return cached.(*Type), nil
+}
+
+// TypeOf returns the layout of value's type under rules. Pointers are followed.
+func TypeOf(rules Rules, value any) (*Type, error) {
+	goType := reflect.TypeOf(value)
+	if goType == nil {
+		return nil, errors.New("cannot lay out nil")
+	}
+
+	for goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+
+	return Of(rules, goType)
+}
+
+func alignUp(value int, alignment int) int {
+	return (value + alignment - 1) / alignment * alignment
+}
+
+func build(rules Rules, goType reflect.Type) (*Type, error) {
+	if goType.PkgPath() == mathPackage {
+		return buildMath(rules, goType)
+	}
+
+	switch goType.Kind() {
+	case reflect.Bool, reflect.Int32, reflect.Uint32, reflect.Float32:
+		return &Type{GoType: goType, Rules: rules, Kind: KindScalar, Size: 4, Align: 4}, nil
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		return &Type{GoType: goType, Rules: rules, Kind: KindScalar, Size: 8, Align: 8}, nil
+	case reflect.Array:
+		elem, err := build(rules, goType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return arrayOf(rules, goType, elem, goType.Len(), KindArray), nil
+	case reflect.Struct:
+		return buildStruct(rules, goType)
+	}
+
+	return nil, errors.Errorf("%s has no GLSL equivalent", goType)
+}
+
+func vectorOf(rules Rules, goType reflect.Type, scalar *Type, length int) *Type {
+	vector := &Type{
+		GoType: goType,
+		Rules:  rules,
+		Kind:   KindVector,
+		Size:   scalar.Size * length,
+		Align:  scalar.Align,
+		Elem:   scalar,
+		Len:    length,
+		Stride: scalar.Size,
+	}
+
+	if rules != Scalar {
+		// vec2 is aligned to twice its scalar, vec3 and vec4 to four times
+		if length == 2 {
+			vector.Align = 2 * scalar.Align
+		} else {
+			vector.Align = 4 * scalar.Align
+		}
+	}
+
+	return vector
+}
+
+func arrayOf(rules Rules, goType reflect.Type, elem *Type, length int, kind Kind) *Type {
+	align := elem.Align
+	if rules == Std140 {
+		// std140 rounds the alignment of arrays and matrix columns up to a vec4
+		align = alignUp(align, 16)
+	}
+
+	stride := alignUp(elem.Size, align)
+	return &Type{
+		GoType: goType,
+		Rules:  rules,
+		Kind:   kind,
+		Size:   stride * length,
+		Align:  align,
+		Elem:   elem,
+		Len:    length,
+		Stride: stride,
+	}
+}
+
+func buildMath(rules Rules, goType reflect.Type) (*Type, error) {
+	name := goType.Name()
+	switch {
+	case (strings.HasPrefix(name, "Vec") || strings.HasPrefix(name, "Quaternion")) && goType.Kind() == reflect.Struct && goType.NumField() > 0:
+		scalar, err := build(rules, goType.Field(0).Type)
+		if err != nil {
+			return nil, err
+		}
+		return vectorOf(rules, goType, scalar, goType.NumField()), nil
+	case strings.HasPrefix(name, "Mat") && goType.Kind() == reflect.Array:
+		// Mat4Row and the like are arrays too, but of scalars
+		column := goType.Elem()
+		if column.Kind() != reflect.Array {
+			return nil, errors.Errorf("%s is a single matrix row, not a matrix; use a Vec type for a vector", goType)
+		}
+		scalar, err := build(rules, column.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return arrayOf(rules, goType, vectorOf(rules, column, scalar, column.Len()), goType.Len(), KindMatrix), nil
+	}
+
+	return nil, errors.Errorf("%s has no GLSL equivalent", goType)
+}
+
+func buildStruct(rules Rules, goType reflect.Type) (*Type, error) {
+	layout := &Type{
+		GoType: goType,
+		Rules:  rules,
+		Kind:   KindStruct,
+		Align:  1,
+	}
+
+	offset := 0
+	for fieldIndex := 0; fieldIndex < goType.NumField(); fieldIndex++ {
+		field := goType.Field(fieldIndex)
+		if field.Name == "_" {
+			continue
+		}
+
+		member, err := build(rules, field.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s.%s", goType, field.Name)
+		}
+
+		offset = alignUp(offset, member.Align)
+		layout.Members = append(layout.Members, Member{
+			Name:     field.Name,
+			Offset:   offset,
+			Type:     member,
+			goOffset: field.Offset,
+		})
+		offset += member.Size
+
+		if member.Align > layout.Align {
+			layout.Align = member.Align
+		}
+	}
+
+	if rules == Std140 {
+		layout.Align = alignUp(layout.Align, 16)
+	}
+	layout.Size = alignUp(offset, layout.Align)
+
+	return layout, nil
+}
@@ -0,0 +1,173 @@
+package blocklayout
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	vkngmath "github.com/vkngwrapper/math"
+)
+
+type vec3Float struct {
+	Position vkngmath.Vec3[float32]
+	Scale    float32
+}
+
+type floatArray struct {
+	Weights [4]float32
+	Scale   float32
+}
+
+type vec2Array struct {
+	Points [3]vkngmath.Vec2[float32]
+}
+
+type vec3Array struct {
+	Normals [2]vkngmath.Vec3[float32]
+	Scale   float32
+}
+
+type mat3Float struct {
+	Rotation vkngmath.Mat3x3[float32]
+	Scale    float32
+}
+
+type mat4Float struct {
+	Transform vkngmath.Mat4x4[float32]
+	Scale     float32
+}
+
+type inner struct {
+	Scale  float32
+	Offset vkngmath.Vec2[float32]
+}
+
+type nested struct {
+	Weight float32
+	Inner  inner
+	Bias   float32
+}
+
+type mixed struct {
+	Scale  float32
+	Normal vkngmath.Vec3[float32]
+	UV     vkngmath.Vec2[float32]
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Rules
+		goType reflect.Type
+		size   int
+		// offsets are the offsets of the struct's members, and strides their array or
+		// column strides, or 0 for members that aren't arrays or matrices
+		offsets []int
+		strides []int
+	}{
+		{"vec3 then float std140", Std140, reflect.TypeFor[vec3Float](), 16, []int{0, 12}, []int{0, 0}},
+		{"vec3 then float std430", Std430, reflect.TypeFor[vec3Float](), 16, []int{0, 12}, []int{0, 0}},
+		{"vec3 then float scalar", Scalar, reflect.TypeFor[vec3Float](), 16, []int{0, 12}, []int{0, 0}},
+
+		{"float array std140", Std140, reflect.TypeFor[floatArray](), 80, []int{0, 64}, []int{16, 0}},
+		{"float array std430", Std430, reflect.TypeFor[floatArray](), 20, []int{0, 16}, []int{4, 0}},
+		{"float array scalar", Scalar, reflect.TypeFor[floatArray](), 20, []int{0, 16}, []int{4, 0}},
+		{"vec2 array std140", Std140, reflect.TypeFor[vec2Array](), 48, []int{0}, []int{16}},
+		{"vec2 array std430", Std430, reflect.TypeFor[vec2Array](), 24, []int{0}, []int{8}},
+		{"vec3 array std140", Std140, reflect.TypeFor[vec3Array](), 48, []int{0, 32}, []int{16, 0}},
+		{"vec3 array std430", Std430, reflect.TypeFor[vec3Array](), 48, []int{0, 32}, []int{16, 0}},
+		{"vec3 array scalar", Scalar, reflect.TypeFor[vec3Array](), 28, []int{0, 24}, []int{12, 0}},
+
+		{"mat3 std140", Std140, reflect.TypeFor[mat3Float](), 64, []int{0, 48}, []int{16, 0}},
+		{"mat3 std430", Std430, reflect.TypeFor[mat3Float](), 64, []int{0, 48}, []int{16, 0}},
+		{"mat3 scalar", Scalar, reflect.TypeFor[mat3Float](), 40, []int{0, 36}, []int{12, 0}},
+		{"mat4 std140", Std140, reflect.TypeFor[mat4Float](), 80, []int{0, 64}, []int{16, 0}},
+		{"mat4 std430", Std430, reflect.TypeFor[mat4Float](), 80, []int{0, 64}, []int{16, 0}},
+		{"mat4 scalar", Scalar, reflect.TypeFor[mat4Float](), 68, []int{0, 64}, []int{16, 0}},
+
+		{"nested struct std140", Std140, reflect.TypeFor[nested](), 48, []int{0, 16, 32}, []int{0, 0, 0}},
+		{"nested struct std430", Std430, reflect.TypeFor[nested](), 32, []int{0, 8, 24}, []int{0, 0, 0}},
+		{"nested struct scalar", Scalar, reflect.TypeFor[nested](), 20, []int{0, 4, 16}, []int{0, 0, 0}},
+
+		{"vec3 packing std430", Std430, reflect.TypeFor[mixed](), 48, []int{0, 16, 32}, []int{0, 0, 0}},
+		{"vec3 packing scalar", Scalar, reflect.TypeFor[mixed](), 24, []int{0, 4, 16}, []int{0, 0, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout, err := Of(test.rules, test.goType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if layout.Size != test.size {
+				t.Errorf("size is %d, want %d", layout.Size, test.size)
+			}
+
+			var offsets, strides []int
+			for _, member := range layout.Members {
+				offsets = append(offsets, member.Offset)
+				strides = append(strides, member.Type.Stride)
+				if member.Type.Kind != KindArray && member.Type.Kind != KindMatrix {
+					strides[len(strides)-1] = 0
+				}
+			}
+
+			if !slices.Equal(offsets, test.offsets) {
+				t.Errorf("offsets are %v, want %v", offsets, test.offsets)
+			}
+			if !slices.Equal(strides, test.strides) {
+				t.Errorf("strides are %v, want %v", strides, test.strides)
+			}
+		})
+	}
+}
+
+func TestNestedStructLayout(t *testing.T) {
+	tests := []struct {
+		rules   Rules
+		size    int
+		offsets []int
+	}{
+		{Std140, 16, []int{0, 8}},
+		{Std430, 16, []int{0, 8}},
+		{Scalar, 12, []int{0, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rules.String(), func(t *testing.T) {
+			layout, err := Of(test.rules, reflect.TypeFor[nested]())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			innerLayout := layout.Members[1].Type
+			if innerLayout.Size != test.size {
+				t.Errorf("inner struct size is %d, want %d", innerLayout.Size, test.size)
+			}
+
+			var offsets []int
+			for _, member := range innerLayout.Members {
+				offsets = append(offsets, member.Offset)
+			}
+			if !slices.Equal(offsets, test.offsets) {
+				t.Errorf("inner struct offsets are %v, want %v", offsets, test.offsets)
+			}
+		})
+	}
+}
+
+func TestLayoutRejects(t *testing.T) {
+	for _, goType := range []reflect.Type{
+		reflect.TypeFor[int](),
+		reflect.TypeFor[uint8](),
+		reflect.TypeFor[[]float32](),
+		reflect.TypeFor[vkngmath.Mat4Row[float32]](),
+		reflect.TypeFor[struct{ Name string }](),
+	} {
+		_, err := Of(Std140, goType)
+		if err == nil {
+			t.Errorf("laid out %s, which has no GLSL equivalent", goType)
+		}
+	}
+}
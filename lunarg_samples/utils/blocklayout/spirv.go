@@ -0,0 +1,200 @@
+package blocklayout
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/internal/spirv"
+)
+
+// ShaderMember is a member of a struct as declared in a shader
+type ShaderMember struct {
+	Name   string
+	Offset int
+	// ArrayStride is the stride of the member if it's an array, or 0
+	ArrayStride int
+	// MatrixStride is the column stride of the member if it's a matrix or an array of
+	// matrices, or 0
+	MatrixStride int
+	// Struct is the member's type if it's a struct or an array of structs
+	Struct *ShaderStruct
+}
+
+// ShaderStruct is a struct type as declared in a shader
+type ShaderStruct struct {
+	Name    string
+	Members []ShaderMember
+}
+
+// ShaderBlock is a uniform block, storage block or push constant block in a shader
+type ShaderBlock struct {
+	// Name is the block's instance name, or its type name if the instance is unnamed
+	Name          string
+	Set, Binding  int
+	PushConstants bool
+	Struct        *ShaderStruct
+}
+
+type spirvModule struct {
+	names             map[uint32]string
+	memberNames       map[uint32]map[uint32]string
+	decorations       map[uint32]map[uint32]uint32
+	memberDecorations map[uint32]map[uint32]map[uint32]uint32
+	types             map[uint32]spirv.Type
+	structs           map[uint32]*ShaderStruct
+}
+
+// ReflectBlocks finds every uniform, storage and push constant block declared in a
+// SPIR-V module
+func ReflectBlocks(code []uint32) ([]ShaderBlock, error) {
+	module := &spirvModule{
+		names:             make(map[uint32]string),
+		memberNames:       make(map[uint32]map[uint32]string),
+		decorations:       make(map[uint32]map[uint32]uint32),
+		memberDecorations: make(map[uint32]map[uint32]map[uint32]uint32),
+		types:             make(map[uint32]spirv.Type),
+		structs:           make(map[uint32]*ShaderStruct),
+	}
+
+	type variable struct {
+		id, pointerType, storageClass uint32
+	}
+	var variables []variable
+
+	err := spirv.Instructions(code, func(opcode int, operands []uint32) {
+		switch opcode {
+		case spirv.OpName:
+			module.names[operands[0]] = spirv.DecodeString(operands[1:])
+		case spirv.OpMemberName:
+			if module.memberNames[operands[0]] == nil {
+				module.memberNames[operands[0]] = make(map[uint32]string)
+			}
+			module.memberNames[operands[0]][operands[1]] = spirv.DecodeString(operands[2:])
+		case spirv.OpDecorate:
+			if module.decorations[operands[0]] == nil {
+				module.decorations[operands[0]] = make(map[uint32]uint32)
+			}
+			var value uint32
+			if len(operands) > 2 {
+				value = operands[2]
+			}
+			module.decorations[operands[0]][operands[1]] = value
+		case spirv.OpMemberDecorate:
+			structID, member := operands[0], operands[1]
+			if module.memberDecorations[structID] == nil {
+				module.memberDecorations[structID] = make(map[uint32]map[uint32]uint32)
+			}
+			if module.memberDecorations[structID][member] == nil {
+				module.memberDecorations[structID][member] = make(map[uint32]uint32)
+			}
+			var value uint32
+			if len(operands) > 3 {
+				value = operands[3]
+			}
+			module.memberDecorations[structID][member][operands[2]] = value
+		case spirv.OpTypeBool, spirv.OpTypeInt, spirv.OpTypeFloat, spirv.OpTypeVector, spirv.OpTypeMatrix, spirv.OpTypeArray,
+			spirv.OpTypeRuntimeArray, spirv.OpTypeStruct, spirv.OpTypePointer:
+			module.types[operands[0]] = spirv.Type{Opcode: opcode, Operands: operands[1:]}
+		case spirv.OpVariable:
+			variables = append(variables, variable{id: operands[1], pointerType: operands[0], storageClass: operands[2]})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []ShaderBlock
+	for _, candidate := range variables {
+		if candidate.storageClass != spirv.StorageClassUniform &&
+			candidate.storageClass != spirv.StorageClassStorageBuffer &&
+			candidate.storageClass != spirv.StorageClassPushConstant {
+			continue
+		}
+
+		pointer, isPointer := module.types[candidate.pointerType]
+		if !isPointer || pointer.Opcode != spirv.OpTypePointer {
+			continue
+		}
+
+		// Arrays of blocks are bound as one descriptor binding with many elements
+		blockType := module.elementType(pointer.Operands[1])
+		blockDecorations := module.decorations[blockType]
+		_, isBlock := blockDecorations[spirv.DecorationBlock]
+		_, isBufferBlock := blockDecorations[spirv.DecorationBufferBlock]
+		if !isBlock && !isBufferBlock {
+			continue
+		}
+
+		block := ShaderBlock{
+			Name:          module.names[candidate.id],
+			Set:           int(module.decorations[candidate.id][spirv.DecorationDescriptorSet]),
+			Binding:       int(module.decorations[candidate.id][spirv.DecorationBinding]),
+			PushConstants: candidate.storageClass == spirv.StorageClassPushConstant,
+			Struct:        module.shaderStruct(blockType),
+		}
+		if block.Name == "" {
+			block.Name = block.Struct.Name
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// FindBlock returns the block in a SPIR-V module with the provided instance or type name
+func FindBlock(code []uint32, name string) (ShaderBlock, error) {
+	blocks, err := ReflectBlocks(code)
+	if err != nil {
+		return ShaderBlock{}, err
+	}
+
+	for _, block := range blocks {
+		if block.Name == name || block.Struct.Name == name {
+			return block, nil
+		}
+	}
+
+	return ShaderBlock{}, errors.Errorf("shader has no block named %s", name)
+}
+
+// elementType strips any arrays from typeID
+func (m *spirvModule) elementType(typeID uint32) uint32 {
+	for {
+		declared := m.types[typeID]
+		if declared.Opcode != spirv.OpTypeArray && declared.Opcode != spirv.OpTypeRuntimeArray {
+			return typeID
+		}
+		typeID = declared.Operands[0]
+	}
+}
+
+func (m *spirvModule) shaderStruct(structID uint32) *ShaderStruct {
+	existing, found := m.structs[structID]
+	if found {
+		return existing
+	}
+
+	declared := &ShaderStruct{Name: m.names[structID]}
+	m.structs[structID] = declared
+
+	for memberIndex, memberType := range m.types[structID].Operands {
+		memberDecorations := m.memberDecorations[structID][uint32(memberIndex)]
+		member := ShaderMember{
+			Name:         m.memberNames[structID][uint32(memberIndex)],
+			Offset:       int(memberDecorations[spirv.DecorationOffset]),
+			MatrixStride: int(memberDecorations[spirv.DecorationMatrixStride]),
+		}
+
+		if m.types[memberType].Opcode == spirv.OpTypeArray || m.types[memberType].Opcode == spirv.OpTypeRuntimeArray {
+			member.ArrayStride = int(m.decorations[memberType][spirv.DecorationArrayStride])
+		}
+
+		elementType := m.elementType(memberType)
+		if m.types[elementType].Opcode == spirv.OpTypeStruct {
+			member.Struct = m.shaderStruct(elementType)
+		}
+
+		declared.Members = append(declared.Members, member)
+	}
+
+	return declared
+}
@@ -0,0 +1,291 @@
+package breadcrumbs
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct breadcrumbsCheckpointData {
+	int32_t sType;
+	void* pNext;
+	uint32_t stage;
+	void* pCheckpointMarker;
+} breadcrumbsCheckpointData;
+
+typedef void (*breadcrumbsWriteBufferMarkerFunc)(void* commandBuffer, uint32_t pipelineStage, uint64_t dstBuffer, uint64_t dstOffset, uint32_t marker);
+typedef void (*breadcrumbsSetCheckpointFunc)(void* commandBuffer, const void* pCheckpointMarker);
+typedef void (*breadcrumbsGetQueueCheckpointDataFunc)(void* queue, uint32_t* pCheckpointDataCount, breadcrumbsCheckpointData* pCheckpointData);
+
+static void breadcrumbsWriteBufferMarker(void* fn, uintptr_t commandBuffer, uint32_t stage, uintptr_t buffer, uint64_t offset, uint32_t marker) {
+	((breadcrumbsWriteBufferMarkerFunc)fn)((void*)commandBuffer, stage, (uint64_t)buffer, offset, marker);
+}
+
+static void breadcrumbsSetCheckpoint(void* fn, uintptr_t commandBuffer, uint32_t marker) {
+	((breadcrumbsSetCheckpointFunc)fn)((void*)commandBuffer, (const void*)(uintptr_t)marker);
+}
+
+static uint32_t breadcrumbsGetCheckpoints(void* fn, uintptr_t queue, uint32_t count, uint32_t* stages, uint32_t* markers) {
+	breadcrumbsGetQueueCheckpointDataFunc getData = (breadcrumbsGetQueueCheckpointDataFunc)fn;
+
+	uint32_t available = 0;
+	getData((void*)queue, &available, NULL);
+	if (available > count) {
+		available = count;
+	}
+
+	breadcrumbsCheckpointData* data = calloc(available, sizeof(breadcrumbsCheckpointData));
+	for (uint32_t i = 0; i < available; i++) {
+		// VK_STRUCTURE_TYPE_CHECKPOINT_DATA_NV
+		data[i].sType = 1000206000;
+	}
+	getData((void*)queue, &available, data);
+
+	for (uint32_t i = 0; i < available; i++) {
+		stages[i] = data[i].stage;
+		markers[i] = (uint32_t)(uintptr_t)data[i].pCheckpointMarker;
+	}
+	free(data);
+
+	return available;
+}
+*/
+import "C"
+import (
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+const (
+	AMDBufferMarkerExtensionName               = "VK_AMD_buffer_marker"
+	NVDeviceDiagnosticCheckpointsExtensionName = "VK_NV_device_diagnostic_checkpoints"
+
+	maxCheckpoints = 32
+)
+
+type gpuMarkers interface {
+	write(commandBuffer core1_0.CommandBuffer, id uint32)
+	report(w io.Writer, queues []core1_0.Queue, find func(id uint32) (Breadcrumb, bool))
+	destroy()
+}
+
+// DeviceExtension returns the GPU marker extension that should be enabled on the device,
+// chosen from availableExtensions, or an empty string if neither is supported
+func DeviceExtension(availableExtensions map[string]*core1_0.ExtensionProperties) string {
+	if _, ok := availableExtensions[AMDBufferMarkerExtensionName]; ok {
+		return AMDBufferMarkerExtensionName
+	}
+
+	if _, ok := availableExtensions[NVDeviceDiagnosticCheckpointsExtensionName]; ok {
+		return NVDeviceDiagnosticCheckpointsExtensionName
+	}
+
+	return ""
+}
+
+// AttachDevice enables GPU markers if one of the extensions returned by DeviceExtension is
+// in enabledExtensions. It does nothing otherwise.
+func (t *Tracker) AttachDevice(deviceDriver core1_0.CoreDeviceDriver, memoryProperties *core1_0.PhysicalDeviceMemoryProperties, enabledExtensions []string) error {
+	var gpu gpuMarkers
+	var err error
+
+	for _, extension := range enabledExtensions {
+		if extension == AMDBufferMarkerExtensionName {
+			gpu, err = newBufferMarkers(deviceDriver, memoryProperties)
+			break
+		} else if extension == NVDeviceDiagnosticCheckpointsExtensionName {
+			gpu, err = newCheckpoints(deviceDriver)
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.gpu = gpu
+	return nil
+}
+
+// Destroy frees any resources used for GPU markers. It must be called before the device
+// is destroyed.
+func (t *Tracker) Destroy() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.gpu != nil {
+		t.gpu.destroy()
+		t.gpu = nil
+	}
+}
+
+func loadProc(deviceDriver core1_0.CoreDeviceDriver, name string) (unsafe.Pointer, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	proc := deviceDriver.Loader().LoadProcAddr((*loader.Char)(unsafe.Pointer(cName)))
+	if proc == nil {
+		return nil, errors.Errorf("could not load %s", name)
+	}
+
+	return proc, nil
+}
+
+// bufferMarkers uses VK_AMD_buffer_marker to write the ID of each breadcrumb into a
+// host-visible buffer when the GPU starts and finishes it
+type bufferMarkers struct {
+	deviceDriver core1_0.CoreDeviceDriver
+	writeMarker  unsafe.Pointer
+	buffer       core1_0.Buffer
+	memory       core1_0.DeviceMemory
+	values       []uint32
+}
+
+func newBufferMarkers(deviceDriver core1_0.CoreDeviceDriver, memoryProperties *core1_0.PhysicalDeviceMemoryProperties) (*bufferMarkers, error) {
+	writeMarker, err := loadProc(deviceDriver, "vkCmdWriteBufferMarkerAMD")
+	if err != nil {
+		return nil, err
+	}
+
+	markers := &bufferMarkers{
+		deviceDriver: deviceDriver,
+		writeMarker:  writeMarker,
+	}
+
+	markers.buffer, _, err = deviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+		Size:        8,
+		Usage:       core1_0.BufferUsageTransferDst,
+		SharingMode: core1_0.SharingModeExclusive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	memReqs := deviceDriver.GetBufferMemoryRequirements(markers.buffer)
+	memoryTypeIndex := -1
+	flags := core1_0.MemoryPropertyHostVisible | core1_0.MemoryPropertyHostCoherent
+	for typeIndex, memType := range memoryProperties.MemoryTypes {
+		if memReqs.MemoryTypeBits&(1<<typeIndex) != 0 && memType.PropertyFlags&flags == flags {
+			memoryTypeIndex = typeIndex
+			break
+		}
+	}
+	if memoryTypeIndex < 0 {
+		deviceDriver.DestroyBuffer(markers.buffer, nil)
+		return nil, errors.New("no host-coherent memory type for the breadcrumb marker buffer")
+	}
+
+	markers.memory, _, err = deviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+		AllocationSize:  memReqs.Size,
+		MemoryTypeIndex: memoryTypeIndex,
+	})
+	if err != nil {
+		deviceDriver.DestroyBuffer(markers.buffer, nil)
+		return nil, err
+	}
+
+	_, err = deviceDriver.BindBufferMemory(markers.buffer, markers.memory, 0)
+	if err != nil {
+		markers.destroy()
+		return nil, err
+	}
+
+	// The memory stays mapped so it can still be read after the device is lost
+	data, _, err := deviceDriver.MapMemory(markers.memory, 0, 8, 0)
+	if err != nil {
+		markers.destroy()
+		return nil, err
+	}
+	markers.values = unsafe.Slice((*uint32)(data), 2)
+	markers.values[0] = 0
+	markers.values[1] = 0
+
+	return markers, nil
+}
+
+func (m *bufferMarkers) write(commandBuffer core1_0.CommandBuffer, id uint32) {
+	C.breadcrumbsWriteBufferMarker(m.writeMarker, C.uintptr_t(commandBuffer.Handle()), C.uint32_t(core1_0.PipelineStageTopOfPipe),
+		C.uintptr_t(m.buffer.Handle()), 0, C.uint32_t(id))
+	C.breadcrumbsWriteBufferMarker(m.writeMarker, C.uintptr_t(commandBuffer.Handle()), C.uint32_t(core1_0.PipelineStageBottomOfPipe),
+		C.uintptr_t(m.buffer.Handle()), 4, C.uint32_t(id))
+}
+
+func (m *bufferMarkers) report(w io.Writer, queues []core1_0.Queue, find func(id uint32) (Breadcrumb, bool)) {
+	fmt.Fprintln(w, "GPU progress (VK_AMD_buffer_marker):")
+	writeMarker(w, "last started", m.values[0], find)
+	writeMarker(w, "last finished", m.values[1], find)
+}
+
+func (m *bufferMarkers) destroy() {
+	if m.values != nil {
+		m.deviceDriver.UnmapMemory(m.memory)
+		m.values = nil
+	}
+	m.deviceDriver.DestroyBuffer(m.buffer, nil)
+	m.deviceDriver.FreeMemory(m.memory, nil)
+}
+
+// checkpoints uses VK_NV_device_diagnostic_checkpoints to tag each breadcrumb with its
+// ID, which the driver reports per queue along with the last stage it reached
+type checkpoints struct {
+	setCheckpoint     unsafe.Pointer
+	getCheckpointData unsafe.Pointer
+}
+
+func newCheckpoints(deviceDriver core1_0.CoreDeviceDriver) (*checkpoints, error) {
+	setCheckpoint, err := loadProc(deviceDriver, "vkCmdSetCheckpointNV")
+	if err != nil {
+		return nil, err
+	}
+
+	getCheckpointData, err := loadProc(deviceDriver, "vkGetQueueCheckpointDataNV")
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkpoints{
+		setCheckpoint:     setCheckpoint,
+		getCheckpointData: getCheckpointData,
+	}, nil
+}
+
+func (c *checkpoints) write(commandBuffer core1_0.CommandBuffer, id uint32) {
+	C.breadcrumbsSetCheckpoint(c.setCheckpoint, C.uintptr_t(commandBuffer.Handle()), C.uint32_t(id))
+}
+
+func (c *checkpoints) report(w io.Writer, queues []core1_0.Queue, find func(id uint32) (Breadcrumb, bool)) {
+	fmt.Fprintln(w, "GPU progress (VK_NV_device_diagnostic_checkpoints):")
+
+	stages := make([]C.uint32_t, maxCheckpoints)
+	markers := make([]C.uint32_t, maxCheckpoints)
+	for _, queue := range queues {
+		count := C.breadcrumbsGetCheckpoints(c.getCheckpointData, C.uintptr_t(queue.Handle()), maxCheckpoints, &stages[0], &markers[0])
+
+		fmt.Fprintf(w, "\tQueue %#x:\n", queue.Handle())
+		for index := 0; index < int(count); index++ {
+			writeMarker(w, "\treached "+core1_0.PipelineStageFlags(stages[index]).String(), uint32(markers[index]), find)
+		}
+	}
+}
+
+func (c *checkpoints) destroy() {}
+
+func writeMarker(w io.Writer, description string, id uint32, find func(id uint32) (Breadcrumb, bool)) {
+	if id == 0 {
+		fmt.Fprintf(w, "\t%s: (none)\n", description)
+		return
+	}
+
+	crumb, found := find(id)
+	if !found {
+		fmt.Fprintf(w, "\t%s: #%d (no longer retained)\n", description, id)
+		return
+	}
+
+	fmt.Fprintf(w, "\t%s: %s\n", description, crumb)
+}
@@ -0,0 +1,346 @@
+package breadcrumbs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+const defaultRetained = 8
+
+type Kind int
+
+const (
+	KindPass Kind = iota
+	KindDraw
+	KindDispatch
+	KindMarker
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindPass:
+		return "pass"
+	case KindDraw:
+		return "draw"
+	case KindDispatch:
+		return "dispatch"
+	}
+
+	return "marker"
+}
+
+// Breadcrumb is a CPU-side record of a single command recorded into a command buffer
+type Breadcrumb struct {
+	// ID is unique across the Tracker and is the value written by GPU markers
+	ID    uint32
+	Kind  Kind
+	Label string
+	// Pipeline is the pipeline that was bound in the command buffer when the breadcrumb
+	// was recorded, if one was registered with BindPipeline
+	Pipeline string
+	Frame    uint64
+}
+
+func (b Breadcrumb) String() string {
+	if b.Pipeline == "" {
+		return fmt.Sprintf("#%d %s %q", b.ID, b.Kind, b.Label)
+	}
+
+	return fmt.Sprintf("#%d %s %q (pipeline %q)", b.ID, b.Kind, b.Label, b.Pipeline)
+}
+
+type commandTrail struct {
+	pipeline string
+	crumbs   []Breadcrumb
+}
+
+type submission struct {
+	id        uint64
+	frame     uint64
+	fence     core1_0.Fence
+	completed bool
+	crumbs    []Breadcrumb
+}
+
+// Tracker keeps a trail of breadcrumbs for each command buffer as it is recorded, and
+// a ring of the most recent submissions. When a call returns VK_ERROR_DEVICE_LOST,
+// Check writes the breadcrumbs that were in flight and the ones that most recently
+// completed, which narrows down the command that hung or crashed the GPU.
+//
+// If the device was created with one of the extensions returned by DeviceExtension,
+// each breadcrumb also writes a GPU-side marker and the report includes the last marker
+// the GPU reached.
+type Tracker struct {
+	// Output receives the report when device loss is detected. It defaults to os.Stderr.
+	Output io.Writer
+
+	lock           sync.Mutex
+	retained       int
+	frame          uint64
+	nextID         uint32
+	nextSubmission uint64
+	trails         map[loader.VulkanHandle]*commandTrail
+	submissions    []*submission
+	queues         map[loader.VulkanHandle]core1_0.Queue
+	gpu            gpuMarkers
+	reported       bool
+}
+
+// New creates a Tracker which keeps the last retained submissions, or a default number
+// if retained is 0
+func New(retained int) *Tracker {
+	if retained <= 0 {
+		retained = defaultRetained
+	}
+
+	return &Tracker{
+		Output:   os.Stderr,
+		retained: retained,
+		trails:   make(map[loader.VulkanHandle]*commandTrail),
+		queues:   make(map[loader.VulkanHandle]core1_0.Queue),
+	}
+}
+
+// BeginFrame sets the frame number attached to breadcrumbs recorded from now on
+func (t *Tracker) BeginFrame(frame uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.frame = frame
+}
+
+// Reset clears the trail of commandBuffer. It should be called whenever the command
+// buffer is begun or reset, since its previous commands won't be submitted again.
+func (t *Tracker) Reset(commandBuffer core1_0.CommandBuffer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.trails, loader.VulkanHandle(commandBuffer.Handle()))
+}
+
+// BindPipeline records that name is the pipeline bound in commandBuffer, which is attached
+// to every later breadcrumb in that command buffer
+func (t *Tracker) BindPipeline(commandBuffer core1_0.CommandBuffer, name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.trail(commandBuffer).pipeline = name
+}
+
+func (t *Tracker) Pass(commandBuffer core1_0.CommandBuffer, label string) {
+	t.record(commandBuffer, KindPass, label)
+}
+
+func (t *Tracker) Draw(commandBuffer core1_0.CommandBuffer, label string) {
+	t.record(commandBuffer, KindDraw, label)
+}
+
+func (t *Tracker) Dispatch(commandBuffer core1_0.CommandBuffer, label string) {
+	t.record(commandBuffer, KindDispatch, label)
+}
+
+func (t *Tracker) Mark(commandBuffer core1_0.CommandBuffer, label string) {
+	t.record(commandBuffer, KindMarker, label)
+}
+
+func (t *Tracker) trail(commandBuffer core1_0.CommandBuffer) *commandTrail {
+	trail, exists := t.trails[loader.VulkanHandle(commandBuffer.Handle())]
+	if !exists {
+		trail = &commandTrail{}
+		t.trails[loader.VulkanHandle(commandBuffer.Handle())] = trail
+	}
+
+	return trail
+}
+
+func (t *Tracker) record(commandBuffer core1_0.CommandBuffer, kind Kind, label string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// 0 is left unused so an untouched GPU marker can't be mistaken for a breadcrumb
+	t.nextID++
+	trail := t.trail(commandBuffer)
+	crumb := Breadcrumb{
+		ID:       t.nextID,
+		Kind:     kind,
+		Label:    label,
+		Pipeline: trail.pipeline,
+		Frame:    t.frame,
+	}
+	trail.crumbs = append(trail.crumbs, crumb)
+
+	if t.gpu != nil {
+		t.gpu.write(commandBuffer, crumb.ID)
+	}
+}
+
+// Submitted moves the trails of commandBuffers into the submission ring and returns an
+// identifier for the submission. fence may be left empty, in which case the submission must be
+// marked complete with CompleteSubmission.
+func (t *Tracker) Submitted(queue core1_0.Queue, fence core1_0.Fence, commandBuffers ...core1_0.CommandBuffer) uint64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.nextSubmission++
+	sub := &submission{
+		id:    t.nextSubmission,
+		frame: t.frame,
+		fence: fence,
+	}
+
+	for _, commandBuffer := range commandBuffers {
+		trail, exists := t.trails[loader.VulkanHandle(commandBuffer.Handle())]
+		if exists {
+			sub.crumbs = append(sub.crumbs, trail.crumbs...)
+		}
+	}
+
+	if queue.Handle() != 0 {
+		t.queues[loader.VulkanHandle(queue.Handle())] = queue
+	}
+
+	t.submissions = append(t.submissions, sub)
+	if len(t.submissions) > t.retained {
+		t.submissions = t.submissions[len(t.submissions)-t.retained:]
+	}
+
+	return sub.id
+}
+
+// Completed marks every submission made with fence as complete. It should be called once
+// the fence has been signaled.
+func (t *Tracker) Completed(fence core1_0.Fence) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, sub := range t.submissions {
+		if sub.fence.Handle() != 0 && sub.fence.Handle() == fence.Handle() {
+			sub.completed = true
+		}
+	}
+}
+
+// CompleteSubmission marks the submission with the provided identifier, and every one
+// before it, as complete
+func (t *Tracker) CompleteSubmission(id uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.completeThrough(id)
+}
+
+// CompleteAll marks every submission as complete, for use after vkDeviceWaitIdle or
+// vkQueueWaitIdle
+func (t *Tracker) CompleteAll() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.completeThrough(t.nextSubmission)
+}
+
+func (t *Tracker) completeThrough(id uint64) {
+	for _, sub := range t.submissions {
+		if sub.id <= id {
+			sub.completed = true
+		}
+	}
+}
+
+// Check writes a report to Output if res is VK_ERROR_DEVICE_LOST and returns true. The
+// report is only written the first time device loss is detected.
+func (t *Tracker) Check(res common.VkResult) bool {
+	if res != core1_0.VKErrorDeviceLost {
+		return false
+	}
+
+	t.lock.Lock()
+	alreadyReported := t.reported
+	t.reported = true
+	t.lock.Unlock()
+
+	if !alreadyReported {
+		t.Report(t.Output)
+	}
+
+	return true
+}
+
+// Report writes the in-flight and most recently completed breadcrumbs to w
+func (t *Tracker) Report(w io.Writer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	fmt.Fprintf(w, "DEVICE LOST during frame %d\n", t.frame)
+
+	var pending, completed []*submission
+	activePipelines := make(map[string]struct{})
+	for _, sub := range t.submissions {
+		if sub.completed {
+			completed = append(completed, sub)
+			continue
+		}
+
+		pending = append(pending, sub)
+		for _, crumb := range sub.crumbs {
+			if crumb.Pipeline != "" {
+				activePipelines[crumb.Pipeline] = struct{}{}
+			}
+		}
+	}
+
+	if len(activePipelines) > 0 {
+		pipelines := make([]string, 0, len(activePipelines))
+		for pipeline := range activePipelines {
+			pipelines = append(pipelines, pipeline)
+		}
+		sort.Strings(pipelines)
+		fmt.Fprintf(w, "Active pipelines: %s\n", strings.Join(pipelines, ", "))
+	}
+
+	fmt.Fprintln(w, "In-flight submissions:")
+	writeSubmissions(w, pending)
+	fmt.Fprintln(w, "Completed submissions:")
+	writeSubmissions(w, completed)
+
+	if t.gpu != nil {
+		queues := make([]core1_0.Queue, 0, len(t.queues))
+		for _, queue := range t.queues {
+			queues = append(queues, queue)
+		}
+		t.gpu.report(w, queues, t.findBreadcrumb)
+	}
+}
+
+func writeSubmissions(w io.Writer, submissions []*submission) {
+	if len(submissions) == 0 {
+		fmt.Fprintln(w, "\t(none)")
+		return
+	}
+
+	for _, sub := range submissions {
+		fmt.Fprintf(w, "\tSubmission %d, frame %d:\n", sub.id, sub.frame)
+		for _, crumb := range sub.crumbs {
+			fmt.Fprintf(w, "\t\t%s\n", crumb)
+		}
+	}
+}
+
+func (t *Tracker) findBreadcrumb(id uint32) (Breadcrumb, bool) {
+	for _, sub := range t.submissions {
+		for _, crumb := range sub.crumbs {
+			if crumb.ID == id {
+				return crumb, true
+			}
+		}
+	}
+
+	return Breadcrumb{}, false
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"os"
+
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/validation"
 )
 
 func (i *SampleInfo) ProcessCommandLineArgs() error {
@@ -11,10 +13,13 @@ func (i *SampleInfo) ProcessCommandLineArgs() error {
 	for _, arg := range args {
 		if arg == "--save-images" {
 			i.SaveImages = true
+		} else if i.Validation.ParseArg(arg) {
+			continue
 		} else if arg == "--help" || arg == "-h" {
 			fmt.Println("\nOther options")
 			fmt.Println("\t--save-images")
 			fmt.Println("\t\tSave tests images as ppm files in current working directory")
+			validation.PrintUsage()
 			os.Exit(0)
 			return nil
 		} else {
@@ -0,0 +1,172 @@
+package conditional
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct conditionalBeginInfo {
+	int32_t sType;
+	const void* pNext;
+	uint64_t buffer;
+	uint64_t offset;
+	uint32_t flags;
+} conditionalBeginInfo;
+
+typedef struct conditionalFeatures {
+	int32_t sType;
+	void* pNext;
+	uint32_t conditionalRendering;
+	uint32_t inheritedConditionalRendering;
+} conditionalFeatures;
+
+typedef void (*conditionalBeginFunc)(void* commandBuffer, const conditionalBeginInfo* pConditionalRenderingBegin);
+typedef void (*conditionalEndFunc)(void* commandBuffer);
+
+static void conditionalBegin(void* fn, uintptr_t commandBuffer, uintptr_t buffer, uint64_t offset, uint32_t flags) {
+	conditionalBeginInfo beginInfo = {
+		// VK_STRUCTURE_TYPE_CONDITIONAL_RENDERING_BEGIN_INFO_EXT
+		.sType = 1000081002,
+		.pNext = NULL,
+		.buffer = (uint64_t)buffer,
+		.offset = offset,
+		.flags = flags,
+	};
+	((conditionalBeginFunc)fn)((void*)commandBuffer, &beginInfo);
+}
+
+static void conditionalEnd(void* fn, uintptr_t commandBuffer) {
+	((conditionalEndFunc)fn)((void*)commandBuffer);
+}
+*/
+import "C"
+import (
+	"unsafe"
+
+	"github.com/CannibalVox/cgoparam"
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+// ExtensionName is VK_EXT_conditional_rendering, which vkngwrapper has no wrapper for
+const ExtensionName = "VK_EXT_conditional_rendering"
+
+const (
+	// BufferUsageConditionalRendering is VK_BUFFER_USAGE_CONDITIONAL_RENDERING_BIT_EXT
+	BufferUsageConditionalRendering core1_0.BufferUsageFlags = 0x00000200
+	// PipelineStageConditionalRendering is VK_PIPELINE_STAGE_CONDITIONAL_RENDERING_BIT_EXT
+	PipelineStageConditionalRendering core1_0.PipelineStageFlags = 0x00040000
+	// AccessConditionalRenderingRead is VK_ACCESS_CONDITIONAL_RENDERING_READ_BIT_EXT
+	AccessConditionalRenderingRead core1_0.AccessFlags = 0x00100000
+
+	// structureTypeFeatures is VK_STRUCTURE_TYPE_PHYSICAL_DEVICE_CONDITIONAL_RENDERING_FEATURES_EXT
+	structureTypeFeatures = 1000081001
+	// flagInverted is VK_CONDITIONAL_RENDERING_INVERTED_BIT_EXT
+	flagInverted = 0x00000001
+)
+
+// Features is VkPhysicalDeviceConditionalRenderingFeaturesEXT, to be chained onto
+// core1_0.DeviceCreateInfo to enable the extension's features, or onto
+// core1_1.PhysicalDeviceFeatures2 to query them
+type Features struct {
+	ConditionalRendering          bool
+	InheritedConditionalRendering bool
+
+	common.NextOptions
+	common.NextOutData
+}
+
+func vkBool(value bool) C.uint32_t {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (o Features) PopulateCPointer(allocator *cgoparam.Allocator, preallocatedPointer unsafe.Pointer, next unsafe.Pointer) (unsafe.Pointer, error) {
+	if preallocatedPointer == unsafe.Pointer(nil) {
+		preallocatedPointer = allocator.Malloc(C.sizeof_struct_conditionalFeatures)
+	}
+
+	features := (*C.conditionalFeatures)(preallocatedPointer)
+	features.sType = structureTypeFeatures
+	features.pNext = next
+	features.conditionalRendering = vkBool(o.ConditionalRendering)
+	features.inheritedConditionalRendering = vkBool(o.InheritedConditionalRendering)
+
+	return preallocatedPointer, nil
+}
+
+func (o *Features) PopulateHeader(allocator *cgoparam.Allocator, preallocatedPointer unsafe.Pointer, next unsafe.Pointer) (unsafe.Pointer, error) {
+	if preallocatedPointer == unsafe.Pointer(nil) {
+		preallocatedPointer = allocator.Malloc(C.sizeof_struct_conditionalFeatures)
+	}
+
+	features := (*C.conditionalFeatures)(preallocatedPointer)
+	features.sType = structureTypeFeatures
+	features.pNext = next
+
+	return preallocatedPointer, nil
+}
+
+func (o *Features) PopulateOutData(cDataPointer unsafe.Pointer, helpers ...any) (next unsafe.Pointer, err error) {
+	features := (*C.conditionalFeatures)(cDataPointer)
+	o.ConditionalRendering = features.conditionalRendering != 0
+	o.InheritedConditionalRendering = features.inheritedConditionalRendering != 0
+
+	return features.pNext, nil
+}
+
+// Driver records conditional rendering commands
+type Driver struct {
+	begin unsafe.Pointer
+	end   unsafe.Pointer
+}
+
+// New loads the extension's commands from a device that was created with ExtensionName
+// enabled
+func New(deviceDriver core1_0.CoreDeviceDriver) (*Driver, error) {
+	begin, err := loadProc(deviceDriver, "vkCmdBeginConditionalRenderingEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := loadProc(deviceDriver, "vkCmdEndConditionalRenderingEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Driver{begin: begin, end: end}, nil
+}
+
+func loadProc(deviceDriver core1_0.CoreDeviceDriver, name string) (unsafe.Pointer, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+
+	proc := deviceDriver.Loader().LoadProcAddr((*loader.Char)(unsafe.Pointer(cName)))
+	if proc == nil {
+		return nil, errors.Errorf("could not load %s", name)
+	}
+
+	return proc, nil
+}
+
+// CmdBeginConditionalRendering discards the draws and dispatches recorded until
+// CmdEndConditionalRendering if the 32-bit value at offset in buffer is zero, or if it is
+// not zero when inverted is set. buffer must be created with
+// BufferUsageConditionalRendering, and offset must be a multiple of 4.
+func (d *Driver) CmdBeginConditionalRendering(commandBuffer core1_0.CommandBuffer, buffer core1_0.Buffer, offset int, inverted bool) {
+	var flags C.uint32_t
+	if inverted {
+		flags = flagInverted
+	}
+
+	C.conditionalBegin(d.begin, C.uintptr_t(commandBuffer.Handle()), C.uintptr_t(buffer.Handle()), C.uint64_t(offset), flags)
+}
+
+// CmdEndConditionalRendering ends the conditional rendering begun by
+// CmdBeginConditionalRendering
+func (d *Driver) CmdEndConditionalRendering(commandBuffer core1_0.CommandBuffer) {
+	C.conditionalEnd(d.end, C.uintptr_t(commandBuffer.Handle()))
+}
@@ -1,13 +1,13 @@
 package utils
 
 type Vertex struct {
-	PosX, PosY, PosZ, PosW float32
-	R, G, B, A             float32
+	PosX, PosY, PosZ, PosW float32 `vk:"location=0"`
+	R, G, B, A             float32 `vk:"location=1"`
 }
 
 type VertexUV struct {
-	PosX, PosY, PosZ, PosW float32
-	U, V                   float32
+	PosX, PosY, PosZ, PosW float32 `vk:"location=0"`
+	U, V                   float32 `vk:"location=1"`
 }
 
 //var VBData = []Vertex{
@@ -0,0 +1,196 @@
+package debugnames
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
+	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
+)
+
+// Namer attaches debug names to Vulkan objects and opens debug label regions through
+// ext_debug_utils. A nil *Namer is valid and does nothing, so callers don't need to check
+// whether the extension was enabled.
+type Namer struct {
+	driver ext_debug_utils.ExtensionDriver
+	device core1_0.Device
+}
+
+// New returns a Namer for objects created from deviceDriver, or nil if ext_debug_utils
+// is not active on the instance
+func New(instanceDriver core1_0.CoreInstanceDriver, deviceDriver core1_0.CoreDeviceDriver) *Namer {
+	driver := ext_debug_utils.CreateExtensionDriverFromCoreDriver(instanceDriver)
+	if driver == nil {
+		return nil
+	}
+
+	return &Namer{
+		driver: driver,
+		device: deviceDriver.Device(),
+	}
+}
+
+func (n *Namer) Enabled() bool {
+	return n != nil
+}
+
+// Name attaches a debug name to object, which must be one of the core1_0 handle types or
+// a khr_swapchain.Swapchain. Uninitialized objects are ignored.
+func (n *Namer) Name(object any, name string) error {
+	if n == nil {
+		return nil
+	}
+
+	objectType, handle, err := objectHandle(object)
+	if err != nil {
+		return err
+	}
+
+	if handle == 0 {
+		return nil
+	}
+
+	_, err = n.driver.SetDebugUtilsObjectName(n.device, ext_debug_utils.DebugUtilsObjectNameInfo{
+		ObjectName:   name,
+		ObjectHandle: handle,
+		ObjectType:   objectType,
+	})
+	return err
+}
+
+// Namef is Name with a formatted name
+func (n *Namer) Namef(object any, format string, args ...any) error {
+	if n == nil {
+		return nil
+	}
+
+	return n.Name(object, fmt.Sprintf(format, args...))
+}
+
+func objectHandle(object any) (core1_0.ObjectType, loader.VulkanHandle, error) {
+	switch o := object.(type) {
+	case core1_0.Buffer:
+		return core1_0.ObjectTypeBuffer, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.BufferView:
+		return core1_0.ObjectTypeBufferView, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.CommandBuffer:
+		return core1_0.ObjectTypeCommandBuffer, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.CommandPool:
+		return core1_0.ObjectTypeCommandPool, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.DescriptorPool:
+		return core1_0.ObjectTypeDescriptorPool, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.DescriptorSetLayout:
+		return core1_0.ObjectTypeDescriptorSetLayout, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.DescriptorSet:
+		return core1_0.ObjectTypeDescriptorSet, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.DeviceMemory:
+		return core1_0.ObjectTypeDeviceMemory, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Device:
+		return core1_0.ObjectTypeDevice, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Event:
+		return core1_0.ObjectTypeEvent, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Fence:
+		return core1_0.ObjectTypeFence, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Framebuffer:
+		return core1_0.ObjectTypeFramebuffer, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Image:
+		return core1_0.ObjectTypeImage, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.ImageView:
+		return core1_0.ObjectTypeImageView, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Pipeline:
+		return core1_0.ObjectTypePipeline, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.PipelineCache:
+		return core1_0.ObjectTypePipelineCache, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.PipelineLayout:
+		return core1_0.ObjectTypePipelineLayout, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.QueryPool:
+		return core1_0.ObjectTypeQueryPool, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Queue:
+		return core1_0.ObjectTypeQueue, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.RenderPass:
+		return core1_0.ObjectTypeRenderPass, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Sampler:
+		return core1_0.ObjectTypeSampler, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.Semaphore:
+		return core1_0.ObjectTypeSemaphore, loader.VulkanHandle(o.Handle()), nil
+	case core1_0.ShaderModule:
+		return core1_0.ObjectTypeShaderModule, loader.VulkanHandle(o.Handle()), nil
+	case khr_swapchain.Swapchain:
+		return khr_swapchain.ObjectTypeSwapchain, loader.VulkanHandle(o.Handle()), nil
+	}
+
+	return core1_0.ObjectTypeUnknown, 0, errors.Errorf("cannot name object of type %T", object)
+}
+
+// LabelScope is an open debug label region, closed by calling End. The zero value's End
+// does nothing.
+type LabelScope struct {
+	end func()
+}
+
+func (s LabelScope) End() {
+	if s.end != nil {
+		s.end()
+	}
+}
+
+// BeginCommandLabel opens a label region in commandBuffer. The region must be closed in the
+// same command buffer, usually with a deferred End.
+func (n *Namer) BeginCommandLabel(commandBuffer core1_0.CommandBuffer, name string, labelColor color.Color) LabelScope {
+	if n == nil {
+		return LabelScope{}
+	}
+
+	err := n.driver.CmdBeginDebugUtilsLabel(commandBuffer, label(name, labelColor))
+	if err != nil {
+		return LabelScope{}
+	}
+
+	return LabelScope{end: func() { n.driver.CmdEndDebugUtilsLabel(commandBuffer) }}
+}
+
+func (n *Namer) InsertCommandLabel(commandBuffer core1_0.CommandBuffer, name string, labelColor color.Color) error {
+	if n == nil {
+		return nil
+	}
+
+	return n.driver.CmdInsertDebugUtilsLabel(commandBuffer, label(name, labelColor))
+}
+
+// BeginQueueLabel opens a label region on queue, which covers every submission made until
+// End is called
+func (n *Namer) BeginQueueLabel(queue core1_0.Queue, name string, labelColor color.Color) LabelScope {
+	if n == nil {
+		return LabelScope{}
+	}
+
+	err := n.driver.QueueBeginDebugUtilsLabel(queue, label(name, labelColor))
+	if err != nil {
+		return LabelScope{}
+	}
+
+	return LabelScope{end: func() { n.driver.QueueEndDebugUtilsLabel(queue) }}
+}
+
+func (n *Namer) InsertQueueLabel(queue core1_0.Queue, name string, labelColor color.Color) error {
+	if n == nil {
+		return nil
+	}
+
+	return n.driver.QueueInsertDebugUtilsLabel(queue, label(name, labelColor))
+}
+
+func label(name string, labelColor color.Color) ext_debug_utils.DebugUtilsLabel {
+	// A zero color tells tools to pick their own
+	if labelColor == nil {
+		labelColor = color.RGBA{}
+	}
+
+	return ext_debug_utils.DebugUtilsLabel{
+		LabelName: name,
+		Color:     labelColor,
+	}
+}
@@ -0,0 +1,225 @@
+package deletion
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/framesync"
+)
+
+type deferred struct {
+	frame   uint64
+	destroy func()
+}
+
+// Queue destroys Vulkan objects once the GPU has finished every frame that might use
+// them, so they can be replaced while rendering continues instead of idling the device
+// first.
+//
+// Each request is tagged with the last frame submitted to a framesync.Counter when it is
+// made, and runs from Collect once the counter has completed that frame. Requests run in
+// the order they were made, so objects that depend on each other should be queued
+// dependents first, the same order they would be destroyed in directly.
+type Queue struct {
+	deviceDriver core1_0.DeviceDriver
+	frames       *framesync.Counter
+
+	lock    sync.Mutex
+	pending []deferred
+}
+
+// New creates an empty Queue that waits on frames
+func New(deviceDriver core1_0.DeviceDriver, frames *framesync.Counter) *Queue {
+	return &Queue{
+		deviceDriver: deviceDriver,
+		frames:       frames,
+	}
+}
+
+// Defer runs destroy once the GPU has finished every frame submitted so far. It is for
+// objects Destroy doesn't know about, and for cleanup that isn't a single destroy call,
+// such as releasing a pipeline back to a registry.
+func (q *Queue) Defer(destroy func()) {
+	q.DeferUntil(q.frames.Submitted(), destroy)
+}
+
+// DeferUntil runs destroy once the GPU has finished frame
+func (q *Queue) DeferUntil(frame uint64, destroy func()) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.pending = append(q.pending, deferred{frame: frame, destroy: destroy})
+}
+
+// Destroy destroys or frees each of objects once the GPU has finished every frame
+// submitted so far. Uninitialized objects are skipped, so fields that may not have been
+// created yet can be passed without checking. Nothing is queued if any object is of a
+// type Destroy doesn't know how to destroy.
+func (q *Queue) Destroy(objects ...any) error {
+	var destroys []func()
+	for _, object := range objects {
+		destroy, err := q.destroyer(object)
+		if err != nil {
+			return err
+		}
+
+		if destroy != nil {
+			destroys = append(destroys, destroy)
+		}
+	}
+
+	for _, destroy := range destroys {
+		q.Defer(destroy)
+	}
+	return nil
+}
+
+func (q *Queue) destroyer(object any) (func(), error) {
+	dd := q.deviceDriver
+
+	switch o := object.(type) {
+	case core1_0.Buffer:
+		if o.Initialized() {
+			return func() { dd.DestroyBuffer(o, nil) }, nil
+		}
+	case core1_0.BufferView:
+		if o.Initialized() {
+			return func() { dd.DestroyBufferView(o, nil) }, nil
+		}
+	case core1_0.CommandBuffer:
+		if o.Initialized() {
+			return func() { dd.FreeCommandBuffers(o) }, nil
+		}
+	case core1_0.CommandPool:
+		if o.Initialized() {
+			return func() { dd.DestroyCommandPool(o, nil) }, nil
+		}
+	case core1_0.DescriptorPool:
+		if o.Initialized() {
+			return func() { dd.DestroyDescriptorPool(o, nil) }, nil
+		}
+	case core1_0.DescriptorSetLayout:
+		if o.Initialized() {
+			return func() { dd.DestroyDescriptorSetLayout(o, nil) }, nil
+		}
+	case core1_0.DeviceMemory:
+		if o.Initialized() {
+			return func() { dd.FreeMemory(o, nil) }, nil
+		}
+	case core1_0.Event:
+		if o.Initialized() {
+			return func() { dd.DestroyEvent(o, nil) }, nil
+		}
+	case core1_0.Fence:
+		if o.Initialized() {
+			return func() { dd.DestroyFence(o, nil) }, nil
+		}
+	case core1_0.Framebuffer:
+		if o.Initialized() {
+			return func() { dd.DestroyFramebuffer(o, nil) }, nil
+		}
+	case core1_0.Image:
+		if o.Initialized() {
+			return func() { dd.DestroyImage(o, nil) }, nil
+		}
+	case core1_0.ImageView:
+		if o.Initialized() {
+			return func() { dd.DestroyImageView(o, nil) }, nil
+		}
+	case core1_0.Pipeline:
+		if o.Initialized() {
+			return func() { dd.DestroyPipeline(o, nil) }, nil
+		}
+	case core1_0.PipelineLayout:
+		if o.Initialized() {
+			return func() { dd.DestroyPipelineLayout(o, nil) }, nil
+		}
+	case core1_0.QueryPool:
+		if o.Initialized() {
+			return func() { dd.DestroyQueryPool(o, nil) }, nil
+		}
+	case core1_0.RenderPass:
+		if o.Initialized() {
+			return func() { dd.DestroyRenderPass(o, nil) }, nil
+		}
+	case core1_0.Sampler:
+		if o.Initialized() {
+			return func() { dd.DestroySampler(o, nil) }, nil
+		}
+	case core1_0.Semaphore:
+		if o.Initialized() {
+			return func() { dd.DestroySemaphore(o, nil) }, nil
+		}
+	case core1_0.ShaderModule:
+		if o.Initialized() {
+			return func() { dd.DestroyShaderModule(o, nil) }, nil
+		}
+	default:
+		return nil, errors.Errorf("cannot destroy a %T, use Defer instead", object)
+	}
+
+	return nil, nil
+}
+
+// Collect runs every request whose frame the GPU has finished, and returns how many ran.
+// Call it once a frame, after waiting for an earlier frame.
+func (q *Queue) Collect() (int, error) {
+	completed, err := q.frames.Completed()
+	if err != nil {
+		return 0, err
+	}
+
+	return q.runThrough(completed), nil
+}
+
+func (q *Queue) runThrough(completed uint64) int {
+	q.lock.Lock()
+	var ready []deferred
+	var waiting []deferred
+	for _, request := range q.pending {
+		if request.frame <= completed {
+			ready = append(ready, request)
+		} else {
+			waiting = append(waiting, request)
+		}
+	}
+	q.pending = waiting
+	q.lock.Unlock()
+
+	// Destroy calls run without the lock, so they may queue more requests
+	for _, request := range ready {
+		request.destroy()
+	}
+
+	return len(ready)
+}
+
+// Flush waits for the GPU to finish every frame that a request is waiting on, or for ctx
+// to be done, then runs every request. Call it before shutting down.
+func (q *Queue) Flush(ctx context.Context) (common.VkResult, error) {
+	q.lock.Lock()
+	var last uint64
+	for _, request := range q.pending {
+		last = max(last, request.frame)
+	}
+	q.lock.Unlock()
+
+	res, err := q.frames.Wait(ctx, last)
+	if err != nil {
+		return res, err
+	}
+
+	q.runThrough(last)
+	return res, nil
+}
+
+// Len is the number of requests that haven't run yet
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.pending)
+}
@@ -0,0 +1,327 @@
+package descriptors
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_1"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+)
+
+const (
+	defaultSetsPerPool = 16
+	maxSetsPerPool     = 4096
+)
+
+// PoolRatio reserves PerSet descriptors of Type in each pool for every set the pool can
+// hold. A ratio of 0.5 for storage buffers means a pool with room for 32 sets has room
+// for 16 storage buffers.
+type PoolRatio struct {
+	Type   core1_0.DescriptorType
+	PerSet float32
+}
+
+// DefaultRatios covers the descriptor types used by the samples
+var DefaultRatios = []PoolRatio{
+	{Type: core1_0.DescriptorTypeUniformBuffer, PerSet: 2},
+	{Type: core1_0.DescriptorTypeUniformBufferDynamic, PerSet: 1},
+	{Type: core1_0.DescriptorTypeCombinedImageSampler, PerSet: 2},
+	{Type: core1_0.DescriptorTypeStorageBuffer, PerSet: 1},
+	{Type: core1_0.DescriptorTypeUniformTexelBuffer, PerSet: 0.5},
+	{Type: core1_0.DescriptorTypeInputAttachment, PerSet: 0.5},
+}
+
+// pool is a descriptor pool along with how much room it has left
+type pool struct {
+	handle core1_0.DescriptorPool
+	// maxSets and maxDescriptors are the pool's capacity, and sets and descriptors how
+	// much of it is left
+	maxSets        int
+	maxDescriptors map[core1_0.DescriptorType]int
+	sets           int
+	descriptors    map[core1_0.DescriptorType]int
+}
+
+func (p *pool) fits(sets int, descriptors map[core1_0.DescriptorType]int) bool {
+	if sets > p.sets {
+		return false
+	}
+
+	for descriptorType, count := range descriptors {
+		if count > p.descriptors[descriptorType] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *pool) take(sets int, descriptors map[core1_0.DescriptorType]int) {
+	p.sets -= sets
+	for descriptorType, count := range descriptors {
+		p.descriptors[descriptorType] -= count
+	}
+}
+
+func (p *pool) reset() {
+	p.sets = p.maxSets
+	maps.Copy(p.descriptors, p.maxDescriptors)
+}
+
+// Allocator allocates descriptor sets from a growing list of descriptor pools. It keeps
+// track of the sets and descriptors left in each pool, and moves on to another before one
+// runs out, creating a new one (larger than the last) if none are free. It may be used
+// from several goroutines at once.
+type Allocator struct {
+	// Names is used to attach debug names to created pools, and may be nil
+	Names *debugnames.Namer
+	// Name is the prefix for the debug names of created pools
+	Name string
+	// ReportsOutOfPoolMemory is set when the device is Vulkan 1.1 or newer, or has
+	// VK_KHR_maintenance1 enabled, so that allocating from a pool without room fails with
+	// VK_ERROR_OUT_OF_POOL_MEMORY. The Allocator then falls back to a new pool when an
+	// allocation fails that way, rather than returning the error.
+	ReportsOutOfPoolMemory bool
+
+	deviceDriver core1_0.CoreDeviceDriver
+	ratios       []PoolRatio
+
+	lock        sync.Mutex
+	setsPerPool int
+	ready       []*pool
+	full        []*pool
+	poolCount   int
+}
+
+// NewAllocator creates an Allocator whose first pool holds setsPerPool sets, or a default
+// size if setsPerPool is 0. If ratios is nil, DefaultRatios is used.
+func NewAllocator(deviceDriver core1_0.CoreDeviceDriver, setsPerPool int, ratios []PoolRatio) *Allocator {
+	if setsPerPool <= 0 {
+		setsPerPool = defaultSetsPerPool
+	}
+
+	if ratios == nil {
+		ratios = DefaultRatios
+	}
+
+	return &Allocator{
+		Name:         "Descriptor Pool",
+		deviceDriver: deviceDriver,
+		ratios:       ratios,
+		setsPerPool:  setsPerPool,
+	}
+}
+
+func isPoolExhausted(res common.VkResult) bool {
+	return res == core1_1.VkErrorOutOfPoolMemory || res == core1_0.VKErrorFragmentedPool
+}
+
+// Allocate allocates one descriptor set for each of layouts
+func (a *Allocator) Allocate(layouts ...*Layout) ([]core1_0.DescriptorSet, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	handles := make([]core1_0.DescriptorSetLayout, 0, len(layouts))
+	descriptors := make(map[core1_0.DescriptorType]int)
+	for _, layout := range layouts {
+		handles = append(handles, layout.Handle)
+		for _, binding := range layout.bindings {
+			descriptors[binding.DescriptorType] += binding.DescriptorCount
+		}
+	}
+
+	current, err := a.readyPool(len(layouts), descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, res, err := a.deviceDriver.AllocateDescriptorSets(core1_0.DescriptorSetAllocateInfo{
+		DescriptorPool: current.handle,
+		SetLayouts:     handles,
+	})
+	if err == nil {
+		current.take(len(layouts), descriptors)
+		return sets, nil
+	} else if !a.ReportsOutOfPoolMemory || !isPoolExhausted(res) {
+		return nil, err
+	}
+
+	// The pool had less room than it was counted as having, so retire it and try once
+	// more with a fresh one. If that fails too, the request can't be satisfied by any
+	// pool we'd create.
+	a.retire(current)
+
+	current, err = a.readyPool(len(layouts), descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, _, err = a.deviceDriver.AllocateDescriptorSets(core1_0.DescriptorSetAllocateInfo{
+		DescriptorPool: current.handle,
+		SetLayouts:     handles,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "allocating %d descriptor sets from a new pool", len(layouts))
+	}
+
+	current.take(len(layouts), descriptors)
+	return sets, nil
+}
+
+func (a *Allocator) retire(retired *pool) {
+	a.ready = slices.DeleteFunc(a.ready, func(ready *pool) bool { return ready == retired })
+	a.full = append(a.full, retired)
+}
+
+// readyPool returns the last pool in a.ready with room for sets sets holding descriptors,
+// retiring any after it that have none. If no pool has room, it creates one that does.
+func (a *Allocator) readyPool(sets int, descriptors map[core1_0.DescriptorType]int) (*pool, error) {
+	for len(a.ready) > 0 {
+		last := a.ready[len(a.ready)-1]
+		if last.fits(sets, descriptors) {
+			return last, nil
+		}
+		a.retire(last)
+	}
+
+	maxSets := max(a.setsPerPool, sets)
+	maxDescriptors := make(map[core1_0.DescriptorType]int)
+	for _, ratio := range a.ratios {
+		maxDescriptors[ratio.Type] = int(math.Ceil(float64(ratio.PerSet) * float64(maxSets)))
+	}
+	for descriptorType, count := range descriptors {
+		maxDescriptors[descriptorType] = max(maxDescriptors[descriptorType], count)
+	}
+
+	var poolSizes []core1_0.DescriptorPoolSize
+	for _, descriptorType := range slices.Sorted(maps.Keys(maxDescriptors)) {
+		if maxDescriptors[descriptorType] > 0 {
+			poolSizes = append(poolSizes, core1_0.DescriptorPoolSize{
+				Type:            descriptorType,
+				DescriptorCount: maxDescriptors[descriptorType],
+			})
+		}
+	}
+
+	handle, _, err := a.deviceDriver.CreateDescriptorPool(nil, core1_0.DescriptorPoolCreateInfo{
+		MaxSets:   maxSets,
+		PoolSizes: poolSizes,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating descriptor pool for %d sets", maxSets)
+	}
+
+	err = a.Names.Namef(handle, "%s %d", a.Name, a.poolCount)
+	if err != nil {
+		a.deviceDriver.DestroyDescriptorPool(handle, nil)
+		return nil, err
+	}
+	a.poolCount++
+
+	// Each pool we have to create is a sign the last one was too small
+	a.setsPerPool += a.setsPerPool / 2
+	if a.setsPerPool > maxSetsPerPool {
+		a.setsPerPool = maxSetsPerPool
+	}
+
+	created := &pool{
+		handle:         handle,
+		maxSets:        maxSets,
+		maxDescriptors: maxDescriptors,
+		sets:           maxSets,
+		descriptors:    maps.Clone(maxDescriptors),
+	}
+	a.ready = append(a.ready, created)
+	return created, nil
+}
+
+// Reset frees every set allocated from the Allocator at once, keeping the pools for
+// reuse. None of the sets may still be in use by the GPU.
+func (a *Allocator) Reset() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.ready = append(a.ready, a.full...)
+	a.full = a.full[:0]
+
+	for _, ready := range a.ready {
+		_, err := a.deviceDriver.ResetDescriptorPool(ready.handle, 0)
+		if err != nil {
+			return err
+		}
+		ready.reset()
+	}
+
+	return nil
+}
+
+// Destroy destroys every pool, freeing all sets allocated from the Allocator
+func (a *Allocator) Destroy() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, ready := range a.ready {
+		a.deviceDriver.DestroyDescriptorPool(ready.handle, nil)
+	}
+
+	for _, full := range a.full {
+		a.deviceDriver.DestroyDescriptorPool(full.handle, nil)
+	}
+
+	a.ready = nil
+	a.full = nil
+}
+
+// FrameAllocator keeps a separate Allocator for each frame in flight, for sets that are
+// only used for a single frame. Rather than freeing them individually, each frame's sets
+// are released in bulk when that frame comes around again.
+type FrameAllocator struct {
+	frames []*Allocator
+}
+
+// NewFrameAllocator creates a FrameAllocator for the provided number of frames in flight.
+// setsPerPool and ratios are passed to each frame's Allocator.
+func NewFrameAllocator(deviceDriver core1_0.CoreDeviceDriver, frames int, setsPerPool int, ratios []PoolRatio) *FrameAllocator {
+	frameAllocator := &FrameAllocator{}
+	for frameIndex := 0; frameIndex < frames; frameIndex++ {
+		frameAllocator.frames = append(frameAllocator.frames, NewAllocator(deviceDriver, setsPerPool, ratios))
+	}
+
+	return frameAllocator
+}
+
+// SetNames attaches debug names to every frame's pools
+func (f *FrameAllocator) SetNames(names *debugnames.Namer, name string) {
+	for frameIndex, allocator := range f.frames {
+		allocator.Names = names
+		allocator.Name = fmt.Sprintf("%s Frame %d", name, frameIndex)
+	}
+}
+
+// SetReportsOutOfPoolMemory sets ReportsOutOfPoolMemory on every frame's Allocator
+func (f *FrameAllocator) SetReportsOutOfPoolMemory(reports bool) {
+	for _, allocator := range f.frames {
+		allocator.ReportsOutOfPoolMemory = reports
+	}
+}
+
+// BeginFrame resets the Allocator for frameIndex and returns it. The previous sets from
+// that frame must no longer be in use, which is usually guaranteed by waiting on the
+// frame's fence first.
+func (f *FrameAllocator) BeginFrame(frameIndex int) (*Allocator, error) {
+	allocator := f.frames[frameIndex%len(f.frames)]
+	return allocator, allocator.Reset()
+}
+
+// Destroy destroys every frame's pools
+func (f *FrameAllocator) Destroy() {
+	for _, allocator := range f.frames {
+		allocator.Destroy()
+	}
+}
@@ -0,0 +1,157 @@
+package descriptors
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_1"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+type fakePool struct {
+	createInfo  core1_0.DescriptorPoolCreateInfo
+	sets        int
+	descriptors map[core1_0.DescriptorType]int
+}
+
+func (p *fakePool) reset() {
+	p.sets = p.createInfo.MaxSets
+	p.descriptors = make(map[core1_0.DescriptorType]int)
+	for _, size := range p.createInfo.PoolSizes {
+		p.descriptors[size.Type] += size.DescriptorCount
+	}
+}
+
+// fakeDriver implements the descriptor pool commands, failing allocations from a pool
+// without room as a Vulkan 1.1 device would
+type fakeDriver struct {
+	core1_0.CoreDeviceDriver
+
+	layouts map[loader.VkDescriptorSetLayout]core1_0.DescriptorSetLayoutCreateInfo
+	pools   []*fakePool
+	// failures counts allocations that failed, and failNext makes the next one fail
+	failures int
+	failNext bool
+}
+
+func (d *fakeDriver) CreateDescriptorPool(allocationCallbacks *loader.AllocationCallbacks, o core1_0.DescriptorPoolCreateInfo) (core1_0.DescriptorPool, common.VkResult, error) {
+	created := &fakePool{createInfo: o}
+	created.reset()
+	d.pools = append(d.pools, created)
+
+	return core1_0.InternalDescriptorPool(0, loader.VkDescriptorPool(len(d.pools)), common.Vulkan1_1), core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) AllocateDescriptorSets(o core1_0.DescriptorSetAllocateInfo) ([]core1_0.DescriptorSet, common.VkResult, error) {
+	allocated := d.pools[o.DescriptorPool.Handle()-1]
+	remaining := allocated.sets - len(o.SetLayouts)
+	descriptors := make(map[core1_0.DescriptorType]int)
+	for _, layout := range o.SetLayouts {
+		for _, binding := range d.layouts[layout.Handle()].Bindings {
+			descriptors[binding.DescriptorType] += binding.DescriptorCount
+		}
+	}
+
+	fails := d.failNext || remaining < 0
+	for descriptorType, count := range descriptors {
+		fails = fails || count > allocated.descriptors[descriptorType]
+	}
+	if fails {
+		d.failNext = false
+		d.failures++
+		return nil, core1_1.VkErrorOutOfPoolMemory, errors.New("out of pool memory")
+	}
+
+	allocated.sets = remaining
+	for descriptorType, count := range descriptors {
+		allocated.descriptors[descriptorType] -= count
+	}
+
+	sets := make([]core1_0.DescriptorSet, len(o.SetLayouts))
+	return sets, core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) ResetDescriptorPool(descriptorPool core1_0.DescriptorPool, flags core1_0.DescriptorPoolResetFlags) (common.VkResult, error) {
+	d.pools[descriptorPool.Handle()-1].reset()
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) DestroyDescriptorPool(descriptorPool core1_0.DescriptorPool, callbacks *loader.AllocationCallbacks) {
+}
+
+func (d *fakeDriver) layout(t *testing.T, bindings ...core1_0.DescriptorSetLayoutBinding) *Layout {
+	handle := loader.VkDescriptorSetLayout(len(d.layouts) + 1)
+	createInfo := core1_0.DescriptorSetLayoutCreateInfo{Bindings: bindings}
+	d.layouts[handle] = createInfo
+
+	layout, err := NewLayout(core1_0.InternalDescriptorSetLayout(0, handle, common.Vulkan1_1), createInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layout
+}
+
+func TestAllocatorMovesOnBeforePoolRunsOut(t *testing.T) {
+	driver := &fakeDriver{layouts: make(map[loader.VkDescriptorSetLayout]core1_0.DescriptorSetLayoutCreateInfo)}
+	allocator := NewAllocator(driver, 16, []PoolRatio{
+		{Type: core1_0.DescriptorTypeUniformBuffer, PerSet: 1},
+		{Type: core1_0.DescriptorTypeCombinedImageSampler, PerSet: 0.5},
+	})
+
+	// Each set uses more combined image samplers than the ratio allows for, and a storage
+	// image the ratios don't mention at all
+	layout := driver.layout(t,
+		core1_0.DescriptorSetLayoutBinding{Binding: 0, DescriptorType: core1_0.DescriptorTypeUniformBuffer, DescriptorCount: 1},
+		core1_0.DescriptorSetLayoutBinding{Binding: 1, DescriptorType: core1_0.DescriptorTypeCombinedImageSampler, DescriptorCount: 1},
+		core1_0.DescriptorSetLayoutBinding{Binding: 2, DescriptorType: core1_0.DescriptorTypeStorageImage, DescriptorCount: 1},
+	)
+
+	for range 40 {
+		_, err := allocator.Allocate(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if driver.failures != 0 {
+		t.Errorf("%d allocations ran out of pool memory", driver.failures)
+	}
+
+	// Reset restores every pool's room, so allocating as much again needs no new pools
+	err := allocator.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools := len(driver.pools)
+
+	for range 40 {
+		_, err := allocator.Allocate(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if driver.failures != 0 {
+		t.Errorf("%d allocations ran out of pool memory after Reset", driver.failures)
+	}
+	if len(driver.pools) != pools {
+		t.Errorf("created %d pools after Reset", len(driver.pools)-pools)
+	}
+}
+
+func TestAllocatorRetriesOnlyWhenReported(t *testing.T) {
+	for _, reports := range []bool{false, true} {
+		driver := &fakeDriver{layouts: make(map[loader.VkDescriptorSetLayout]core1_0.DescriptorSetLayoutCreateInfo)}
+		allocator := NewAllocator(driver, 0, nil)
+		allocator.ReportsOutOfPoolMemory = reports
+		layout := driver.layout(t, core1_0.DescriptorSetLayoutBinding{Binding: 0, DescriptorType: core1_0.DescriptorTypeUniformBuffer, DescriptorCount: 1})
+
+		driver.failNext = true
+		_, err := allocator.Allocate(layout)
+		if reports && err != nil {
+			t.Errorf("allocation wasn't retried from a new pool: %v", err)
+		} else if !reports && err == nil {
+			t.Error("allocation was retried on a device that needn't report VK_ERROR_OUT_OF_POOL_MEMORY")
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package descriptors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Layout is a descriptor set layout along with the bindings it was created with, so
+// groups can be checked against it
+type Layout struct {
+	Handle core1_0.DescriptorSetLayout
+
+	bindings map[int]core1_0.DescriptorSetLayoutBinding
+	names    map[string]int
+}
+
+// NewLayout describes layout, which was created from createInfo. names, if provided, are
+// the names of createInfo.Bindings in the same order, and may be used to refer to
+// bindings in groups instead of their binding index.
+func NewLayout(layout core1_0.DescriptorSetLayout, createInfo core1_0.DescriptorSetLayoutCreateInfo, names ...string) (*Layout, error) {
+	if len(names) > len(createInfo.Bindings) {
+		return nil, errors.Errorf("%d names provided for %d bindings", len(names), len(createInfo.Bindings))
+	}
+
+	described := &Layout{
+		Handle:   layout,
+		bindings: make(map[int]core1_0.DescriptorSetLayoutBinding),
+		names:    make(map[string]int),
+	}
+
+	for bindingIndex, binding := range createInfo.Bindings {
+		described.bindings[binding.Binding] = binding
+		if bindingIndex < len(names) && names[bindingIndex] != "" {
+			described.names[names[bindingIndex]] = binding.Binding
+		}
+	}
+
+	return described, nil
+}
+
+// Slot identifies a binding in a Layout, either by its index or by name
+type Slot struct {
+	binding int
+	name    string
+}
+
+// Binding refers to the binding with the provided index
+func Binding(binding int) Slot {
+	return Slot{binding: binding}
+}
+
+// Named refers to the binding with the provided name
+func Named(name string) Slot {
+	return Slot{name: name}
+}
+
+func (s Slot) String() string {
+	if s.name != "" {
+		return fmt.Sprintf("binding %q", s.name)
+	}
+
+	return fmt.Sprintf("binding %d", s.binding)
+}
+
+func (l *Layout) resolve(slot Slot) (core1_0.DescriptorSetLayoutBinding, error) {
+	index := slot.binding
+	if slot.name != "" {
+		var named bool
+		index, named = l.names[slot.name]
+		if !named {
+			return core1_0.DescriptorSetLayoutBinding{}, errors.Errorf("layout has no %s", slot)
+		}
+	}
+
+	binding, found := l.bindings[index]
+	if !found {
+		return binding, errors.Errorf("layout has no %s", slot)
+	}
+
+	return binding, nil
+}
+
+type resourceKind int
+
+const (
+	bufferResource resourceKind = iota
+	imageResource
+	samplerResource
+	texelBufferResource
+)
+
+func (k resourceKind) String() string {
+	switch k {
+	case bufferResource:
+		return "buffer"
+	case imageResource:
+		return "image"
+	case samplerResource:
+		return "sampler"
+	}
+
+	return "texel buffer view"
+}
+
+func kindOf(descriptorType core1_0.DescriptorType) resourceKind {
+	switch descriptorType {
+	case core1_0.DescriptorTypeUniformBuffer, core1_0.DescriptorTypeStorageBuffer,
+		core1_0.DescriptorTypeUniformBufferDynamic, core1_0.DescriptorTypeStorageBufferDynamic:
+		return bufferResource
+	case core1_0.DescriptorTypeUniformTexelBuffer, core1_0.DescriptorTypeStorageTexelBuffer:
+		return texelBufferResource
+	case core1_0.DescriptorTypeSampler:
+		return samplerResource
+	}
+
+	return imageResource
+}
+
+// Group collects the resources for every binding of a descriptor set. Errors are deferred
+// until the group is written, so calls can be chained.
+type Group struct {
+	layout *Layout
+	writes map[int]core1_0.WriteDescriptorSet
+	err    error
+}
+
+// Group starts an empty group of resources for this layout
+func (l *Layout) Group() *Group {
+	return &Group{
+		layout: l,
+		writes: make(map[int]core1_0.WriteDescriptorSet),
+	}
+}
+
+// bind records write for slot, after checking slot is a binding of the right kind and
+// size. check, if not nil, then checks write against the binding.
+func (g *Group) bind(slot Slot, kind resourceKind, count int, write core1_0.WriteDescriptorSet, check func(binding core1_0.DescriptorSetLayoutBinding) error) *Group {
+	if g.err != nil {
+		return g
+	}
+
+	binding, err := g.layout.resolve(slot)
+	if err != nil {
+		g.err = err
+		return g
+	}
+
+	if kindOf(binding.DescriptorType) != kind {
+		g.err = errors.Errorf("%s expects a %s, not a %s", slot, kindOf(binding.DescriptorType), kind)
+		return g
+	}
+
+	if count != binding.DescriptorCount {
+		g.err = errors.Errorf("%s has %d descriptors, but %d were provided", slot, binding.DescriptorCount, count)
+		return g
+	}
+
+	if check != nil {
+		err = check(binding)
+		if err != nil {
+			g.err = errors.Wrapf(err, "%s", slot)
+			return g
+		}
+	}
+
+	write.DstBinding = binding.Binding
+	write.DescriptorType = binding.DescriptorType
+	g.writes[binding.Binding] = write
+	return g
+}
+
+// Buffer binds uniform or storage buffers
+func (g *Group) Buffer(slot Slot, buffers ...core1_0.DescriptorBufferInfo) *Group {
+	return g.bind(slot, bufferResource, len(buffers), core1_0.WriteDescriptorSet{BufferInfo: buffers}, nil)
+}
+
+// Image binds sampled images, storage images, combined image samplers or input
+// attachments. Every image needs an ImageView, and the images of a combined image sampler
+// also need a Sampler unless the layout gives the binding immutable samplers.
+func (g *Group) Image(slot Slot, images ...core1_0.DescriptorImageInfo) *Group {
+	return g.bind(slot, imageResource, len(images), core1_0.WriteDescriptorSet{ImageInfo: images}, func(binding core1_0.DescriptorSetLayoutBinding) error {
+		needsSampler := binding.DescriptorType == core1_0.DescriptorTypeCombinedImageSampler && len(binding.ImmutableSamplers) == 0
+
+		for index, image := range images {
+			if !image.ImageView.Initialized() {
+				return errors.Errorf("image %d has no image view", index)
+			}
+			if needsSampler && !image.Sampler.Initialized() {
+				return errors.Errorf("image %d has no sampler, and the binding has no immutable samplers", index)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Sampler binds standalone samplers. Bindings with immutable samplers ignore them.
+func (g *Group) Sampler(slot Slot, samplers ...core1_0.Sampler) *Group {
+	images := make([]core1_0.DescriptorImageInfo, 0, len(samplers))
+	for _, sampler := range samplers {
+		images = append(images, core1_0.DescriptorImageInfo{Sampler: sampler})
+	}
+
+	return g.bind(slot, samplerResource, len(samplers), core1_0.WriteDescriptorSet{ImageInfo: images}, func(binding core1_0.DescriptorSetLayoutBinding) error {
+		if len(binding.ImmutableSamplers) > 0 {
+			return nil
+		}
+
+		for index, sampler := range samplers {
+			if !sampler.Initialized() {
+				return errors.Errorf("sampler %d is null", index)
+			}
+		}
+
+		return nil
+	})
+}
+
+// TexelBuffer binds uniform or storage texel buffer views
+func (g *Group) TexelBuffer(slot Slot, views ...core1_0.BufferView) *Group {
+	return g.bind(slot, texelBufferResource, len(views), core1_0.WriteDescriptorSet{TexelBufferView: views}, nil)
+}
+
+// validate checks that every binding in the layout has been bound. Bindings whose
+// samplers are all immutable don't need anything bound.
+func (g *Group) validate() error {
+	if g.err != nil {
+		return g.err
+	}
+
+	for index, binding := range g.layout.bindings {
+		_, bound := g.writes[index]
+		immutable := binding.DescriptorType == core1_0.DescriptorTypeSampler && len(binding.ImmutableSamplers) > 0
+		if !bound && !immutable && binding.DescriptorCount > 0 {
+			return errors.Errorf("binding %d was not bound", index)
+		}
+	}
+
+	return nil
+}
+
+// key identifies the layout and every resource in the group, so identical groups can
+// share a descriptor set
+func (g *Group) key() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%x", g.layout.Handle.Handle())
+
+	bindings := make([]int, 0, len(g.writes))
+	for binding := range g.writes {
+		bindings = append(bindings, binding)
+	}
+	sort.Ints(bindings)
+
+	for _, binding := range bindings {
+		write := g.writes[binding]
+		fmt.Fprintf(&sb, "|%d:", binding)
+		for _, buffer := range write.BufferInfo {
+			fmt.Fprintf(&sb, "b%x+%d/%d", buffer.Buffer.Handle(), buffer.Offset, buffer.Range)
+		}
+		for _, image := range write.ImageInfo {
+			fmt.Fprintf(&sb, "i%x.%x.%d", image.ImageView.Handle(), image.Sampler.Handle(), image.ImageLayout)
+		}
+		for _, view := range write.TexelBufferView {
+			fmt.Fprintf(&sb, "t%x", view.Handle())
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package descriptors
+
+import (
+	"testing"
+
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+func TestGroupChecksDescriptorTypes(t *testing.T) {
+	sampler := core1_0.InternalSampler(0, 1, common.Vulkan1_0)
+	view := core1_0.InternalImageView(0, 2, common.Vulkan1_0)
+
+	layout, err := NewLayout(core1_0.DescriptorSetLayout{}, core1_0.DescriptorSetLayoutCreateInfo{
+		Bindings: []core1_0.DescriptorSetLayoutBinding{
+			{Binding: 0, DescriptorType: core1_0.DescriptorTypeSampler, DescriptorCount: 1},
+			{Binding: 1, DescriptorType: core1_0.DescriptorTypeSampledImage, DescriptorCount: 1},
+			{Binding: 2, DescriptorType: core1_0.DescriptorTypeCombinedImageSampler, DescriptorCount: 1},
+			{Binding: 3, DescriptorType: core1_0.DescriptorTypeCombinedImageSampler, DescriptorCount: 1, ImmutableSamplers: []core1_0.Sampler{sampler}},
+		},
+	}, "sampler", "sampled", "combined", "immutable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		bind  func(group *Group) *Group
+		fails bool
+	}{
+		{
+			name: "sampler",
+			bind: func(group *Group) *Group { return group.Sampler(Named("sampler"), sampler) },
+		},
+		{
+			name:  "null sampler",
+			bind:  func(group *Group) *Group { return group.Sampler(Named("sampler"), core1_0.Sampler{}) },
+			fails: true,
+		},
+		{
+			name:  "sampler for sampled image",
+			bind:  func(group *Group) *Group { return group.Sampler(Named("sampled"), sampler) },
+			fails: true,
+		},
+		{
+			name:  "sampler for combined image sampler",
+			bind:  func(group *Group) *Group { return group.Sampler(Named("combined"), sampler) },
+			fails: true,
+		},
+		{
+			name: "image for sampler",
+			bind: func(group *Group) *Group {
+				return group.Image(Named("sampler"), core1_0.DescriptorImageInfo{Sampler: sampler})
+			},
+			fails: true,
+		},
+		{
+			name: "sampled image",
+			bind: func(group *Group) *Group {
+				return group.Image(Named("sampled"), core1_0.DescriptorImageInfo{ImageView: view})
+			},
+		},
+		{
+			name:  "no image view",
+			bind:  func(group *Group) *Group { return group.Image(Named("sampled"), core1_0.DescriptorImageInfo{}) },
+			fails: true,
+		},
+		{
+			name: "combined image sampler",
+			bind: func(group *Group) *Group {
+				return group.Image(Named("combined"), core1_0.DescriptorImageInfo{ImageView: view, Sampler: sampler})
+			},
+		},
+		{
+			name: "combined image sampler without sampler",
+			bind: func(group *Group) *Group {
+				return group.Image(Named("combined"), core1_0.DescriptorImageInfo{ImageView: view})
+			},
+			fails: true,
+		},
+		{
+			name: "immutable sampler",
+			bind: func(group *Group) *Group {
+				return group.Image(Named("immutable"), core1_0.DescriptorImageInfo{ImageView: view})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.bind(layout.Group()).err
+			if test.fails && err == nil {
+				t.Error("bound without an error")
+			} else if !test.fails && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package descriptors
+
+import (
+	"sync"
+
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Writer turns groups into descriptor sets allocated from an Allocator. Writes are queued
+// and submitted together in a single vkUpdateDescriptorSets call by Flush. Writing a
+// group identical to one already written returns the same set without writing it again.
+// It may be used from several goroutines at once.
+type Writer struct {
+	deviceDriver core1_0.CoreDeviceDriver
+	allocator    *Allocator
+
+	lock    sync.Mutex
+	cache   map[string]core1_0.DescriptorSet
+	pending []core1_0.WriteDescriptorSet
+}
+
+// NewWriter creates a Writer which allocates its sets from allocator
+func NewWriter(deviceDriver core1_0.CoreDeviceDriver, allocator *Allocator) *Writer {
+	return &Writer{
+		deviceDriver: deviceDriver,
+		allocator:    allocator,
+		cache:        make(map[string]core1_0.DescriptorSet),
+	}
+}
+
+// Write checks group against its layout and returns a descriptor set containing it. The
+// set's contents aren't written until the next call to Flush.
+func (w *Writer) Write(group *Group) (core1_0.DescriptorSet, error) {
+	err := group.validate()
+	if err != nil {
+		return core1_0.DescriptorSet{}, err
+	}
+
+	key := group.key()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	set, cached := w.cache[key]
+	if cached {
+		return set, nil
+	}
+
+	sets, err := w.allocator.Allocate(group.layout)
+	if err != nil {
+		return core1_0.DescriptorSet{}, err
+	}
+	set = sets[0]
+
+	for _, write := range group.writes {
+		write.DstSet = set
+		w.pending = append(w.pending, write)
+	}
+	w.cache[key] = set
+
+	return set, nil
+}
+
+// WriteAll writes every group and flushes, returning their sets in the same order
+func (w *Writer) WriteAll(groups ...*Group) ([]core1_0.DescriptorSet, error) {
+	sets := make([]core1_0.DescriptorSet, 0, len(groups))
+	for _, group := range groups {
+		set, err := w.Write(group)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+
+	return sets, w.Flush()
+}
+
+// Flush submits every queued write
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	err := w.deviceDriver.UpdateDescriptorSets(w.pending, nil)
+	w.pending = w.pending[:0]
+	return err
+}
+
+// Reset forgets every set the Writer has returned. It should be called whenever the
+// Allocator is reset.
+func (w *Writer) Reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.cache = make(map[string]core1_0.DescriptorSet)
+	w.pending = w.pending[:0]
+}
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_1"
+	"github.com/vkngwrapper/extensions/v3/khr_get_physical_device_properties2"
+)
+
+// ErrMissingRequirements is wrapped by the error InitEnumerateDevice returns when no
+// device has every required feature and extension
+var ErrMissingRequirements = errors.New("the device is missing required features or extensions")
+
+// ExtendedFeatures requests features from a feature structure that is chained onto the
+// device create info, such as core1_2.PhysicalDeviceTimelineSemaphoreFeatures.
+// Required and Optional are values of the same structure type, with the features wanted
+// set to true, and either may be nil. The structure type must be able to be queried
+// through core1_1.PhysicalDeviceFeatures2.
+type ExtendedFeatures struct {
+	Required any
+	Optional any
+
+	// Extension is the device extension that provides the structure, and is enabled
+	// along with it. When it is empty the structure is part of core Vulkan Version.
+	Extension string
+	Version   common.APIVersion
+}
+
+// DeviceRequirements are the features and extensions a sample needs from its device.
+// InitEnumerateDevice only chooses a device that has every required one, and InitDevice
+// enables the optional ones that are present.
+//
+// Timeline semaphores are enabled by InitDevice for Frames, so Extended must not
+// include core1_2.PhysicalDeviceTimelineSemaphoreFeatures.
+type DeviceRequirements struct {
+	RequiredFeatures core1_0.PhysicalDeviceFeatures
+	OptionalFeatures core1_0.PhysicalDeviceFeatures
+
+	RequiredExtensions []string
+	OptionalExtensions []string
+
+	Extended []ExtendedFeatures
+}
+
+// DeviceExtensionEnabled reports whether InitDevice enabled extension
+func (i *SampleInfo) DeviceExtensionEnabled(extension string) bool {
+	return slices.Contains(i.DeviceExtensionNames, extension)
+}
+
+// ExtendedFeaturesEnabled fills features, a pointer to a feature structure such as
+// &core1_2.PhysicalDeviceTimelineSemaphoreFeatures{}, with the features InitDevice
+// enabled from it, and reports whether any were
+func (i *SampleInfo) ExtendedFeaturesEnabled(features any) bool {
+	target := reflect.ValueOf(features)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return false
+	}
+
+	for _, enabled := range i.EnabledExtendedFeatures {
+		value := reflect.ValueOf(enabled)
+		if value.Type() == target.Elem().Type() {
+			target.Elem().Set(value)
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvedRequirements is how one physical device meets DeviceRequirements
+type resolvedRequirements struct {
+	// extensions are the required extensions and the optional ones that are present
+	extensions       []string
+	features         core1_0.PhysicalDeviceFeatures
+	extendedFeatures []any
+	// next is DeviceNext with extendedFeatures chained in front of it
+	next common.Options
+}
+
+// applyRequirements adds the extensions resolved enables to DeviceExtensionNames, and
+// sets EnabledFeatures and EnabledExtendedFeatures
+func (i *SampleInfo) applyRequirements(resolved resolvedRequirements) {
+	for _, extension := range resolved.extensions {
+		if !i.DeviceExtensionEnabled(extension) {
+			i.DeviceExtensionNames = append(i.DeviceExtensionNames, extension)
+		}
+	}
+
+	i.EnabledFeatures = resolved.features
+	i.EnabledExtendedFeatures = resolved.extendedFeatures
+}
+
+// resolveRequirements checks DeviceRequirements against gpu, which was negotiated to
+// version and has the extensions in available. The error wraps ErrMissingRequirements
+// when gpu lacks anything required.
+func (i *SampleInfo) resolveRequirements(gpu core1_0.PhysicalDevice, version common.APIVersion, available map[string]*core1_0.ExtensionProperties) (resolvedRequirements, error) {
+	requirements := i.DeviceRequirements
+	var resolved resolvedRequirements
+	var missing []string
+
+	supported := i.InstanceDriver.GetPhysicalDeviceFeatures(gpu)
+	enabled, missingFeatures := mergeFeatures(
+		reflect.ValueOf(requirements.RequiredFeatures),
+		reflect.ValueOf(requirements.OptionalFeatures),
+		reflect.ValueOf(*supported),
+	)
+	resolved.features = enabled.Interface().(core1_0.PhysicalDeviceFeatures)
+	missing = append(missing, missingFeatures...)
+
+	for _, extension := range requirements.RequiredExtensions {
+		_, found := available[extension]
+		if !found {
+			missing = append(missing, extension)
+			continue
+		}
+		resolved.extensions = append(resolved.extensions, extension)
+	}
+
+	for _, extension := range requirements.OptionalExtensions {
+		_, found := available[extension]
+		if found {
+			resolved.extensions = append(resolved.extensions, extension)
+		}
+	}
+
+	next := i.DeviceNext
+	for _, extended := range requirements.Extended {
+		structType, err := extendedType(extended)
+		if err != nil {
+			return resolvedRequirements{}, err
+		}
+
+		if extended.Extension != "" {
+			_, found := available[extended.Extension]
+			if !found {
+				if extended.Required != nil {
+					missing = append(missing, fmt.Sprintf("%s for %s", extended.Extension, structType.Name()))
+				}
+				continue
+			}
+		} else if !version.IsAtLeast(extended.Version) {
+			if extended.Required != nil {
+				missing = append(missing, fmt.Sprintf("Vulkan %s for %s", extended.Version, structType.Name()))
+			}
+			continue
+		}
+
+		supported, err := i.queryFeatures(gpu, version, structType)
+		if err != nil {
+			if extended.Required != nil {
+				return resolvedRequirements{}, err
+			}
+			continue
+		}
+
+		enabled, missingFeatures := mergeFeatures(
+			featureValue(extended.Required, structType),
+			featureValue(extended.Optional, structType),
+			supported,
+		)
+		if len(missingFeatures) > 0 {
+			for _, feature := range missingFeatures {
+				missing = append(missing, structType.Name()+"."+feature)
+			}
+			continue
+		}
+
+		if !anyFeature(enabled) {
+			continue
+		}
+
+		if extended.Extension != "" && !slices.Contains(resolved.extensions, extended.Extension) {
+			resolved.extensions = append(resolved.extensions, extended.Extension)
+		}
+		resolved.extendedFeatures = append(resolved.extendedFeatures, enabled.Interface())
+
+		enabled.FieldByName("NextOptions").Set(reflect.ValueOf(common.NextOptions{Next: next}))
+		next = enabled.Interface().(common.Options)
+	}
+
+	if len(missing) > 0 {
+		return resolvedRequirements{}, errors.Wrap(ErrMissingRequirements, strings.Join(missing, ", "))
+	}
+
+	resolved.next = next
+	return resolved, nil
+}
+
+// extendedType is the structure type of an ExtendedFeatures, which must be a struct
+// with bool features that embeds common.NextOptions
+func extendedType(extended ExtendedFeatures) (reflect.Type, error) {
+	value := extended.Required
+	if value == nil {
+		value = extended.Optional
+	}
+	if value == nil {
+		return nil, errors.New("extended features need a Required or Optional structure")
+	}
+
+	structType := reflect.TypeOf(value)
+	if extended.Required != nil && extended.Optional != nil && reflect.TypeOf(extended.Optional) != structType {
+		return nil, errors.Errorf("required %s and optional %T are different structures", structType, extended.Optional)
+	}
+
+	if structType.Kind() != reflect.Struct {
+		return nil, errors.Errorf("extended features must be a structure value, not %s", structType)
+	}
+
+	_, hasNext := structType.FieldByName("NextOptions")
+	if !hasNext || !structType.Implements(reflect.TypeOf((*common.Options)(nil)).Elem()) {
+		return nil, errors.Errorf("%s cannot be chained onto a device create info", structType)
+	}
+
+	return structType, nil
+}
+
+// queryFeatures asks gpu which of the features in a structType are supported, with
+// Vulkan 1.1 or VK_KHR_get_physical_device_properties2
+func (i *SampleInfo) queryFeatures(gpu core1_0.PhysicalDevice, version common.APIVersion, structType reflect.Type) (reflect.Value, error) {
+	query := reflect.New(structType)
+	out, ok := query.Interface().(common.OutData)
+	if !ok {
+		return reflect.Value{}, errors.Errorf("%s cannot be queried", structType)
+	}
+
+	features := &core1_1.PhysicalDeviceFeatures2{
+		NextOutData: common.NextOutData{Next: out},
+	}
+
+	var err error
+	if i.InstanceDriver11 != nil && version.IsAtLeast(common.Vulkan1_1) {
+		err = i.InstanceDriver11.GetPhysicalDeviceFeatures2(gpu, features)
+	} else if slices.Contains(i.InstanceExtensionNames, khr_get_physical_device_properties2.ExtensionName) {
+		err = khr_get_physical_device_properties2.CreateExtensionDriverFromCoreDriver(i.InstanceDriver).
+			GetPhysicalDeviceFeatures2(gpu, features)
+	} else {
+		err = errors.Errorf("querying %s needs Vulkan 1.1 or %s", structType.Name(), khr_get_physical_device_properties2.ExtensionName)
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return query.Elem(), nil
+}
+
+// featureValue is features as a value of structType, or its zero value when features is
+// nil
+func featureValue(features any, structType reflect.Type) reflect.Value {
+	if features == nil {
+		return reflect.New(structType).Elem()
+	}
+
+	return reflect.ValueOf(features)
+}
+
+// mergeFeatures returns a structure of the same type as supported with each bool feature
+// set when it is required, or optional and supported, along with the names of required
+// features that aren't supported. Fields that aren't bools are left zero.
+func mergeFeatures(required, optional, supported reflect.Value) (reflect.Value, []string) {
+	enabled := reflect.New(supported.Type()).Elem()
+	var missing []string
+
+	for field := 0; field < supported.NumField(); field++ {
+		if supported.Field(field).Kind() != reflect.Bool {
+			continue
+		}
+
+		isRequired := required.Field(field).Bool()
+		isSupported := supported.Field(field).Bool()
+		if isRequired && !isSupported {
+			missing = append(missing, supported.Type().Field(field).Name)
+		}
+
+		enabled.Field(field).SetBool(isRequired || (optional.Field(field).Bool() && isSupported))
+	}
+
+	return enabled, missing
+}
+
+func anyFeature(features reflect.Value) bool {
+	for field := 0; field < features.NumField(); field++ {
+		if features.Field(field).Kind() == reflect.Bool && features.Field(field).Bool() {
+			return true
+		}
+	}
+
+	return false
+}
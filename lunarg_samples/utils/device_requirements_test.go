@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_1"
+	"github.com/vkngwrapper/core/v3/core1_2"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+// fakeGpuInfo is what fakeInstanceDriver reports for one physical device
+type fakeGpuInfo struct {
+	version     common.APIVersion
+	features    core1_0.PhysicalDeviceFeatures
+	memoryModel core1_2.PhysicalDeviceVulkanMemoryModelFeatures
+	extensions  []string
+}
+
+// fakeInstanceDriver answers the physical device queries InitEnumerateDevice and
+// resolveRequirements make, and panics if anything else is called
+type fakeInstanceDriver struct {
+	core1_1.CoreInstanceDriver
+
+	gpus []fakeGpuInfo
+}
+
+func (d *fakeInstanceDriver) gpu(physicalDevice core1_0.PhysicalDevice) fakeGpuInfo {
+	return d.gpus[physicalDevice.Handle()-1]
+}
+
+func (d *fakeInstanceDriver) physicalDevices() []core1_0.PhysicalDevice {
+	var gpus []core1_0.PhysicalDevice
+	for index, gpu := range d.gpus {
+		gpus = append(gpus, fakeGpu(index+1, gpu.version))
+	}
+	return gpus
+}
+
+func (d *fakeInstanceDriver) EnumeratePhysicalDevices() ([]core1_0.PhysicalDevice, common.VkResult, error) {
+	return d.physicalDevices(), core1_0.VKSuccess, nil
+}
+
+func (d *fakeInstanceDriver) GetPhysicalDeviceFeatures(physicalDevice core1_0.PhysicalDevice) *core1_0.PhysicalDeviceFeatures {
+	features := d.gpu(physicalDevice).features
+	return &features
+}
+
+func (d *fakeInstanceDriver) GetPhysicalDeviceFeatures2(physicalDevice core1_0.PhysicalDevice, out *core1_1.PhysicalDeviceFeatures2) error {
+	memoryModel, ok := out.NextOutData.Next.(*core1_2.PhysicalDeviceVulkanMemoryModelFeatures)
+	if !ok {
+		return errors.Errorf("unexpected query for %T", out.NextOutData.Next)
+	}
+
+	*memoryModel = d.gpu(physicalDevice).memoryModel
+	return nil
+}
+
+func (d *fakeInstanceDriver) EnumerateDeviceExtensionProperties(physicalDevice core1_0.PhysicalDevice) (map[string]*core1_0.ExtensionProperties, common.VkResult, error) {
+	extensions := make(map[string]*core1_0.ExtensionProperties)
+	for _, extension := range d.gpu(physicalDevice).extensions {
+		extensions[extension] = &core1_0.ExtensionProperties{ExtensionName: extension}
+	}
+	return extensions, core1_0.VKSuccess, nil
+}
+
+func (d *fakeInstanceDriver) GetPhysicalDeviceQueueFamilyProperties(physicalDevice core1_0.PhysicalDevice) []*core1_0.QueueFamilyProperties {
+	return []*core1_0.QueueFamilyProperties{{QueueFlags: core1_0.QueueGraphics, QueueCount: 1}}
+}
+
+func (d *fakeInstanceDriver) GetPhysicalDeviceMemoryProperties(physicalDevice core1_0.PhysicalDevice) *core1_0.PhysicalDeviceMemoryProperties {
+	return &core1_0.PhysicalDeviceMemoryProperties{}
+}
+
+func (d *fakeInstanceDriver) GetPhysicalDeviceProperties(physicalDevice core1_0.PhysicalDevice) (*core1_0.PhysicalDeviceProperties, error) {
+	return &core1_0.PhysicalDeviceProperties{DriverType: core1_0.PhysicalDeviceTypeDiscreteGPU}, nil
+}
+
+func fakeGpu(handle int, version common.APIVersion) core1_0.PhysicalDevice {
+	return core1_0.InternalPhysicalDevice(loader.VkPhysicalDevice(handle), common.Vulkan1_2, version)
+}
+
+func handles(gpus []core1_0.PhysicalDevice) []loader.VkPhysicalDevice {
+	var result []loader.VkPhysicalDevice
+	for _, gpu := range gpus {
+		result = append(result, gpu.Handle())
+	}
+	return result
+}
+
+func fakeSampleInfo(gpus ...fakeGpuInfo) *SampleInfo {
+	driver := &fakeInstanceDriver{gpus: gpus}
+	return &SampleInfo{
+		InstanceDriver:     driver,
+		InstanceDriver11:   driver,
+		InstanceAPIVersion: common.Vulkan1_2,
+	}
+}
+
+func TestMergeFeatures(t *testing.T) {
+	required := core1_0.PhysicalDeviceFeatures{GeometryShader: true, SamplerAnisotropy: true}
+	optional := core1_0.PhysicalDeviceFeatures{WideLines: true, FillModeNonSolid: true}
+	supported := core1_0.PhysicalDeviceFeatures{GeometryShader: true, WideLines: true, DepthClamp: true}
+
+	enabled, missing := mergeFeatures(reflect.ValueOf(required), reflect.ValueOf(optional), reflect.ValueOf(supported))
+
+	want := core1_0.PhysicalDeviceFeatures{GeometryShader: true, SamplerAnisotropy: true, WideLines: true}
+	if enabled.Interface() != want {
+		t.Errorf("enabled %+v, want %+v", enabled.Interface(), want)
+	}
+	if !slices.Equal(missing, []string{"SamplerAnisotropy"}) {
+		t.Errorf("missing %v, want SamplerAnisotropy", missing)
+	}
+}
+
+func TestMergeFeaturesSkipsNonBools(t *testing.T) {
+	required := core1_2.PhysicalDeviceVulkanMemoryModelFeatures{VulkanMemoryModel: true}
+	supported := core1_2.PhysicalDeviceVulkanMemoryModelFeatures{
+		VulkanMemoryModel: true,
+		NextOptions:       common.NextOptions{Next: core1_0.DeviceCreateInfo{}},
+	}
+	structType := reflect.TypeOf(required)
+
+	enabled, missing := mergeFeatures(reflect.ValueOf(required), featureValue(nil, structType), reflect.ValueOf(supported))
+	if len(missing) > 0 {
+		t.Errorf("missing %v", missing)
+	}
+
+	features := enabled.Interface().(core1_2.PhysicalDeviceVulkanMemoryModelFeatures)
+	if !features.VulkanMemoryModel || features.VulkanMemoryModelDeviceScope {
+		t.Errorf("enabled %+v, want only VulkanMemoryModel", features)
+	}
+	if features.NextOptions.Next != nil {
+		t.Error("the supported structure's chain was copied into the enabled structure")
+	}
+}
+
+func TestExtendedType(t *testing.T) {
+	memoryModel := reflect.TypeFor[core1_2.PhysicalDeviceVulkanMemoryModelFeatures]()
+
+	tests := []struct {
+		name     string
+		extended ExtendedFeatures
+		want     reflect.Type
+	}{
+		{
+			name:     "required",
+			extended: ExtendedFeatures{Required: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{}},
+			want:     memoryModel,
+		},
+		{
+			name:     "optional",
+			extended: ExtendedFeatures{Optional: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{}},
+			want:     memoryModel,
+		},
+		{
+			name: "both",
+			extended: ExtendedFeatures{
+				Required: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{},
+				Optional: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{},
+			},
+			want: memoryModel,
+		},
+		{
+			name: "neither",
+		},
+		{
+			name: "mismatched",
+			extended: ExtendedFeatures{
+				Required: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{},
+				Optional: core1_2.PhysicalDeviceTimelineSemaphoreFeatures{},
+			},
+		},
+		{
+			name:     "pointer",
+			extended: ExtendedFeatures{Required: &core1_2.PhysicalDeviceVulkanMemoryModelFeatures{}},
+		},
+		{
+			name:     "not chainable",
+			extended: ExtendedFeatures{Required: core1_0.PhysicalDeviceFeatures{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			structType, err := extendedType(test.extended)
+			if test.want == nil {
+				if err == nil {
+					t.Errorf("accepted %s, want an error", structType)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if structType != test.want {
+				t.Errorf("got %s, want %s", structType, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveRequirements(t *testing.T) {
+	info := fakeSampleInfo(fakeGpuInfo{
+		version:     common.Vulkan1_2,
+		features:    core1_0.PhysicalDeviceFeatures{GeometryShader: true, WideLines: true},
+		memoryModel: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{VulkanMemoryModel: true},
+		extensions:  []string{"VK_required", "VK_optional"},
+	})
+	info.DeviceRequirements = DeviceRequirements{
+		RequiredFeatures:   core1_0.PhysicalDeviceFeatures{GeometryShader: true},
+		OptionalFeatures:   core1_0.PhysicalDeviceFeatures{WideLines: true, LogicOp: true},
+		RequiredExtensions: []string{"VK_required"},
+		OptionalExtensions: []string{"VK_optional", "VK_absent"},
+		Extended: []ExtendedFeatures{
+			{
+				Optional: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{
+					VulkanMemoryModel:            true,
+					VulkanMemoryModelDeviceScope: true,
+				},
+				Version: common.Vulkan1_2,
+			},
+		},
+	}
+	info.DeviceNext = core1_0.DeviceCreateInfo{}
+
+	gpus := info.InstanceDriver.(*fakeInstanceDriver).physicalDevices()
+	extensions, _, _ := info.InstanceDriver.EnumerateDeviceExtensionProperties(gpus[0])
+	resolved, err := info.resolveRequirements(gpus[0], common.Vulkan1_2, extensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFeatures := core1_0.PhysicalDeviceFeatures{GeometryShader: true, WideLines: true}
+	if resolved.features != wantFeatures {
+		t.Errorf("enabled features %+v, want %+v", resolved.features, wantFeatures)
+	}
+	if !slices.Equal(resolved.extensions, []string{"VK_required", "VK_optional"}) {
+		t.Errorf("enabled extensions %v", resolved.extensions)
+	}
+
+	if len(resolved.extendedFeatures) != 1 {
+		t.Fatalf("enabled extended features %v, want the memory model", resolved.extendedFeatures)
+	}
+	memoryModel := resolved.extendedFeatures[0].(core1_2.PhysicalDeviceVulkanMemoryModelFeatures)
+	if !memoryModel.VulkanMemoryModel || memoryModel.VulkanMemoryModelDeviceScope {
+		t.Errorf("enabled %+v, want only the supported VulkanMemoryModel", memoryModel)
+	}
+
+	chained, ok := resolved.next.(core1_2.PhysicalDeviceVulkanMemoryModelFeatures)
+	if !ok {
+		t.Fatalf("next is %T, want the memory model features", resolved.next)
+	}
+	if _, ok := chained.NextOptions.Next.(core1_0.DeviceCreateInfo); !ok {
+		t.Errorf("memory model features are chained onto %T, want DeviceNext", chained.NextOptions.Next)
+	}
+
+	info.applyRequirements(resolved)
+	info.applyRequirements(resolved)
+	if !slices.Equal(info.DeviceExtensionNames, []string{"VK_required", "VK_optional"}) {
+		t.Errorf("applying twice enabled %v", info.DeviceExtensionNames)
+	}
+	if !info.ExtendedFeaturesEnabled(&core1_2.PhysicalDeviceVulkanMemoryModelFeatures{}) {
+		t.Error("ExtendedFeaturesEnabled does not find the applied memory model features")
+	}
+}
+
+func TestResolveRequirementsMissing(t *testing.T) {
+	info := fakeSampleInfo(fakeGpuInfo{version: common.Vulkan1_1})
+	info.DeviceRequirements = DeviceRequirements{
+		RequiredFeatures:   core1_0.PhysicalDeviceFeatures{TessellationShader: true},
+		RequiredExtensions: []string{"VK_required"},
+		Extended: []ExtendedFeatures{
+			{
+				Required: core1_2.PhysicalDeviceVulkanMemoryModelFeatures{VulkanMemoryModel: true},
+				Version:  common.Vulkan1_2,
+			},
+		},
+	}
+
+	gpus := info.InstanceDriver.(*fakeInstanceDriver).physicalDevices()
+	_, err := info.resolveRequirements(gpus[0], common.Vulkan1_1, nil)
+	if !errors.Is(err, ErrMissingRequirements) {
+		t.Fatalf("got %v, want ErrMissingRequirements", err)
+	}
+
+	want := "TessellationShader, VK_required, Vulkan 1.2.0 for PhysicalDeviceVulkanMemoryModelFeatures: " + ErrMissingRequirements.Error()
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestInitEnumerateDeviceSkipsUnsuitableDevices(t *testing.T) {
+	info := fakeSampleInfo(
+		fakeGpuInfo{version: common.Vulkan1_2, extensions: []string{"VK_optional"}},
+		fakeGpuInfo{version: common.Vulkan1_1, features: core1_0.PhysicalDeviceFeatures{GeometryShader: true}},
+		fakeGpuInfo{version: common.Vulkan1_2, features: core1_0.PhysicalDeviceFeatures{GeometryShader: true}},
+	)
+	info.DeviceRequirements = DeviceRequirements{
+		RequiredFeatures:   core1_0.PhysicalDeviceFeatures{GeometryShader: true},
+		OptionalExtensions: []string{"VK_optional"},
+	}
+
+	err := info.InitEnumerateDevice()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order := handles(info.Gpus); !slices.Equal(order, []loader.VkPhysicalDevice{3, 1, 2}) {
+		t.Errorf("devices ordered %v, want the newest device with geometry shaders first", order)
+	}
+	if info.DeviceAPIVersion != common.Vulkan1_2 {
+		t.Errorf("negotiated Vulkan %s, want 1.2", info.DeviceAPIVersion)
+	}
+
+	info = fakeSampleInfo(fakeGpuInfo{version: common.Vulkan1_2})
+	info.DeviceRequirements.RequiredFeatures.GeometryShader = true
+	err = info.InitEnumerateDevice()
+	if !errors.Is(err, ErrMissingRequirements) {
+		t.Errorf("got %v, want ErrMissingRequirements", err)
+	}
+}
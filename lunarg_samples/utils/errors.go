@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/internal/vkerror"
+)
+
+var (
+	ErrOutOfDate         = vkerror.ErrOutOfDate
+	ErrSuboptimal        = vkerror.ErrSuboptimal
+	ErrIncomplete        = vkerror.ErrIncomplete
+	ErrDeviceLost        = vkerror.ErrDeviceLost
+	ErrOutOfDeviceMemory = vkerror.ErrOutOfDeviceMemory
+	ErrOutOfHostMemory   = vkerror.ErrOutOfHostMemory
+	ErrFormatUnsupported = vkerror.ErrFormatUnsupported
+)
+
+// VulkanError is returned by the SampleInfo methods, and by the utility packages that
+// create Vulkan objects, when a Vulkan command fails or returns a success code other than
+// VK_SUCCESS. It matches the Err* sentinels for its Result with errors.Is.
+type VulkanError = vkerror.VulkanError
+
+// IsStatus reports whether err is a VulkanError carrying a non-error result code, which
+// callers can usually continue past
+func IsStatus(err error) bool {
+	return vkerror.IsStatus(err)
+}
+
+func vulkanError(op string, object string, res common.VkResult, err error) error {
+	return vkerror.New(op, object, res, err)
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+const (
+	// MinBackoff is how long Wait first sleeps between polls of the event's status
+	MinBackoff = 10 * time.Microsecond
+	// MaxBackoff is the longest Wait ever sleeps between polls
+	MaxBackoff = time.Millisecond
+)
+
+// ErrHostSignalPending is returned by CheckSubmit for an event that a command buffer waits
+// on from the host, but that is not signaled
+var ErrHostSignalPending = errors.New("event waited on from the host has not been set")
+
+// Barriers are the memory barriers applied when a wait on an event completes
+type Barriers struct {
+	Memory []core1_0.MemoryBarrier
+	Buffer []core1_0.BufferMemoryBarrier
+	Image  []core1_0.ImageMemoryBarrier
+}
+
+// Event wraps a core1_0.Event with a cancellable host wait, split barriers for GPU-side
+// signalling, and a check that events the GPU waits on from the host get set.
+type Event struct {
+	// Name is used in error messages
+	Name string
+
+	deviceDriver core1_0.DeviceDriver
+	event        core1_0.Event
+
+	lock sync.Mutex
+	// waitsOnHost is set once a wait for a host signal has been recorded, so CheckSubmit
+	// knows to query the event's status
+	waitsOnHost bool
+}
+
+// New creates an event in the unsignaled state
+func New(deviceDriver core1_0.DeviceDriver, name string) (*Event, error) {
+	event, _, err := deviceDriver.CreateEvent(nil, core1_0.EventCreateInfo{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		Name:         name,
+		deviceDriver: deviceDriver,
+		event:        event,
+	}, nil
+}
+
+// Event is the underlying Vulkan event
+func (e *Event) Event() core1_0.Event {
+	return e.event
+}
+
+// Set signals the event from the host
+func (e *Event) Set() error {
+	_, err := e.deviceDriver.SetEvent(e.event)
+	return err
+}
+
+// Reset unsignals the event from the host. The GPU must not be using the event.
+func (e *Event) Reset() error {
+	_, err := e.deviceDriver.ResetEvent(e.event)
+	return err
+}
+
+// IsSet reports whether the event is signaled
+func (e *Event) IsSet() (bool, error) {
+	res, err := e.deviceDriver.GetEventStatus(e.event)
+	if err != nil {
+		return false, err
+	}
+
+	return res == core1_0.VKEventSet, nil
+}
+
+// Wait blocks until the event is signaled or ctx is done. Vulkan has no host wait for
+// events, so the status is polled, sleeping between polls for twice as long each time
+// from MinBackoff up to MaxBackoff.
+func (e *Event) Wait(ctx context.Context) error {
+	backoff := MinBackoff
+	for {
+		set, err := e.IsSet()
+		if err != nil {
+			return err
+		} else if set {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrapf(ctx.Err(), "waiting for %s", e.Name)
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, MaxBackoff)
+	}
+}
+
+// Split is the first half of a split barrier: the GPU signals the event once the commands
+// before it reach a stage, and commands after the matching CmdWait can start without
+// waiting for the work recorded between the two.
+type Split struct {
+	event    *Event
+	srcStage core1_0.PipelineStageFlags
+}
+
+// CmdSignal records a signal of the event once every command before it has reached
+// srcStage, and returns the split barrier to wait on later
+func (e *Event) CmdSignal(commandBuffer core1_0.CommandBuffer, srcStage core1_0.PipelineStageFlags) Split {
+	e.deviceDriver.CmdSetEvent(commandBuffer, e.event, srcStage)
+	return Split{event: e, srcStage: srcStage}
+}
+
+// CmdWait records the second half of the split barrier: commands after it wait at
+// dstStage for the signal, then barriers are applied. The stage the event was signaled
+// at is used as the source stage, as Vulkan requires.
+func (s Split) CmdWait(commandBuffer core1_0.CommandBuffer, dstStage core1_0.PipelineStageFlags, barriers Barriers) error {
+	return s.event.deviceDriver.CmdWaitEvents(commandBuffer, []core1_0.Event{s.event.event}, s.srcStage, dstStage, barriers.Memory, barriers.Buffer, barriers.Image)
+}
+
+// CmdReset records an unsignal of the event once every command before it has reached
+// stage, so the event can be signaled again
+func (e *Event) CmdReset(commandBuffer core1_0.CommandBuffer, stage core1_0.PipelineStageFlags) {
+	e.deviceDriver.CmdResetEvent(commandBuffer, e.event, stage)
+}
+
+// CmdWaitHost records a wait for the host to set the event, after which barriers are
+// applied. The host must call Set before the command buffer is submitted; Vulkan does
+// not allow the host to signal an event that submitted work is already waiting on,
+// and implementations may hang. CheckSubmit catches events that haven't been set.
+func (e *Event) CmdWaitHost(commandBuffer core1_0.CommandBuffer, dstStage core1_0.PipelineStageFlags, barriers Barriers) error {
+	err := e.deviceDriver.CmdWaitEvents(commandBuffer, []core1_0.Event{e.event}, core1_0.PipelineStageHost, dstStage, barriers.Memory, barriers.Buffer, barriers.Image)
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.waitsOnHost = true
+	return nil
+}
+
+// CheckSubmit returns ErrHostSignalPending if any of events has been waited on with
+// CmdWaitHost and is not signaled right now. The status is queried from Vulkan, so resets
+// by the host or the GPU are seen. Call it before submitting the command buffers that
+// wait.
+func CheckSubmit(events ...*Event) error {
+	for _, event := range events {
+		event.lock.Lock()
+		waitsOnHost := event.waitsOnHost
+		event.lock.Unlock()
+
+		if !waitsOnHost {
+			continue
+		}
+
+		set, err := event.IsSet()
+		if err != nil {
+			return errors.Wrapf(err, "checking %s", event.Name)
+		}
+		if !set {
+			return errors.Wrap(ErrHostSignalPending, event.Name)
+		}
+	}
+
+	return nil
+}
+
+// Destroy destroys the event. The GPU must be done with it.
+func (e *Event) Destroy() {
+	e.deviceDriver.DestroyEvent(e.event, nil)
+}
@@ -0,0 +1,360 @@
+package framesync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_2"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queues"
+	"github.com/vkngwrapper/extensions/v3/khr_timeline_semaphore"
+)
+
+// PollInterval is the longest Wait blocks inside Vulkan before checking whether its
+// context is done
+const PollInterval = 10 * time.Millisecond
+
+// TimelineDriver is the part of core1_2.DeviceDriver and
+// khr_timeline_semaphore.ExtensionDriver that a Counter uses
+type TimelineDriver interface {
+	GetSemaphoreCounterValue(semaphore core1_0.Semaphore) (uint64, common.VkResult, error)
+	WaitSemaphores(timeout time.Duration, o core1_2.SemaphoreWaitInfo) (common.VkResult, error)
+}
+
+// Setup describes how timeline semaphores are enabled on a device. The zero Setup leaves
+// them disabled, and a Counter created with it uses fences.
+type Setup struct {
+	// Supported is whether the device can use timeline semaphores
+	Supported bool
+	// Extension is the device extension that provides them, or empty when they are part
+	// of the device's core API version
+	Extension string
+}
+
+// Configure works out how physicalDevice can use timeline semaphores: as part of Vulkan
+// 1.2, through VK_KHR_timeline_semaphore, or not at all. The extension depends on Vulkan
+// 1.1, so it is only used on 1.1 devices. Both guarantee the timelineSemaphore feature,
+// which still has to be enabled with Features.
+func Configure(physicalDevice core1_0.PhysicalDevice, availableExtensions map[string]*core1_0.ExtensionProperties) Setup {
+	version := physicalDevice.DeviceAPIVersion()
+	if version.IsAtLeast(common.Vulkan1_2) {
+		return Setup{Supported: true}
+	}
+
+	_, found := availableExtensions[khr_timeline_semaphore.ExtensionName]
+	if found && version.IsAtLeast(common.Vulkan1_1) {
+		return Setup{Supported: true, Extension: khr_timeline_semaphore.ExtensionName}
+	}
+
+	return Setup{}
+}
+
+// Features returns next with the timelineSemaphore feature chained in front of it when
+// timeline semaphores are supported, for the Next of a core1_0.DeviceCreateInfo.
+// next may be nil.
+func (s Setup) Features(next common.Options) common.Options {
+	if !s.Supported {
+		return next
+	}
+
+	// The extension's structure has the same type and layout as the core one
+	return core1_2.PhysicalDeviceTimelineSemaphoreFeatures{
+		TimelineSemaphore: true,
+		NextOptions:       common.NextOptions{Next: next},
+	}
+}
+
+type pendingFence struct {
+	frame uint64
+	fence core1_0.Fence
+	// waiters is how many Waits are blocked on fence, which can't be reset until they
+	// return. done is set once the frame has completed, and the last waiter recycles it.
+	waiters int
+	done    bool
+}
+
+// Counter is a GPU frame counter. Every Submit is numbered one higher than the last, and
+// the counter's completed value rises as the GPU finishes each of them, so a resource
+// used by a submission can be reused once Completed reaches its number.
+//
+// With timeline semaphores the counter is a single semaphore signaled with the frame
+// number. Without them, each submission signals a fence from a small pool, and the
+// completed value is the last frame whose fence, and every fence before it, has
+// signaled.
+type Counter struct {
+	deviceDriver core1_0.DeviceDriver
+	timeline     TimelineDriver
+	semaphore    core1_0.Semaphore
+
+	lock      sync.Mutex
+	submitted uint64
+	completed uint64
+	pending   []*pendingFence
+	free      []core1_0.Fence
+}
+
+// New creates a Counter for a device created with setup, whose features must have been
+// chained into the device's create info with setup.Features
+func New(deviceDriver core1_0.DeviceDriver, setup Setup) (*Counter, error) {
+	counter := &Counter{deviceDriver: deviceDriver}
+	if !setup.Supported {
+		return counter, nil
+	}
+
+	if setup.Extension != "" {
+		counter.timeline = khr_timeline_semaphore.CreateExtensionDriverFromCoreDriver(deviceDriver)
+	} else {
+		core12, ok := deviceDriver.(core1_2.DeviceDriver)
+		if !ok {
+			return nil, errors.New("timeline semaphores need a Vulkan 1.2 device driver or VK_KHR_timeline_semaphore")
+		}
+		counter.timeline = core12
+	}
+
+	semaphore, _, err := deviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{
+		NextOptions: common.NextOptions{Next: core1_2.SemaphoreTypeCreateInfo{
+			SemaphoreType: core1_2.SemaphoreTypeTimeline,
+			InitialValue:  0,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	counter.semaphore = semaphore
+
+	return counter, nil
+}
+
+// Timeline reports whether the counter is backed by a timeline semaphore rather than
+// fences
+func (c *Counter) Timeline() bool {
+	return c.timeline != nil
+}
+
+// Semaphore is the timeline semaphore, which other submissions may wait on for a frame
+// number. It is uninitialized when the counter uses fences.
+func (c *Counter) Semaphore() core1_0.Semaphore {
+	return c.semaphore
+}
+
+// Submit submits submitInfos to queue, along with anything already enqueued on it, and
+// returns the frame number that completes once all of them have. The signal is added to
+// the last SubmitInfo; when timeline semaphores are used it gains a
+// core1_2.TimelineSemaphoreSubmitInfo, so it must not already have one.
+func (c *Counter) Submit(queue *queues.Queue, submitInfos ...core1_0.SubmitInfo) (uint64, common.VkResult, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	frame := c.submitted + 1
+
+	if c.timeline == nil {
+		fence, err := c.takeFence()
+		if err != nil {
+			return 0, core1_0.VKErrorUnknown, err
+		}
+
+		res, err := queue.Submit(&fence, submitInfos...)
+		if err != nil {
+			c.free = append(c.free, fence)
+			return 0, res, err
+		}
+
+		c.pending = append(c.pending, &pendingFence{frame: frame, fence: fence})
+		c.submitted = frame
+		return frame, res, nil
+	}
+
+	// Copy so the caller's SubmitInfos are left alone
+	submitInfos = append([]core1_0.SubmitInfo{}, submitInfos...)
+	if len(submitInfos) == 0 {
+		submitInfos = append(submitInfos, core1_0.SubmitInfo{})
+	}
+
+	last := &submitInfos[len(submitInfos)-1]
+	last.SignalSemaphores = append(append([]core1_0.Semaphore{}, last.SignalSemaphores...), c.semaphore)
+
+	// Binary semaphores ignore their values, but every semaphore needs one
+	signalValues := make([]uint64, len(last.SignalSemaphores))
+	signalValues[len(signalValues)-1] = frame
+	last.Next = core1_2.TimelineSemaphoreSubmitInfo{
+		WaitSemaphoreValues:   make([]uint64, len(last.WaitSemaphores)),
+		SignalSemaphoreValues: signalValues,
+		NextOptions:           common.NextOptions{Next: last.Next},
+	}
+
+	res, err := queue.Submit(nil, submitInfos...)
+	if err != nil {
+		return 0, res, err
+	}
+
+	c.submitted = frame
+	return frame, res, nil
+}
+
+func (c *Counter) takeFence() (core1_0.Fence, error) {
+	if len(c.free) > 0 {
+		fence := c.free[len(c.free)-1]
+		c.free = c.free[:len(c.free)-1]
+		return fence, nil
+	}
+
+	fence, _, err := c.deviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
+	return fence, err
+}
+
+// Submitted is the number of the most recent Submit
+func (c *Counter) Submitted() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.submitted
+}
+
+// Completed is the number of the most recent frame the GPU has finished, along with
+// every frame before it. It does not block.
+func (c *Counter) Completed() (uint64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.poll()
+}
+
+func (c *Counter) poll() (uint64, error) {
+	if c.timeline != nil {
+		value, _, err := c.timeline.GetSemaphoreCounterValue(c.semaphore)
+		if err != nil {
+			return c.completed, err
+		}
+
+		c.completed = max(c.completed, value)
+		return c.completed, nil
+	}
+
+	for len(c.pending) > 0 {
+		oldest := c.pending[0]
+		res, err := c.deviceDriver.GetFenceStatus(oldest.fence)
+		if err != nil {
+			return c.completed, err
+		} else if res != core1_0.VKSuccess {
+			break
+		}
+
+		c.completed = oldest.frame
+		c.pending = c.pending[1:]
+		oldest.done = true
+
+		err = c.recycleIfUnused(oldest)
+		if err != nil {
+			return c.completed, err
+		}
+	}
+
+	return c.completed, nil
+}
+
+// recycleIfUnused resets a completed frame's fence and returns it to the pool once no
+// Wait is blocked on it. c.lock must be held.
+func (c *Counter) recycleIfUnused(pending *pendingFence) error {
+	if !pending.done || pending.waiters > 0 {
+		return nil
+	}
+
+	_, err := c.deviceDriver.ResetFences(pending.fence)
+	if err != nil {
+		c.deviceDriver.DestroyFence(pending.fence, nil)
+		return err
+	}
+
+	c.free = append(c.free, pending.fence)
+	return nil
+}
+
+// Wait blocks until the GPU has finished frame, or ctx is done. The result is that of the
+// last Vulkan wait, so device loss can be told apart from other errors.
+func (c *Counter) Wait(ctx context.Context, frame uint64) (common.VkResult, error) {
+	for {
+		c.lock.Lock()
+		if frame > c.submitted {
+			c.lock.Unlock()
+			return core1_0.VKErrorUnknown, errors.Errorf("frame %d has not been submitted, the last submitted frame is %d", frame, c.submitted)
+		}
+
+		completed, err := c.poll()
+		if err != nil || completed >= frame {
+			c.lock.Unlock()
+			return core1_0.VKSuccess, err
+		}
+
+		var waitingOn *pendingFence
+		if c.timeline == nil {
+			for _, pending := range c.pending {
+				if pending.frame >= frame {
+					waitingOn = pending
+					break
+				}
+			}
+
+			// The fence isn't reset or recycled while anyone is waiting on it
+			waitingOn.waiters++
+		}
+		c.lock.Unlock()
+
+		var res common.VkResult
+		if c.timeline != nil {
+			res, err = c.timeline.WaitSemaphores(PollInterval, core1_2.SemaphoreWaitInfo{
+				Semaphores: []core1_0.Semaphore{c.semaphore},
+				Values:     []uint64{frame},
+			})
+		} else {
+			res, err = c.deviceDriver.WaitForFences(true, PollInterval, waitingOn.fence)
+
+			c.lock.Lock()
+			waitingOn.waiters--
+			recycleErr := c.recycleIfUnused(waitingOn)
+			c.lock.Unlock()
+
+			if err == nil {
+				err = recycleErr
+			}
+		}
+		if err != nil {
+			return res, err
+		}
+
+		if res == core1_0.VKTimeout {
+			select {
+			case <-ctx.Done():
+				return res, errors.Wrapf(ctx.Err(), "waiting for frame %d", frame)
+			default:
+			}
+		}
+	}
+}
+
+// WaitIdle blocks until every submitted frame has finished, or ctx is done
+func (c *Counter) WaitIdle(ctx context.Context) (common.VkResult, error) {
+	return c.Wait(ctx, c.Submitted())
+}
+
+// Destroy destroys the counter's semaphore or fences. The GPU must be done with every
+// frame.
+func (c *Counter) Destroy() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.semaphore.Initialized() {
+		c.deviceDriver.DestroySemaphore(c.semaphore, nil)
+	}
+
+	for _, pending := range c.pending {
+		c.deviceDriver.DestroyFence(pending.fence, nil)
+	}
+	for _, fence := range c.free {
+		c.deviceDriver.DestroyFence(fence, nil)
+	}
+	c.pending = nil
+	c.free = nil
+}
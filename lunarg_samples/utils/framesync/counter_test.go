@@ -0,0 +1,162 @@
+package framesync
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queues"
+)
+
+// fakeDriver implements the fence and queue commands a fence-backed Counter uses, and
+// fails the test if a fence is reset while a wait on it is blocked
+type fakeDriver struct {
+	core1_0.DeviceDriver
+	t *testing.T
+
+	lock      sync.Mutex
+	signaled  map[loader.VkFence]bool
+	waiting   map[loader.VkFence]int
+	nextFence loader.VkFence
+	resets    int
+	// waitStarted receives each fence a wait blocks on, and the wait returns once
+	// releaseWait is closed
+	waitStarted chan loader.VkFence
+	releaseWait chan struct{}
+}
+
+func newFakeDriver(t *testing.T) *fakeDriver {
+	return &fakeDriver{
+		t:           t,
+		signaled:    make(map[loader.VkFence]bool),
+		waiting:     make(map[loader.VkFence]int),
+		waitStarted: make(chan loader.VkFence, 1),
+		releaseWait: make(chan struct{}),
+	}
+}
+
+func (d *fakeDriver) QueueSubmit(queue core1_0.Queue, fence *core1_0.Fence, o ...core1_0.SubmitInfo) (common.VkResult, error) {
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) QueueWaitIdle(queue core1_0.Queue) (common.VkResult, error) {
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) CreateFence(allocationCallbacks *loader.AllocationCallbacks, o core1_0.FenceCreateInfo) (core1_0.Fence, common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.nextFence++
+	return core1_0.InternalFence(0, d.nextFence, common.Vulkan1_0), core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) GetFenceStatus(fence core1_0.Fence) (common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.signaled[fence.Handle()] {
+		return core1_0.VKSuccess, nil
+	}
+	return core1_0.VKNotReady, nil
+}
+
+func (d *fakeDriver) WaitForFences(waitForAll bool, timeout time.Duration, fences ...core1_0.Fence) (common.VkResult, error) {
+	handle := fences[0].Handle()
+	d.lock.Lock()
+	d.waiting[handle]++
+	d.lock.Unlock()
+
+	d.waitStarted <- handle
+	<-d.releaseWait
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.waiting[handle]--
+	if d.signaled[handle] {
+		return core1_0.VKSuccess, nil
+	}
+	return core1_0.VKTimeout, nil
+}
+
+func (d *fakeDriver) ResetFences(fences ...core1_0.Fence) (common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, fence := range fences {
+		if d.waiting[fence.Handle()] > 0 {
+			d.t.Errorf("fence %#x reset while a wait on it is blocked", fence.Handle())
+		}
+		delete(d.signaled, fence.Handle())
+		d.resets++
+	}
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) DestroyFence(fence core1_0.Fence, callbacks *loader.AllocationCallbacks) {}
+
+func (d *fakeDriver) signal(fence loader.VkFence) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.signaled[fence] = true
+}
+
+func TestFenceNotRecycledWhileWaiting(t *testing.T) {
+	driver := newFakeDriver(t)
+	counter, err := New(driver, Setup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := queues.New(driver).Get(core1_0.InternalQueue(0, 1, common.Vulkan1_0))
+
+	frame, _, err := counter.Submit(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitDone := make(chan error)
+	go func() {
+		_, err := counter.Wait(context.Background(), frame)
+		waitDone <- err
+	}()
+
+	fence := <-driver.waitStarted
+
+	// Another goroutine sees the fence signal while the wait is still blocked on it
+	driver.signal(fence)
+	completed, err := counter.Completed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed != frame {
+		t.Errorf("completed is %d, want %d", completed, frame)
+	}
+	if driver.resets != 0 {
+		t.Error("fence was reset while a wait on it is blocked")
+	}
+
+	close(driver.releaseWait)
+	err = <-waitDone
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if driver.resets != 1 {
+		t.Errorf("fence was reset %d times once the wait returned, want 1", driver.resets)
+	}
+
+	// The next frame reuses the recycled fence
+	_, _, err = counter.Submit(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver.nextFence != 1 {
+		t.Errorf("created %d fences, want the first to be reused", driver.nextFence)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"unsafe"
 
 	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
 	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
 )
@@ -52,11 +53,12 @@ func (i *SampleInfo) SetImageLayout(image core1_0.Image, aspectMask core1_0.Imag
 		imageBarrierOptions.DstAccessMask = core1_0.AccessDepthStencilAttachmentWrite
 	}
 
-	return i.DeviceDriver.CmdPipelineBarrier(i.Cmd, sourceStages, destStages, 0, nil, nil, []core1_0.ImageMemoryBarrier{imageBarrierOptions})
+	err := i.DeviceDriver.CmdPipelineBarrier(i.Cmd, sourceStages, destStages, 0, nil, nil, []core1_0.ImageMemoryBarrier{imageBarrierOptions})
+	return vulkanError("vkCmdPipelineBarrier", "Sample Command Buffer", core1_0.VKSuccess, err)
 }
 
 func (i *SampleInfo) WritePNG(baseName string) error {
-	mappableImage, _, err := i.DeviceDriver.CreateImage(nil, core1_0.ImageCreateInfo{
+	mappableImage, res, err := i.DeviceDriver.CreateImage(nil, core1_0.ImageCreateInfo{
 		ImageType: core1_0.ImageType2D,
 		Format:    i.Format,
 		Extent: core1_0.Extent3D{
@@ -72,8 +74,8 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 		SharingMode:   core1_0.SharingModeExclusive,
 		InitialLayout: core1_0.ImageLayoutUndefined,
 	})
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateImage", "Screenshot Image", res, err)
 	}
 
 	memReqs := i.DeviceDriver.GetImageMemoryRequirements(mappableImage)
@@ -82,22 +84,22 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 		return err
 	}
 
-	mappableMemory, _, err := i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	mappableMemory, res, err := i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryTypeIndex,
 	})
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", "Screenshot Image Memory", res, err)
 	}
 
-	_, err = i.DeviceDriver.BindImageMemory(mappableImage, mappableMemory, 0)
-	if err != nil {
-		return err
+	res, err = i.DeviceDriver.BindImageMemory(mappableImage, mappableMemory, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBindImageMemory", "Screenshot Image", res, err)
 	}
 
-	_, err = i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
-	if err != nil {
-		return err
+	res, err = i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBeginCommandBuffer", "Sample Command Buffer", res, err)
 	}
 
 	err = i.SetImageLayout(mappableImage, core1_0.ImageAspectColor, core1_0.ImageLayoutUndefined, core1_0.ImageLayoutTransferDstOptimal, core1_0.PipelineStageTopOfPipe, core1_0.PipelineStageTransfer)
@@ -133,7 +135,7 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 		},
 	)
 	if err != nil {
-		return err
+		return vulkanError("vkCmdCopyImage", "Screenshot Image", core1_0.VKSuccess, err)
 	}
 
 	err = i.SetImageLayout(mappableImage, core1_0.ImageAspectColor, core1_0.ImageLayoutTransferDstOptimal, core1_0.ImageLayoutGeneral, core1_0.PipelineStageTransfer, core1_0.PipelineStageHost)
@@ -141,29 +143,30 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 		return err
 	}
 
-	_, err = i.DeviceDriver.EndCommandBuffer(i.Cmd)
-	if err != nil {
-		return err
+	res, err = i.DeviceDriver.EndCommandBuffer(i.Cmd)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEndCommandBuffer", "Sample Command Buffer", res, err)
 	}
 
-	cmdFence, _, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
-	if err != nil {
-		return err
+	cmdFence, res, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateFence", "Screenshot Fence", res, err)
 	}
 
-	_, err = i.DeviceDriver.QueueSubmit(i.GraphicsQueue, &cmdFence,
+	res, err = i.SyncGraphicsQueue.Submit(&cmdFence,
 		core1_0.SubmitInfo{
 			CommandBuffers: []core1_0.CommandBuffer{i.Cmd},
 		},
 	)
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkQueueSubmit", "Graphics Queue", res, err)
 	}
 
 	for {
 		res, err := i.DeviceDriver.WaitForFences(true, FenceTimeout, cmdFence)
+		i.Breadcrumbs.Check(res)
 		if err != nil {
-			return err
+			return vulkanError("vkWaitForFences", "Screenshot Fence", res, err)
 		}
 
 		if res != core1_0.VKTimeout {
@@ -180,9 +183,9 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 		ArrayLayer: 0,
 	})
 
-	memPtr, _, err := i.DeviceDriver.MapMemory(mappableMemory, 0, memReqs.Size, 0)
-	if err != nil {
-		return err
+	memPtr, res, err := i.DeviceDriver.MapMemory(mappableMemory, 0, memReqs.Size, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkMapMemory", "Screenshot Image Memory", res, err)
 	}
 
 	dataBuffer := unsafe.Slice((*byte)(memPtr), memReqs.Size)
@@ -234,12 +237,18 @@ func (i *SampleInfo) WritePNG(baseName string) error {
 }
 
 func (i *SampleInfo) InitTextureBuffer(textureObj *TextureObject) error {
+	var res common.VkResult
 	var err error
-	textureObj.Buffer, _, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+	textureObj.Buffer, res, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
 		Size:        textureObj.TexWidth * textureObj.TexHeight * 4,
 		Usage:       core1_0.BufferUsageTransferSrc,
 		SharingMode: core1_0.SharingModeExclusive,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateBuffer", fmt.Sprintf("Texture %d Staging Buffer", len(i.Textures)), res, err)
+	}
+
+	err = i.DebugNames.Namef(textureObj.Buffer, "Texture %d Staging Buffer", len(i.Textures))
 	if err != nil {
 		return err
 	}
@@ -254,16 +263,21 @@ func (i *SampleInfo) InitTextureBuffer(textureObj *TextureObject) error {
 	}
 
 	/* allocate memory */
-	textureObj.BufferMemory, _, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	textureObj.BufferMemory, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryIndex,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", fmt.Sprintf("Texture %d Staging Buffer Memory", len(i.Textures)), res, err)
+	}
+
+	err = i.DebugNames.Namef(textureObj.BufferMemory, "Texture %d Staging Buffer Memory", len(i.Textures))
 	if err != nil {
 		return err
 	}
 
-	_, err = i.DeviceDriver.BindBufferMemory(textureObj.Buffer, textureObj.BufferMemory, 0)
-	return err
+	res, err = i.DeviceDriver.BindBufferMemory(textureObj.Buffer, textureObj.BufferMemory, 0)
+	return vulkanError("vkBindBufferMemory", fmt.Sprintf("Texture %d Staging Buffer", len(i.Textures)), res, err)
 }
 
 func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.ImageUsageFlags, extraFeatures core1_0.FormatFeatureFlags) (*TextureObject, error) {
@@ -272,6 +286,7 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 		return nil, err
 	}
 
+	var res common.VkResult
 	textureObj := &TextureObject{}
 	textureObj.TexWidth = image.Bounds().Size().X
 	textureObj.TexHeight = image.Bounds().Size().Y
@@ -285,7 +300,7 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 
 	if textureObj.NeedsStaging {
 		if (formatProps.OptimalTilingFeatures & allFeatures) != allFeatures {
-			return nil, errors.Errorf("Format %s cannot support featureset %s\n", core1_0.FormatR8G8B8A8UnsignedNormalized, allFeatures)
+			return nil, errors.Wrapf(ErrFormatUnsupported, "format %s cannot support featureset %s", core1_0.FormatR8G8B8A8UnsignedNormalized, allFeatures)
 		}
 		err = i.InitTextureBuffer(textureObj)
 		if err != nil {
@@ -312,7 +327,12 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 		imageOptions.InitialLayout = core1_0.ImageLayoutPreInitialized
 	}
 
-	textureObj.Image, _, err = i.DeviceDriver.CreateImage(nil, imageOptions)
+	textureObj.Image, res, err = i.DeviceDriver.CreateImage(nil, imageOptions)
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkCreateImage", fmt.Sprintf("Texture %d Image", len(i.Textures)), res, err)
+	}
+
+	err = i.DebugNames.Namef(textureObj.Image, "Texture %d Image", len(i.Textures))
 	if err != nil {
 		return nil, err
 	}
@@ -330,38 +350,43 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 	}
 
 	/* allocate memory */
-	textureObj.ImageMemory, _, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	textureObj.ImageMemory, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryIndex,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkAllocateMemory", fmt.Sprintf("Texture %d Image Memory", len(i.Textures)), res, err)
+	}
+
+	err = i.DebugNames.Namef(textureObj.ImageMemory, "Texture %d Image Memory", len(i.Textures))
 	if err != nil {
 		return nil, err
 	}
 
 	/* bind memory */
-	_, err = i.DeviceDriver.BindImageMemory(textureObj.Image, textureObj.ImageMemory, 0)
-	if err != nil {
-		return nil, err
+	res, err = i.DeviceDriver.BindImageMemory(textureObj.Image, textureObj.ImageMemory, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkBindImageMemory", fmt.Sprintf("Texture %d Image", len(i.Textures)), res, err)
 	}
 
-	_, err = i.DeviceDriver.EndCommandBuffer(i.Cmd)
-	if err != nil {
-		return nil, err
+	res, err = i.DeviceDriver.EndCommandBuffer(i.Cmd)
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkEndCommandBuffer", "Sample Command Buffer", res, err)
 	}
 
-	cmdFence, _, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
-	if err != nil {
-		return nil, err
+	cmdFence, res, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkCreateFence", "Texture Upload Fence", res, err)
 	}
 
 	/* Queue the command buffer for execution */
-	_, err = i.DeviceDriver.QueueSubmit(i.GraphicsQueue, &cmdFence,
+	res, err = i.SyncGraphicsQueue.Submit(&cmdFence,
 		core1_0.SubmitInfo{
 			CommandBuffers: []core1_0.CommandBuffer{i.Cmd},
 		},
 	)
-	if err != nil {
-		return nil, err
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkQueueSubmit", "Graphics Queue", res, err)
 	}
 
 	subResource := &core1_0.ImageSubresource{
@@ -378,8 +403,9 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 	/* Make sure command buffer is finished before mapping */
 	for {
 		res, err := i.DeviceDriver.WaitForFences(true, FenceTimeout, cmdFence)
+		i.Breadcrumbs.Check(res)
 		if err != nil {
-			return nil, err
+			return nil, vulkanError("vkWaitForFences", "Texture Upload Fence", res, err)
 		}
 
 		if res != core1_0.VKTimeout {
@@ -391,15 +417,18 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 
 	var dataPtr unsafe.Pointer
 	var data []byte
+	var mappedName string
 	if textureObj.NeedsStaging {
-		dataPtr, _, err = i.DeviceDriver.MapMemory(textureObj.BufferMemory, 0, textureObj.BufferSize, 0)
+		dataPtr, res, err = i.DeviceDriver.MapMemory(textureObj.BufferMemory, 0, textureObj.BufferSize, 0)
 		data = ([]byte)(unsafe.Slice((*byte)(dataPtr), textureObj.BufferSize))
+		mappedName = fmt.Sprintf("Texture %d Staging Buffer Memory", len(i.Textures))
 	} else {
-		dataPtr, _, err = i.DeviceDriver.MapMemory(textureObj.ImageMemory, 0, memReqs.Size, 0)
+		dataPtr, res, err = i.DeviceDriver.MapMemory(textureObj.ImageMemory, 0, memReqs.Size, 0)
 		data = ([]byte)(unsafe.Slice((*byte)(dataPtr), memReqs.Size))
+		mappedName = fmt.Sprintf("Texture %d Image Memory", len(i.Textures))
 	}
-	if err != nil {
-		return nil, err
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkMapMemory", mappedName, res, err)
 	}
 
 	/* Read the image file into the mappable image's memory */
@@ -427,15 +456,21 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 		i.DeviceDriver.UnmapMemory(textureObj.ImageMemory)
 	}
 
-	_, err = i.DeviceDriver.ResetCommandBuffer(i.Cmd, 0)
-	if err != nil {
-		return nil, err
+	res, err = i.DeviceDriver.ResetCommandBuffer(i.Cmd, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkResetCommandBuffer", "Sample Command Buffer", res, err)
 	}
-	_, err = i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
-	if err != nil {
-		return nil, err
+	res, err = i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkBeginCommandBuffer", "Sample Command Buffer", res, err)
 	}
 
+	uploadLabel := i.DebugNames.BeginCommandLabel(i.Cmd, fmt.Sprintf("Texture %d Upload", len(i.Textures)), nil)
+	defer uploadLabel.End()
+
+	i.Breadcrumbs.Reset(i.Cmd)
+	i.Breadcrumbs.Mark(i.Cmd, fmt.Sprintf("Texture %d Upload", len(i.Textures)))
+
 	if !textureObj.NeedsStaging {
 		/* If we can use the linear tiled image as a texture, just do it */
 		textureObj.ImageLayout = core1_0.ImageLayoutShaderReadOnlyOptimal
@@ -468,7 +503,7 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 			},
 		)
 		if err != nil {
-			return nil, err
+			return nil, vulkanError("vkCmdCopyBufferToImage", fmt.Sprintf("Texture %d Image", len(i.Textures)), core1_0.VKSuccess, err)
 		}
 
 		/* Set the layout for the texture image from DESTINATION_OPTIMAL to
@@ -481,7 +516,7 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 	}
 
 	/* create image view */
-	textureObj.View, _, err = i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
+	textureObj.View, res, err = i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
 		Image:    textureObj.Image,
 		ViewType: core1_0.ImageViewType2D,
 		Format:   core1_0.FormatR8G8B8A8UnsignedNormalized,
@@ -499,6 +534,11 @@ func (i *SampleInfo) InitImage(textureReader io.Reader, extraUsages core1_0.Imag
 			LayerCount:     1,
 		},
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vulkanError("vkCreateImageView", fmt.Sprintf("Texture %d Image View", len(i.Textures)), res, err)
+	}
+
+	err = i.DebugNames.Namef(textureObj.View, "Texture %d Image View", len(i.Textures))
 	return textureObj, err
 }
 
@@ -515,6 +555,11 @@ func (i *SampleInfo) InitTexture(textureReader io.Reader, extraUsages core1_0.Im
 		return err
 	}
 
+	err = i.DebugNames.Namef(texObj.Sampler, "Texture %d Sampler", len(i.Textures))
+	if err != nil {
+		return err
+	}
+
 	i.Textures = append(i.Textures, texObj)
 
 	/* track a description of the texture */
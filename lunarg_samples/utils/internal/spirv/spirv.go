@@ -0,0 +1,95 @@
+// Package spirv decodes the parts of a SPIR-V module that vertexinput and blocklayout
+// reflect over: the instruction stream, names and type declarations.
+package spirv
+
+import (
+	"github.com/pkg/errors"
+)
+
+const Magic = 0x07230203
+
+// headerWords is the length of the module header that precedes the first instruction
+const headerWords = 5
+
+// Opcodes
+const (
+	OpName             = 5
+	OpMemberName       = 6
+	OpTypeBool         = 20
+	OpTypeInt          = 21
+	OpTypeFloat        = 22
+	OpTypeVector       = 23
+	OpTypeMatrix       = 24
+	OpTypeArray        = 28
+	OpTypeRuntimeArray = 29
+	OpTypeStruct       = 30
+	OpTypePointer      = 32
+	OpConstant         = 43
+	OpVariable         = 59
+	OpDecorate         = 71
+	OpMemberDecorate   = 72
+)
+
+// Decorations
+const (
+	DecorationBlock         = 2
+	DecorationBufferBlock   = 3
+	DecorationArrayStride   = 6
+	DecorationMatrixStride  = 7
+	DecorationBuiltIn       = 11
+	DecorationLocation      = 30
+	DecorationBinding       = 33
+	DecorationDescriptorSet = 34
+	DecorationOffset        = 35
+)
+
+// Storage classes
+const (
+	StorageClassInput         = 1
+	StorageClassUniform       = 2
+	StorageClassPushConstant  = 9
+	StorageClassStorageBuffer = 12
+)
+
+// Type is a type declaration. Operands excludes the result ID.
+type Type struct {
+	Opcode   int
+	Operands []uint32
+}
+
+// Instructions checks the module header and calls visit with each instruction's opcode
+// and operands, in order
+func Instructions(code []uint32, visit func(opcode int, operands []uint32)) error {
+	if len(code) < headerWords || code[0] != Magic {
+		return errors.New("not a SPIR-V module")
+	}
+
+	for position := headerWords; position < len(code); {
+		wordCount := int(code[position] >> 16)
+		opcode := int(code[position] & 0xffff)
+		if wordCount == 0 || position+wordCount > len(code) {
+			return errors.Errorf("malformed instruction at word %d", position)
+		}
+
+		visit(opcode, code[position+1:position+wordCount])
+		position += wordCount
+	}
+
+	return nil
+}
+
+// DecodeString decodes a nul-terminated literal string operand
+func DecodeString(words []uint32) string {
+	var bytes []byte
+	for _, word := range words {
+		for shift := 0; shift < 32; shift += 8 {
+			b := byte(word >> shift)
+			if b == 0 {
+				return string(bytes)
+			}
+			bytes = append(bytes, b)
+		}
+	}
+
+	return string(bytes)
+}
@@ -0,0 +1,94 @@
+// Package vkerror holds the typed Vulkan errors returned by SampleInfo, so the utility
+// packages it uses can return the same errors.
+package vkerror
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
+)
+
+var (
+	ErrOutOfDate         = errors.New("swapchain out of date")
+	ErrSuboptimal        = errors.New("swapchain suboptimal")
+	ErrIncomplete        = errors.New("incomplete results")
+	ErrDeviceLost        = errors.New("device lost")
+	ErrOutOfDeviceMemory = errors.New("out of device memory")
+	ErrOutOfHostMemory   = errors.New("out of host memory")
+	ErrFormatUnsupported = errors.New("format unsupported")
+)
+
+var resultErrors = map[common.VkResult]error{
+	khr_swapchain.VKErrorOutOfDate:    ErrOutOfDate,
+	khr_swapchain.VKSuboptimal:        ErrSuboptimal,
+	core1_0.VKIncomplete:              ErrIncomplete,
+	core1_0.VKErrorDeviceLost:         ErrDeviceLost,
+	core1_0.VKErrorOutOfDeviceMemory:  ErrOutOfDeviceMemory,
+	core1_0.VKErrorOutOfHostMemory:    ErrOutOfHostMemory,
+	core1_0.VKErrorFormatNotSupported: ErrFormatUnsupported,
+}
+
+// VulkanError is returned when a Vulkan command fails or returns a success code other than
+// VK_SUCCESS. It matches the Err* sentinels for its Result with errors.Is.
+type VulkanError struct {
+	// Op is the Vulkan command that failed, such as vkCreateImage
+	Op string
+	// Object is the debug name of the object being created or operated on, if any
+	Object string
+	Result common.VkResult
+	// Err is the error returned by the command, which is nil for success codes
+	Err error
+}
+
+func (e *VulkanError) Error() string {
+	description := e.Op
+	if e.Object != "" {
+		description = fmt.Sprintf("%s (%s)", e.Op, e.Object)
+	}
+
+	if e.Err == nil {
+		return fmt.Sprintf("%s: returned %s", description, e.Result)
+	}
+
+	return fmt.Sprintf("%s: %s", description, e.Err)
+}
+
+func (e *VulkanError) Unwrap() error {
+	return e.Err
+}
+
+func (e *VulkanError) Is(target error) bool {
+	sentinel, hasSentinel := resultErrors[e.Result]
+	return hasSentinel && sentinel == target
+}
+
+// IsStatus reports whether the command completed and Result is a success code such as
+// VK_SUBOPTIMAL_KHR or VK_INCOMPLETE. Any outputs of the command are still valid.
+func (e *VulkanError) IsStatus() bool {
+	return e.Err == nil && e.Result > core1_0.VKSuccess
+}
+
+// IsStatus reports whether err is a VulkanError carrying a non-error result code, which
+// callers can usually continue past
+func IsStatus(err error) bool {
+	var vkErr *VulkanError
+	return errors.As(err, &vkErr) && vkErr.IsStatus()
+}
+
+// New returns a *VulkanError for a command that returned res and err, or nil if it
+// returned VK_SUCCESS
+func New(op string, object string, res common.VkResult, err error) error {
+	if err == nil && res == core1_0.VKSuccess {
+		return nil
+	}
+
+	return &VulkanError{
+		Op:     op,
+		Object: object,
+		Result: res,
+		Err:    err,
+	}
+}
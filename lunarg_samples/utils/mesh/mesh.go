@@ -0,0 +1,87 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Mesh is indexed geometry: a list of distinct vertices and the triangle list that
+// refers to them
+type Mesh[V any] struct {
+	Vertices []V
+	Indices  []uint32
+}
+
+// IndexTypeFor is the smallest index type that can address vertexCount vertices
+func IndexTypeFor(vertexCount int) core1_0.IndexType {
+	if vertexCount <= math.MaxUint16+1 {
+		return core1_0.IndexTypeUInt16
+	}
+
+	return core1_0.IndexTypeUInt32
+}
+
+// IndexType is the smallest index type that can address every vertex in the mesh
+func (m *Mesh[V]) IndexType() core1_0.IndexType {
+	return IndexTypeFor(len(m.Vertices))
+}
+
+// IndexData returns the indices in the form IndexType expects: a []uint16 for
+// core1_0.IndexTypeUInt16 and a []uint32 for core1_0.IndexTypeUInt32
+func (m *Mesh[V]) IndexData() any {
+	return Narrow(m.Indices, m.IndexType())
+}
+
+// Narrow converts indices to the slice type that indexType expects
+func Narrow(indices []uint32, indexType core1_0.IndexType) any {
+	if indexType != core1_0.IndexTypeUInt16 {
+		return indices
+	}
+
+	narrowed := make([]uint16, len(indices))
+	for i, index := range indices {
+		narrowed[i] = uint16(index)
+	}
+	return narrowed
+}
+
+// Builder assembles a Mesh one vertex at a time, reusing any vertex that was already
+// added under the same key. The key is whatever identifies a vertex in the source data,
+// such as its index in a model file, or the vertex itself.
+type Builder[K comparable, V any] struct {
+	Mesh[V]
+
+	unique map[K]uint32
+}
+
+// NewBuilder creates an empty Builder
+func NewBuilder[K comparable, V any]() *Builder[K, V] {
+	return &Builder[K, V]{
+		unique: make(map[K]uint32),
+	}
+}
+
+// Add appends an index for the vertex identified by key, calling vertex to create it only
+// if key hasn't been seen before
+func (b *Builder[K, V]) Add(key K, vertex func() V) uint32 {
+	index, exists := b.unique[key]
+	if !exists {
+		index = uint32(len(b.Vertices))
+		b.Vertices = append(b.Vertices, vertex())
+		b.unique[key] = index
+	}
+
+	b.Indices = append(b.Indices, index)
+	return index
+}
+
+// Deduplicate turns a raw triangle list into a Mesh, merging vertices that are equal
+func Deduplicate[V comparable](vertices []V) *Mesh[V] {
+	builder := NewBuilder[V, V]()
+	for _, vertex := range vertices {
+		builder.Add(vertex, func() V { return vertex })
+	}
+
+	return &builder.Mesh
+}
@@ -0,0 +1,97 @@
+package pipelinecache
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// HeaderSize is the size in bytes of VkPipelineCacheHeaderVersionOne
+const HeaderSize = 16 + len(uuid.UUID{})
+
+var (
+	// ErrCorrupt is returned when cache data is too short or its header is malformed
+	ErrCorrupt = errors.New("corrupt pipeline cache data")
+	// ErrForeign is returned when cache data was written by a different device or driver
+	ErrForeign = errors.New("pipeline cache data from another device")
+)
+
+// Header is the VkPipelineCacheHeaderVersionOne at the start of every pipeline cache blob
+//
+//	Offset  Size          Meaning
+//	     0  4             length in bytes of the entire pipeline cache header
+//	     4  4             a VkPipelineCacheHeaderVersion value
+//	     8  4             a vendor ID equal to VkPhysicalDeviceProperties::vendorID
+//	    12  4             a device ID equal to VkPhysicalDeviceProperties::deviceID
+//	    16  VK_UUID_SIZE  a pipeline cache ID equal to VkPhysicalDeviceProperties::pipelineCacheUUID
+//
+// All fields are written least significant byte first.
+type Header struct {
+	Length    uint32
+	Version   core1_0.PipelineCacheHeaderVersion
+	VendorID  uint32
+	DeviceID  uint32
+	CacheUUID uuid.UUID
+}
+
+// ParseHeader reads the header from the start of data
+func ParseHeader(data []byte) (Header, error) {
+	var header Header
+	if len(data) < HeaderSize {
+		return header, errors.Wrapf(ErrCorrupt, "%d bytes is too short to contain a header", len(data))
+	}
+
+	var version uint32
+	reader := bytes.NewReader(data)
+	fields := []any{&header.Length, &version, &header.VendorID, &header.DeviceID, &header.CacheUUID}
+	for _, field := range fields {
+		err := binary.Read(reader, common.ByteOrder, field)
+		if err != nil {
+			return header, errors.Wrap(ErrCorrupt, err.Error())
+		}
+	}
+	header.Version = core1_0.PipelineCacheHeaderVersion(version)
+
+	if header.Length < uint32(HeaderSize) || header.Length > uint32(len(data)) {
+		return header, errors.Wrapf(ErrCorrupt, "bad header length %d", header.Length)
+	}
+
+	return header, nil
+}
+
+// Validate reports whether the header was written by the device described by props. The
+// driver is allowed to reject a blob from another device or driver version, but some
+// crash instead, so foreign data should never reach vkCreatePipelineCache.
+func (h Header) Validate(props *core1_0.PhysicalDeviceProperties) error {
+	if h.Version != core1_0.PipelineCacheHeaderVersionOne {
+		return errors.Wrapf(ErrCorrupt, "unsupported header version %d", h.Version)
+	}
+
+	if h.VendorID != props.VendorID {
+		return errors.Wrapf(ErrForeign, "vendor ID 0x%x, device expects 0x%x", h.VendorID, props.VendorID)
+	}
+
+	if h.DeviceID != props.DeviceID {
+		return errors.Wrapf(ErrForeign, "device ID 0x%x, device expects 0x%x", h.DeviceID, props.DeviceID)
+	}
+
+	if h.CacheUUID != props.PipelineCacheUUID {
+		return errors.Wrapf(ErrForeign, "cache UUID %s, device expects %s", h.CacheUUID, props.PipelineCacheUUID)
+	}
+
+	return nil
+}
+
+// ValidateData parses the header from data and validates it against props
+func ValidateData(data []byte, props *core1_0.PhysicalDeviceProperties) error {
+	header, err := ParseHeader(data)
+	if err != nil {
+		return err
+	}
+
+	return header.Validate(props)
+}
@@ -0,0 +1,146 @@
+package pipelinecache
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Store keeps pipeline cache data for a single device on disk. Each device and driver
+// version gets its own file in Dir, so several GPUs can share one cache directory.
+type Store struct {
+	// Dir is the directory cache files are stored in. It is created when the first
+	// cache is saved.
+	Dir string
+	// Logger receives the reason whenever a cache file is discarded. It defaults to the
+	// standard logger.
+	Logger *log.Logger
+
+	deviceDriver core1_0.CoreDeviceDriver
+	props        *core1_0.PhysicalDeviceProperties
+
+	// mergeLock serializes Merge and Save, which both access a cache that Vulkan requires
+	// to be externally synchronized
+	mergeLock sync.Mutex
+}
+
+// NewStore creates a Store for the device owned by deviceDriver, whose physical device has
+// the provided properties
+func NewStore(dir string, deviceDriver core1_0.CoreDeviceDriver, props *core1_0.PhysicalDeviceProperties) *Store {
+	return &Store{
+		Dir:          dir,
+		Logger:       log.Default(),
+		deviceDriver: deviceDriver,
+		props:        props,
+	}
+}
+
+// Path is the file the current device's cache is stored in
+func (s *Store) Path() string {
+	fileName := fmt.Sprintf("%08x-%08x-%s.bin", s.props.VendorID, s.props.DeviceID, s.props.PipelineCacheUUID)
+	return filepath.Join(s.Dir, fileName)
+}
+
+// Load reads the current device's cache data. If there is no cache file, or it fails
+// validation, Load returns nil. Invalid files are removed so they can be repopulated.
+func (s *Store) Load() ([]byte, error) {
+	path := s.Path()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = ValidateData(data, s.props)
+	if err != nil {
+		s.Logger.Printf("discarding pipeline cache %s: %s", path, err)
+		// Not important if this fails, it will be overwritten by the next save
+		_ = os.Remove(path)
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+// Create creates a pipeline cache populated with the current device's cache data, if
+// there is any
+func (s *Store) Create() (core1_0.PipelineCache, error) {
+	data, err := s.Load()
+	if err != nil {
+		return core1_0.PipelineCache{}, err
+	}
+
+	cache, _, err := s.deviceDriver.CreatePipelineCache(nil, core1_0.PipelineCacheCreateInfo{
+		InitialData: data,
+	})
+	return cache, err
+}
+
+// Save writes the contents of cache to disk. The data is written to a temporary file
+// which replaces the old cache file once it's complete, so a crash partway through never
+// leaves a truncated cache behind. It is safe to call while other goroutines Merge into
+// cache.
+func (s *Store) Save(cache core1_0.PipelineCache) error {
+	s.mergeLock.Lock()
+	defer s.mergeLock.Unlock()
+
+	data, _, err := s.deviceDriver.GetPipelineCacheData(cache)
+	if err != nil {
+		return err
+	}
+
+	err = ValidateData(data, s.props)
+	if err != nil {
+		return errors.Wrap(err, "driver returned invalid pipeline cache data")
+	}
+
+	err = os.MkdirAll(s.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	path := s.Path()
+	tempFile, err := os.CreateTemp(s.Dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+
+	_, err = tempFile.Write(data)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// Merge merges the contents of srcCaches into dstCache. Vulkan requires access to dstCache
+// to be externally synchronized, so Merge may be called from several goroutines at once,
+// for instance as each worker finishes compiling pipelines into its own cache.
+func (s *Store) Merge(dstCache core1_0.PipelineCache, srcCaches ...core1_0.PipelineCache) error {
+	s.mergeLock.Lock()
+	defer s.mergeLock.Unlock()
+
+	_, err := s.deviceDriver.MergePipelineCaches(dstCache, srcCaches...)
+	return err
+}
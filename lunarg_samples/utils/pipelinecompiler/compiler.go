@@ -0,0 +1,167 @@
+package pipelinecompiler
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+)
+
+// Request describes a single pipeline to compile. Exactly one of Graphics and Compute
+// should be set.
+type Request struct {
+	// Name is attached to the pipeline as a debug name and used in error messages
+	Name     string
+	Graphics *core1_0.GraphicsPipelineCreateInfo
+	Compute  *core1_0.ComputePipelineCreateInfo
+}
+
+// Future is the result of a Request which may still be compiling
+type Future struct {
+	Name string
+
+	done     chan struct{}
+	pipeline core1_0.Pipeline
+	duration time.Duration
+	err      error
+}
+
+// Done is closed once the pipeline has finished compiling
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the pipeline has finished compiling and returns it
+func (f *Future) Wait() (core1_0.Pipeline, error) {
+	<-f.done
+	return f.pipeline, f.err
+}
+
+// Duration is the time spent in vkCreate*Pipelines. It blocks until the pipeline has
+// finished compiling.
+func (f *Future) Duration() time.Duration {
+	<-f.done
+	return f.duration
+}
+
+type job struct {
+	request Request
+	cache   *core1_0.PipelineCache
+	future  *Future
+}
+
+// Compiler compiles pipelines on a pool of goroutines. Pipeline caches are internally
+// synchronized, so every worker shares the same cache.
+type Compiler struct {
+	// Names is used to attach debug names to compiled pipelines, and may be nil
+	Names *debugnames.Namer
+
+	deviceDriver core1_0.CoreDeviceDriver
+	cache        *core1_0.PipelineCache
+	jobs         chan job
+	workers      sync.WaitGroup
+}
+
+// New starts a Compiler with the provided number of workers, or one per CPU if workers
+// is 0. cache may be nil.
+func New(deviceDriver core1_0.CoreDeviceDriver, cache *core1_0.PipelineCache, workers int) *Compiler {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	compiler := &Compiler{
+		deviceDriver: deviceDriver,
+		cache:        cache,
+		jobs:         make(chan job, workers*4),
+	}
+
+	compiler.workers.Add(workers)
+	for workerIndex := 0; workerIndex < workers; workerIndex++ {
+		go compiler.work()
+	}
+
+	return compiler
+}
+
+// Close waits for queued pipelines to finish and stops the workers. Compile must not be
+// called after Close.
+func (c *Compiler) Close() {
+	close(c.jobs)
+	c.workers.Wait()
+}
+
+// Compile queues request and returns without waiting for it to compile. It only blocks
+// if the queue is full.
+func (c *Compiler) Compile(request Request) *Future {
+	return c.compileWithCache(request, c.cache)
+}
+
+// CompileAll queues every request and returns their futures in the same order
+func (c *Compiler) CompileAll(requests []Request) []*Future {
+	return c.compileAllWithCache(requests, c.cache)
+}
+
+func (c *Compiler) compileAllWithCache(requests []Request, cache *core1_0.PipelineCache) []*Future {
+	futures := make([]*Future, 0, len(requests))
+	for _, request := range requests {
+		futures = append(futures, c.compileWithCache(request, cache))
+	}
+
+	return futures
+}
+
+func (c *Compiler) compileWithCache(request Request, cache *core1_0.PipelineCache) *Future {
+	future := &Future{
+		Name: request.Name,
+		done: make(chan struct{}),
+	}
+
+	c.jobs <- job{
+		request: request,
+		cache:   cache,
+		future:  future,
+	}
+
+	return future
+}
+
+func (c *Compiler) work() {
+	defer c.workers.Done()
+
+	for nextJob := range c.jobs {
+		future := nextJob.future
+		future.pipeline, future.duration, future.err = c.compile(nextJob.request, nextJob.cache)
+		close(future.done)
+	}
+}
+
+func (c *Compiler) compile(request Request, cache *core1_0.PipelineCache) (core1_0.Pipeline, time.Duration, error) {
+	var pipelines []core1_0.Pipeline
+	var err error
+
+	start := time.Now()
+	if request.Graphics != nil {
+		pipelines, _, err = c.deviceDriver.CreateGraphicsPipelines(cache, nil, *request.Graphics)
+	} else if request.Compute != nil {
+		pipelines, _, err = c.deviceDriver.CreateComputePipelines(cache, nil, *request.Compute)
+	} else {
+		err = errors.New("request has no create info")
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		return core1_0.Pipeline{}, duration, errors.Wrapf(err, "compiling pipeline %s", request.Name)
+	}
+
+	// A caller that gets an error won't destroy the pipeline, so don't hand it back
+	err = c.Names.Name(pipelines[0], request.Name)
+	if err != nil {
+		c.deviceDriver.DestroyPipeline(pipelines[0], nil)
+		return core1_0.Pipeline{}, duration, err
+	}
+
+	return pipelines[0], duration, nil
+}
@@ -0,0 +1,152 @@
+package pipelinecompiler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/pipelinecache"
+)
+
+// WarmupPass is the time taken to compile every pipeline in a warm-up
+type WarmupPass struct {
+	// Wall is the time from queueing the first pipeline until the last one finished
+	Wall time.Duration
+	// Total is the sum of the time spent compiling each pipeline
+	Total time.Duration
+}
+
+type WarmupReport struct {
+	Pipelines int
+	// CacheHit is true if the compiler's pipeline cache already contained data when the
+	// warm-up started, usually because it was loaded from disk
+	CacheHit bool
+	// Cached is the pass that compiled against the compiler's pipeline cache
+	Cached WarmupPass
+	// Uncached is the mean of the passes that compiled with an empty pipeline cache, if
+	// MeasuredUncached is true
+	Uncached         WarmupPass
+	MeasuredUncached bool
+}
+
+func (r WarmupReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d pipelines", r.Pipelines)
+
+	cacheState := "cold cache"
+	if r.CacheHit {
+		cacheState = "cache hit"
+	}
+	fmt.Fprintf(&sb, ", %s: %s (%s compiling)", cacheState, r.Cached.Wall, r.Cached.Total)
+
+	if r.MeasuredUncached {
+		fmt.Fprintf(&sb, ", no cache: %s (%s compiling)", r.Uncached.Wall, r.Uncached.Total)
+	}
+
+	return sb.String()
+}
+
+// Warmup compiles every request against the compiler's pipeline cache and waits for all
+// of them, returning the pipelines in request order. If measureUncached is true, the
+// requests are also compiled with an empty pipeline cache and those pipelines discarded,
+// so the report shows how much the cache saved.
+//
+// Drivers keep caches of their own, so whichever pass runs first warms them for the
+// next. The uncached measurement is the mean of one pass before the cached pass and one
+// after it, each with a fresh pipeline cache, so neither order is favored.
+func (c *Compiler) Warmup(requests []Request, measureUncached bool) ([]core1_0.Pipeline, WarmupReport, error) {
+	report := WarmupReport{
+		Pipelines:        len(requests),
+		MeasuredUncached: measureUncached,
+	}
+
+	if c.cache != nil {
+		data, _, err := c.deviceDriver.GetPipelineCacheData(*c.cache)
+		if err != nil {
+			return nil, report, err
+		}
+
+		// An empty cache is just a header
+		report.CacheHit = len(data) > pipelinecache.HeaderSize
+	}
+
+	var before WarmupPass
+	if measureUncached {
+		var err error
+		before, err = c.uncachedPass(requests)
+		if err != nil {
+			return nil, report, err
+		}
+	}
+
+	pipelines, pass, err := c.warmupPass(requests, c.cache)
+	report.Cached = pass
+	if err != nil || !measureUncached {
+		return pipelines, report, err
+	}
+
+	after, err := c.uncachedPass(requests)
+	if err != nil {
+		for _, pipeline := range pipelines {
+			c.deviceDriver.DestroyPipeline(pipeline, nil)
+		}
+		return nil, report, err
+	}
+
+	report.Uncached = WarmupPass{
+		Wall:  (before.Wall + after.Wall) / 2,
+		Total: (before.Total + after.Total) / 2,
+	}
+	return pipelines, report, nil
+}
+
+// uncachedPass compiles requests against a new, empty pipeline cache and discards the
+// pipelines and the cache
+func (c *Compiler) uncachedPass(requests []Request) (WarmupPass, error) {
+	cache, _, err := c.deviceDriver.CreatePipelineCache(nil, core1_0.PipelineCacheCreateInfo{})
+	if err != nil {
+		return WarmupPass{}, err
+	}
+	defer c.deviceDriver.DestroyPipelineCache(cache, nil)
+
+	pipelines, pass, err := c.warmupPass(requests, &cache)
+	for _, pipeline := range pipelines {
+		c.deviceDriver.DestroyPipeline(pipeline, nil)
+	}
+	return pass, err
+}
+
+func (c *Compiler) warmupPass(requests []Request, cache *core1_0.PipelineCache) ([]core1_0.Pipeline, WarmupPass, error) {
+	var pass WarmupPass
+	var firstErr error
+
+	start := time.Now()
+	futures := c.compileAllWithCache(requests, cache)
+
+	pipelines := make([]core1_0.Pipeline, 0, len(futures))
+	for _, future := range futures {
+		pipeline, err := future.Wait()
+		pass.Total += future.Duration()
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+	pass.Wall = time.Since(start)
+
+	if firstErr != nil {
+		// Don't leak the pipelines that did compile
+		for _, pipeline := range pipelines {
+			c.deviceDriver.DestroyPipeline(pipeline, nil)
+		}
+		return nil, pass, firstErr
+	}
+
+	return pipelines, pass, nil
+}
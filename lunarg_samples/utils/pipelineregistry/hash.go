@@ -0,0 +1,226 @@
+package pipelineregistry
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Key identifies a pipeline description
+type Key [sha256.Size]byte
+
+type handleKey struct {
+	objectType reflect.Type
+	handle     uint64
+}
+
+// handleOf returns the handle of a Vulkan object such as a core1_0.ShaderModule, or false
+// if value isn't one
+func handleOf(value reflect.Value) (handleKey, bool) {
+	if value.Kind() != reflect.Struct || !value.CanInterface() {
+		return handleKey{}, false
+	}
+
+	method := value.MethodByName("Handle")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return handleKey{}, false
+	}
+
+	handle := method.Call(nil)[0]
+	if handle.Kind() != reflect.Uintptr {
+		return handleKey{}, false
+	}
+
+	return handleKey{objectType: value.Type(), handle: handle.Uint()}, true
+}
+
+type hasher struct {
+	hash         hash.Hash
+	descriptions map[handleKey]Key
+	scratch      [8]byte
+}
+
+func newHasher(descriptions map[handleKey]Key) *hasher {
+	return &hasher{
+		hash:         sha256.New(),
+		descriptions: descriptions,
+	}
+}
+
+func (h *hasher) sum() Key {
+	var key Key
+	copy(key[:], h.hash.Sum(nil))
+	return key
+}
+
+func (h *hasher) writeUint(value uint64) {
+	binary.LittleEndian.PutUint64(h.scratch[:], value)
+	h.hash.Write(h.scratch[:])
+}
+
+func (h *hasher) writeString(value string) {
+	h.writeUint(uint64(len(value)))
+	h.hash.Write([]byte(value))
+}
+
+// write hashes value structurally: pointers are followed, and Vulkan objects are hashed
+// by their registered description. A handle may be reused by a new object once the old
+// one is destroyed, so an undescribed object is an error rather than being hashed by
+// handle.
+func (h *hasher) write(value reflect.Value) error {
+	if !value.IsValid() {
+		h.writeUint(0)
+		return nil
+	}
+
+	if handle, isHandle := handleOf(value); isHandle {
+		if handle.handle == 0 {
+			h.writeUint(0)
+			return nil
+		}
+
+		description, described := h.descriptions[handle]
+		if !described {
+			return errors.Errorf("%s %#x has not been described to the registry", handle.objectType, handle.handle)
+		}
+		h.writeUint(1)
+		h.hash.Write(description[:])
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			h.writeUint(1)
+		} else {
+			h.writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.writeUint(uint64(value.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.writeUint(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.writeUint(math.Float64bits(value.Float()))
+	case reflect.String:
+		h.writeString(value.String())
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			h.writeUint(0)
+			return nil
+		}
+		h.writeUint(1)
+		if value.Kind() == reflect.Interface {
+			h.writeString(value.Elem().Type().String())
+		}
+		return h.write(value.Elem())
+	case reflect.Slice, reflect.Array:
+		h.writeUint(uint64(value.Len()))
+		for index := 0; index < value.Len(); index++ {
+			err := h.write(value.Index(index))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for field := 0; field < value.NumField(); field++ {
+			err := h.write(value.Field(field))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map iteration order is random, so hash entries in key order
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i], keys[j])
+		})
+
+		h.writeUint(uint64(len(keys)))
+		for _, key := range keys {
+			err := h.write(key)
+			if err != nil {
+				return err
+			}
+
+			err = h.write(value.MapIndex(key))
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf("cannot hash value of type %s", value.Type())
+	}
+
+	return nil
+}
+
+func lessKey(left, right reflect.Value) bool {
+	switch left.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return left.Int() < right.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return left.Uint() < right.Uint()
+	case reflect.String:
+		return left.String() < right.String()
+	}
+
+	return false
+}
+
+// renderPassCompatibility strips the parts of a render pass description that don't affect
+// compatibility: attachment layouts and load/store ops
+func renderPassCompatibility(createInfo core1_0.RenderPassCreateInfo) core1_0.RenderPassCreateInfo {
+	compatible := core1_0.RenderPassCreateInfo{
+		Flags:               createInfo.Flags,
+		SubpassDependencies: createInfo.SubpassDependencies,
+		NextOptions:         createInfo.NextOptions,
+	}
+
+	for _, attachment := range createInfo.Attachments {
+		compatible.Attachments = append(compatible.Attachments, core1_0.AttachmentDescription{
+			Flags:   attachment.Flags,
+			Format:  attachment.Format,
+			Samples: attachment.Samples,
+		})
+	}
+
+	for _, subpass := range createInfo.Subpasses {
+		compatibleSubpass := core1_0.SubpassDescription{
+			Flags:               subpass.Flags,
+			PipelineBindPoint:   subpass.PipelineBindPoint,
+			InputAttachments:    attachmentIndices(subpass.InputAttachments),
+			ColorAttachments:    attachmentIndices(subpass.ColorAttachments),
+			ResolveAttachments:  attachmentIndices(subpass.ResolveAttachments),
+			PreserveAttachments: subpass.PreserveAttachments,
+		}
+
+		if subpass.DepthStencilAttachment != nil {
+			compatibleSubpass.DepthStencilAttachment = &core1_0.AttachmentReference{
+				Attachment: subpass.DepthStencilAttachment.Attachment,
+			}
+		}
+
+		compatible.Subpasses = append(compatible.Subpasses, compatibleSubpass)
+	}
+
+	return compatible
+}
+
+func attachmentIndices(references []core1_0.AttachmentReference) []core1_0.AttachmentReference {
+	if references == nil {
+		return nil
+	}
+
+	indices := make([]core1_0.AttachmentReference, 0, len(references))
+	for _, reference := range references {
+		indices = append(indices, core1_0.AttachmentReference{Attachment: reference.Attachment})
+	}
+
+	return indices
+}
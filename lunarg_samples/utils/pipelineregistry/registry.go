@@ -0,0 +1,291 @@
+package pipelineregistry
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+)
+
+// DefaultMaxIdle is the number of released pipelines a Registry keeps alive by default
+const DefaultMaxIdle = 16
+
+type entry struct {
+	key      Key
+	pipeline core1_0.Pipeline
+	refs     int
+	// idle is this entry's element in Registry.idle while refs is 0
+	idle *list.Element
+}
+
+// Registry deduplicates pipelines by hashing their full description. Acquiring a pipeline
+// that matches one already alive returns the existing pipeline instead of compiling
+// another.
+//
+// Every shader module, pipeline layout, descriptor set layout, immutable sampler and
+// render pass in a description must first be described to the Registry, which hashes
+// their contents rather than their handles: a driver may give a new object the handle of
+// a destroyed one, and recreating an identical object (as happens on every swapchain
+// recreation) should still hit. Render passes are reduced to just the state that affects
+// render pass compatibility, so a pipeline is only recompiled when the new render pass is
+// actually incompatible with the old one. Base pipelines are described by the Registry
+// itself when it creates them.
+type Registry struct {
+	// MaxIdle is the number of pipelines with no references to keep alive in case they are
+	// acquired again. Beyond that, the least recently released are destroyed.
+	MaxIdle int
+	// Names is used to attach debug names to created pipelines, and may be nil
+	Names *debugnames.Namer
+
+	deviceDriver core1_0.CoreDeviceDriver
+	cache        *core1_0.PipelineCache
+
+	lock         sync.Mutex
+	descriptions map[handleKey]Key
+	entries      map[Key]*entry
+	byPipeline   map[loader.VulkanHandle]*entry
+	idle         list.List
+	hits, misses int
+	onDestroy    []func(object any)
+	// destroyed are the objects to pass to the onDestroy hooks once the lock is released
+	destroyed []any
+}
+
+// New creates an empty Registry. cache may be nil.
+func New(deviceDriver core1_0.CoreDeviceDriver, cache *core1_0.PipelineCache) *Registry {
+	return &Registry{
+		MaxIdle:      DefaultMaxIdle,
+		deviceDriver: deviceDriver,
+		cache:        cache,
+		descriptions: make(map[handleKey]Key),
+		entries:      make(map[Key]*entry),
+		byPipeline:   make(map[loader.VulkanHandle]*entry),
+	}
+}
+
+// OnDestroy adds a hook that is called with each pipeline the Registry destroys, and each
+// object passed to Forget. Hooks run after the Registry's lock is released, so they may
+// use the Registry.
+func (r *Registry) OnDestroy(hook func(object any)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.onDestroy = append(r.onDestroy, hook)
+}
+
+// unlock releases the lock, then passes the objects destroyed while it was held to the
+// onDestroy hooks
+func (r *Registry) unlock() {
+	destroyed := r.destroyed
+	hooks := r.onDestroy
+	r.destroyed = nil
+	r.lock.Unlock()
+
+	for _, object := range destroyed {
+		for _, hook := range hooks {
+			hook(object)
+		}
+	}
+}
+
+func (r *Registry) hash(kind string, description any) (Key, error) {
+	h := newHasher(r.descriptions)
+	h.writeString(kind)
+
+	err := h.write(reflect.ValueOf(description))
+	if err != nil {
+		return Key{}, errors.Wrapf(err, "hashing %s", kind)
+	}
+
+	return h.sum(), nil
+}
+
+func (r *Registry) describe(object any, description any) error {
+	handle, isHandle := handleOf(reflect.ValueOf(object))
+	if !isHandle {
+		return errors.Errorf("%T is not a Vulkan object", object)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key, err := r.hash(handle.objectType.String(), description)
+	if err != nil {
+		return err
+	}
+
+	r.descriptions[handle] = key
+	return nil
+}
+
+// DescribeShaderModule causes pipelines using module to be hashed by its code
+func (r *Registry) DescribeShaderModule(module core1_0.ShaderModule, createInfo core1_0.ShaderModuleCreateInfo) error {
+	return r.describe(module, createInfo)
+}
+
+// DescribeDescriptorSetLayout causes pipeline layouts using layout to be hashed by its
+// bindings. It should be called before describing those pipeline layouts.
+func (r *Registry) DescribeDescriptorSetLayout(layout core1_0.DescriptorSetLayout, createInfo core1_0.DescriptorSetLayoutCreateInfo) error {
+	return r.describe(layout, createInfo)
+}
+
+// DescribePipelineLayout causes pipelines using layout to be hashed by its contents
+func (r *Registry) DescribePipelineLayout(layout core1_0.PipelineLayout, createInfo core1_0.PipelineLayoutCreateInfo) error {
+	return r.describe(layout, createInfo)
+}
+
+// DescribeSampler causes descriptor set layouts with sampler as an immutable sampler to
+// be hashed by its parameters
+func (r *Registry) DescribeSampler(sampler core1_0.Sampler, createInfo core1_0.SamplerCreateInfo) error {
+	return r.describe(sampler, createInfo)
+}
+
+// DescribeRenderPass causes pipelines using renderPass to be hashed by the parts of it that
+// determine render pass compatibility
+func (r *Registry) DescribeRenderPass(renderPass core1_0.RenderPass, createInfo core1_0.RenderPassCreateInfo) error {
+	return r.describe(renderPass, renderPassCompatibility(createInfo))
+}
+
+// Forget drops the description of an object, and must be called when it is destroyed so
+// a new object reusing its handle isn't mistaken for it. Pipelines already created with it
+// are unaffected, but the object is passed to the OnDestroy hooks.
+func (r *Registry) Forget(object any) {
+	handle, isHandle := handleOf(reflect.ValueOf(object))
+	if !isHandle {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.unlock()
+
+	delete(r.descriptions, handle)
+	r.destroyed = append(r.destroyed, object)
+}
+
+// AcquireGraphics returns a pipeline matching createInfo, creating it only if no matching
+// pipeline is alive. Each call must be paired with a call to Release.
+func (r *Registry) AcquireGraphics(name string, createInfo core1_0.GraphicsPipelineCreateInfo) (core1_0.Pipeline, error) {
+	return r.acquire(name, createInfo, func() ([]core1_0.Pipeline, error) {
+		pipelines, _, err := r.deviceDriver.CreateGraphicsPipelines(r.cache, nil, createInfo)
+		return pipelines, err
+	})
+}
+
+// AcquireCompute returns a pipeline matching createInfo, creating it only if no matching
+// pipeline is alive. Each call must be paired with a call to Release.
+func (r *Registry) AcquireCompute(name string, createInfo core1_0.ComputePipelineCreateInfo) (core1_0.Pipeline, error) {
+	return r.acquire(name, createInfo, func() ([]core1_0.Pipeline, error) {
+		pipelines, _, err := r.deviceDriver.CreateComputePipelines(r.cache, nil, createInfo)
+		return pipelines, err
+	})
+}
+
+func (r *Registry) acquire(name string, createInfo any, create func() ([]core1_0.Pipeline, error)) (core1_0.Pipeline, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key, err := r.hash(reflect.TypeOf(createInfo).String(), createInfo)
+	if err != nil {
+		return core1_0.Pipeline{}, err
+	}
+
+	existing, found := r.entries[key]
+	if found {
+		if existing.idle != nil {
+			r.idle.Remove(existing.idle)
+			existing.idle = nil
+		}
+		existing.refs++
+		r.hits++
+
+		return existing.pipeline, nil
+	}
+
+	pipelines, err := create()
+	if err != nil {
+		return core1_0.Pipeline{}, errors.Wrapf(err, "creating pipeline %s", name)
+	}
+	r.misses++
+
+	// Name before storing the entry, so a failure doesn't leave a reference nobody holds
+	err = r.Names.Name(pipelines[0], name)
+	if err != nil {
+		r.deviceDriver.DestroyPipeline(pipelines[0], nil)
+		return core1_0.Pipeline{}, err
+	}
+
+	created := &entry{
+		key:      key,
+		pipeline: pipelines[0],
+		refs:     1,
+	}
+	r.entries[key] = created
+	r.byPipeline[loader.VulkanHandle(created.pipeline.Handle())] = created
+
+	// Pipelines derived from this one are hashed by its description
+	handle, _ := handleOf(reflect.ValueOf(created.pipeline))
+	r.descriptions[handle] = key
+
+	return created.pipeline, nil
+}
+
+// Release drops a reference acquired with AcquireGraphics or AcquireCompute. A pipeline
+// with no references stays alive until more than MaxIdle pipelines are idle, so it must
+// not be in use by the GPU when it is released.
+func (r *Registry) Release(pipeline core1_0.Pipeline) {
+	r.lock.Lock()
+	defer r.unlock()
+
+	released, found := r.byPipeline[loader.VulkanHandle(pipeline.Handle())]
+	if !found || released.refs == 0 {
+		return
+	}
+
+	released.refs--
+	if released.refs > 0 {
+		return
+	}
+
+	released.idle = r.idle.PushBack(released)
+	for r.idle.Len() > r.MaxIdle {
+		r.evict(r.idle.Front().Value.(*entry))
+	}
+}
+
+func (r *Registry) evict(evicted *entry) {
+	if evicted.idle != nil {
+		r.idle.Remove(evicted.idle)
+		evicted.idle = nil
+	}
+
+	delete(r.entries, evicted.key)
+	delete(r.byPipeline, loader.VulkanHandle(evicted.pipeline.Handle()))
+	handle, _ := handleOf(reflect.ValueOf(evicted.pipeline))
+	delete(r.descriptions, handle)
+	r.deviceDriver.DestroyPipeline(evicted.pipeline, nil)
+	r.destroyed = append(r.destroyed, evicted.pipeline)
+}
+
+// Stats returns the number of acquisitions that returned an existing pipeline and the
+// number that created a new one
+func (r *Registry) Stats() (hits, misses int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.hits, r.misses
+}
+
+// Destroy destroys every pipeline in the registry, including ones still referenced
+func (r *Registry) Destroy() {
+	r.lock.Lock()
+	defer r.unlock()
+
+	for _, alive := range r.entries {
+		r.evict(alive)
+	}
+	r.descriptions = make(map[handleKey]Key)
+}
@@ -0,0 +1,48 @@
+package queries
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Occlusion is the result of an occlusion query
+type Occlusion struct {
+	// SamplesPassed is the number of samples that passed the depth and stencil tests. For
+	// binary queries, it is only meaningful as zero or not zero.
+	SamplesPassed uint64
+}
+
+// Visible is whether anything drawn during the query passed the depth and stencil tests
+func (o Occlusion) Visible() bool {
+	return o.SamplesPassed != 0
+}
+
+// OcclusionOptions configures an occlusion query pool
+type OcclusionOptions struct {
+	Options
+	// Precise asks for exact sample counts, which needs the occlusionQueryPrecise
+	// feature. Binary queries, which only say whether any samples passed, can be cheaper.
+	Precise bool
+}
+
+// NewOcclusion creates a pool of occlusion queries. enabledFeatures are the features
+// the device was created with, and may be nil if it was created with none.
+func NewOcclusion(deviceDriver core1_0.DeviceDriver, enabledFeatures *core1_0.PhysicalDeviceFeatures, options OcclusionOptions) (*Pool[Occlusion], error) {
+	if options.Precise && (enabledFeatures == nil || !enabledFeatures.OcclusionQueryPrecise) {
+		return nil, errors.New("precise occlusion queries need the occlusionQueryPrecise feature")
+	}
+
+	pool, err := newPool(deviceDriver, core1_0.QueryPoolCreateInfo{
+		QueryType: core1_0.QueryTypeOcclusion,
+	}, options.Options, 1, func(values []uint64) Occlusion {
+		return Occlusion{SamplesPassed: values[0]}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Precise {
+		pool.control = core1_0.QueryControlPrecise
+	}
+	return pool, nil
+}
@@ -0,0 +1,210 @@
+package queries
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Width is the size of each value a query pool writes when its results are read
+type Width int
+
+const (
+	// Width64 reads results as 64-bit values, which never overflow in practice
+	Width64 Width = iota
+	// Width32 reads results as 32-bit values, which take half the space but wrap around
+	// when a counter passes 2^32
+	Width32
+)
+
+func (w Width) size() int {
+	if w == Width32 {
+		return 4
+	}
+	return 8
+}
+
+func (w Width) flags() core1_0.QueryResultFlags {
+	if w == Width32 {
+		return 0
+	}
+	return core1_0.QueryResult64Bit
+}
+
+// Options are the settings shared by every kind of query pool
+type Options struct {
+	// Count is the number of queries in the pool
+	Count int
+	// Width is the size of the values results are read as
+	Width Width
+	// Availability asks Vulkan to write whether each query has finished alongside its
+	// results, so that Poll can report results query by query instead of all or nothing
+	Availability bool
+}
+
+// Result is the result of one query
+type Result[R any] struct {
+	// Value is only meaningful when Available is true
+	Value R
+	// Available is whether the query had finished when its result was read
+	Available bool
+}
+
+// Pool is a query pool whose results are decoded into R. Use NewOcclusion,
+// NewPipelineStatistics or NewTimestamps to create one.
+type Pool[R any] struct {
+	deviceDriver core1_0.DeviceDriver
+	pool         core1_0.QueryPool
+	options      Options
+	control      core1_0.QueryControlFlags
+	// values is how many values each query writes, not counting availability
+	values int
+	decode func(values []uint64) R
+}
+
+func newPool[R any](deviceDriver core1_0.DeviceDriver, createInfo core1_0.QueryPoolCreateInfo, options Options, values int, decode func(values []uint64) R) (*Pool[R], error) {
+	if options.Count < 1 {
+		return nil, errors.Errorf("a query pool needs at least one query, not %d", options.Count)
+	}
+
+	createInfo.QueryCount = options.Count
+	pool, _, err := deviceDriver.CreateQueryPool(nil, createInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pool[R]{
+		deviceDriver: deviceDriver,
+		pool:         pool,
+		options:      options,
+		values:       values,
+		decode:       decode,
+	}, nil
+}
+
+// QueryPool is the underlying Vulkan query pool
+func (p *Pool[R]) QueryPool() core1_0.QueryPool {
+	return p.pool
+}
+
+// Count is the number of queries in the pool
+func (p *Pool[R]) Count() int {
+	return p.options.Count
+}
+
+// Stride is how many bytes each query's result takes when read or copied, including the
+// availability value if the pool has one
+func (p *Pool[R]) Stride() int {
+	values := p.values
+	if p.options.Availability {
+		values++
+	}
+
+	return values * p.options.Width.size()
+}
+
+// Reset records a reset of count queries starting at first. Queries must be reset before
+// they are used, and again before they are reused.
+func (p *Pool[R]) Reset(commandBuffer core1_0.CommandBuffer, first, count int) {
+	p.deviceDriver.CmdResetQueryPool(commandBuffer, p.pool, first, count)
+}
+
+// ResetAll records a reset of every query in the pool
+func (p *Pool[R]) ResetAll(commandBuffer core1_0.CommandBuffer) {
+	p.Reset(commandBuffer, 0, p.options.Count)
+}
+
+// Begin starts query, which counts everything recorded until End. Timestamp queries are
+// written with Timestamps.Write instead.
+func (p *Pool[R]) Begin(commandBuffer core1_0.CommandBuffer, query int) {
+	p.deviceDriver.CmdBeginQuery(commandBuffer, p.pool, query, p.control)
+}
+
+// End ends query
+func (p *Pool[R]) End(commandBuffer core1_0.CommandBuffer, query int) {
+	p.deviceDriver.CmdEndQuery(commandBuffer, p.pool, query)
+}
+
+func (p *Pool[R]) resultFlags(wait bool) core1_0.QueryResultFlags {
+	flags := p.options.Width.flags()
+	if p.options.Availability {
+		flags |= core1_0.QueryResultWithAvailability
+	}
+	if wait {
+		flags |= core1_0.QueryResultWait
+	}
+	return flags
+}
+
+// Wait blocks until count queries starting at first have finished, and returns their
+// results
+func (p *Pool[R]) Wait(first, count int) ([]Result[R], error) {
+	return p.read(first, count, true)
+}
+
+// Poll returns the results of count queries starting at first without waiting, so it can
+// be called every frame until the queries submitted in an earlier frame are done. Pools
+// with Availability report each query separately; without it, either every result is
+// available or none are.
+func (p *Pool[R]) Poll(first, count int) ([]Result[R], error) {
+	return p.read(first, count, false)
+}
+
+func (p *Pool[R]) read(first, count int, wait bool) ([]Result[R], error) {
+	data := make([]byte, count*p.Stride())
+	res, err := p.deviceDriver.GetQueryPoolResults(p.pool, first, count, data, p.Stride(), p.resultFlags(wait))
+	if err != nil {
+		return nil, err
+	}
+
+	results := p.Decode(data)
+	if res == core1_0.VKNotReady && !p.options.Availability {
+		for index := range results {
+			results[index].Available = false
+		}
+	}
+	return results, nil
+}
+
+// Copy records a copy of count query results starting at first into buffer at offset,
+// laid out Stride bytes apart. With wait, the copy waits for the queries to finish;
+// without it, Decode reports which results were available if the pool has Availability.
+// Read the copied data back with Decode.
+func (p *Pool[R]) Copy(commandBuffer core1_0.CommandBuffer, first, count int, buffer core1_0.Buffer, offset int, wait bool) {
+	p.deviceDriver.CmdCopyQueryPoolResults(commandBuffer, p.pool, first, count, buffer, offset, p.Stride(), p.resultFlags(wait))
+}
+
+// Decode decodes results read or copied from the pool, Stride bytes per query
+func (p *Pool[R]) Decode(data []byte) []Result[R] {
+	stride := p.Stride()
+	size := p.options.Width.size()
+
+	results := make([]Result[R], len(data)/stride)
+	values := make([]uint64, p.values)
+	for index := range results {
+		query := data[index*stride : (index+1)*stride]
+		for valueIndex := range values {
+			values[valueIndex] = readValue(query[valueIndex*size:], size)
+		}
+
+		results[index].Value = p.decode(values)
+		results[index].Available = true
+		if p.options.Availability {
+			results[index].Available = readValue(query[p.values*size:], size) != 0
+		}
+	}
+
+	return results
+}
+
+func readValue(data []byte, size int) uint64 {
+	if size == 4 {
+		return uint64(common.ByteOrder.Uint32(data))
+	}
+	return common.ByteOrder.Uint64(data)
+}
+
+// Destroy destroys the query pool
+func (p *Pool[R]) Destroy() {
+	p.deviceDriver.DestroyQueryPool(p.pool, nil)
+}
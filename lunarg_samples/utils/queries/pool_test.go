@@ -0,0 +1,257 @@
+package queries
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+)
+
+// fakeDriver implements the query pool commands, and panics if anything else is called
+type fakeDriver struct {
+	core1_0.DeviceDriver
+
+	createInfo core1_0.QueryPoolCreateInfo
+	destroyed  bool
+
+	// results is copied into the caller's buffer by GetQueryPoolResults, which returns res
+	results []byte
+	res     common.VkResult
+	flags   core1_0.QueryResultFlags
+}
+
+func (d *fakeDriver) CreateQueryPool(allocationCallbacks *loader.AllocationCallbacks, o core1_0.QueryPoolCreateInfo) (core1_0.QueryPool, common.VkResult, error) {
+	d.createInfo = o
+	return core1_0.InternalQueryPool(0, 1, common.Vulkan1_0), core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) GetQueryPoolResults(queryPool core1_0.QueryPool, firstQuery, queryCount int, results []byte, resultStride int, flags core1_0.QueryResultFlags) (common.VkResult, error) {
+	d.flags = flags
+	copy(results, d.results)
+	return d.res, nil
+}
+
+func (d *fakeDriver) DestroyQueryPool(queryPool core1_0.QueryPool, callbacks *loader.AllocationCallbacks) {
+	d.destroyed = true
+}
+
+// encode writes values as a query pool would, size bytes each
+func encode(size int, values ...uint64) []byte {
+	data := make([]byte, len(values)*size)
+	for index, value := range values {
+		if size == 4 {
+			common.ByteOrder.PutUint32(data[index*size:], uint32(value))
+		} else {
+			common.ByteOrder.PutUint64(data[index*size:], value)
+		}
+	}
+	return data
+}
+
+func TestOcclusionDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		data    []byte
+		stride  int
+		want    []Result[Occlusion]
+	}{
+		{
+			name:    "64-bit",
+			options: Options{Count: 2},
+			data:    encode(8, 5, 0),
+			stride:  8,
+			want:    []Result[Occlusion]{{Occlusion{5}, true}, {Occlusion{0}, true}},
+		},
+		{
+			name:    "32-bit",
+			options: Options{Count: 2, Width: Width32},
+			data:    encode(4, 7, 1),
+			stride:  4,
+			want:    []Result[Occlusion]{{Occlusion{7}, true}, {Occlusion{1}, true}},
+		},
+		{
+			name:    "availability",
+			options: Options{Count: 2, Availability: true},
+			data:    encode(8, 3, 1, 0, 0),
+			stride:  16,
+			want:    []Result[Occlusion]{{Occlusion{3}, true}, {Occlusion{0}, false}},
+		},
+		{
+			name:    "32-bit availability",
+			options: Options{Count: 2, Width: Width32, Availability: true},
+			data:    encode(4, 0, 0, 9, 1),
+			stride:  8,
+			want:    []Result[Occlusion]{{Occlusion{0}, false}, {Occlusion{9}, true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool, err := NewOcclusion(&fakeDriver{}, nil, OcclusionOptions{Options: test.options})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if pool.Stride() != test.stride {
+				t.Errorf("stride is %d, want %d", pool.Stride(), test.stride)
+			}
+
+			results := pool.Decode(test.data)
+			if !slices.Equal(results, test.want) {
+				t.Errorf("decoded %v, want %v", results, test.want)
+			}
+		})
+	}
+}
+
+func TestPollWithoutAvailability(t *testing.T) {
+	driver := &fakeDriver{results: encode(8, 12), res: core1_0.VKNotReady}
+	pool, err := NewOcclusion(driver, nil, OcclusionOptions{Options: Options{Count: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := pool.Poll(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Available {
+		t.Error("result reported available while the pool was not ready")
+	}
+	if driver.flags&core1_0.QueryResultWait != 0 {
+		t.Error("Poll waited for results")
+	}
+
+	driver.res = core1_0.VKSuccess
+	results, err = pool.Wait(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Available || results[0].Value.SamplesPassed != 12 {
+		t.Errorf("Wait returned %v, want 12 samples available", results[0])
+	}
+	if driver.flags != core1_0.QueryResult64Bit|core1_0.QueryResultWait {
+		t.Errorf("Wait read results with flags %s", driver.flags)
+	}
+
+	pool.Destroy()
+	if !driver.destroyed {
+		t.Error("Destroy left the query pool alive")
+	}
+}
+
+func TestPreciseOcclusion(t *testing.T) {
+	options := OcclusionOptions{Options: Options{Count: 1}, Precise: true}
+
+	_, err := NewOcclusion(&fakeDriver{}, &core1_0.PhysicalDeviceFeatures{}, options)
+	if err == nil {
+		t.Error("created a precise pool without occlusionQueryPrecise")
+	}
+
+	pool, err := NewOcclusion(&fakeDriver{}, &core1_0.PhysicalDeviceFeatures{OcclusionQueryPrecise: true}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.control != core1_0.QueryControlPrecise {
+		t.Error("precise pool does not begin queries with QueryControlPrecise")
+	}
+}
+
+func TestEmptyPool(t *testing.T) {
+	_, err := NewOcclusion(&fakeDriver{}, nil, OcclusionOptions{})
+	if err == nil {
+		t.Error("created a pool with no queries")
+	}
+}
+
+func TestPipelineStatisticsOrder(t *testing.T) {
+	features := &core1_0.PhysicalDeviceFeatures{PipelineStatisticsQuery: true}
+	driver := &fakeDriver{}
+	pool, err := NewPipelineStatistics(driver, features, StatisticsOptions{
+		Options: Options{Count: 1},
+		// Selected out of flag order, to check the counters are read in flag order
+		Statistics: Statistics{
+			ComputeShaderInvocations:  true,
+			InputAssemblyVertices:     true,
+			FragmentShaderInvocations: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFlags := core1_0.QueryPipelineStatisticInputAssemblyVertices |
+		core1_0.QueryPipelineStatisticFragmentShaderInvocations |
+		core1_0.QueryPipelineStatisticComputeShaderInvocations
+	if driver.createInfo.PipelineStatistics != wantFlags {
+		t.Errorf("pool collects %s, want %s", driver.createInfo.PipelineStatistics, wantFlags)
+	}
+	if pool.Stride() != 24 {
+		t.Errorf("stride is %d, want three 64-bit counters", pool.Stride())
+	}
+
+	results := pool.Decode(encode(8, 100, 200, 300))
+	want := PipelineStatistics{
+		InputAssemblyVertices:     100,
+		FragmentShaderInvocations: 200,
+		ComputeShaderInvocations:  300,
+	}
+	if results[0].Value != want {
+		t.Errorf("decoded %+v, want %+v", results[0].Value, want)
+	}
+}
+
+func TestPipelineStatisticsRequirements(t *testing.T) {
+	options := StatisticsOptions{
+		Options:    Options{Count: 1},
+		Statistics: Statistics{VertexShaderInvocations: true},
+	}
+
+	_, err := NewPipelineStatistics(&fakeDriver{}, nil, options)
+	if err == nil {
+		t.Error("created a statistics pool without pipelineStatisticsQuery")
+	}
+
+	features := &core1_0.PhysicalDeviceFeatures{PipelineStatisticsQuery: true}
+	_, err = NewPipelineStatistics(&fakeDriver{}, features, StatisticsOptions{Options: Options{Count: 1}})
+	if err == nil {
+		t.Error("created a statistics pool that collects nothing")
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	_, err := NewTimestamps(&fakeDriver{}, 1, 0, Options{Count: 2})
+	if err == nil {
+		t.Error("created a timestamp pool for a family without timestamps")
+	}
+
+	timestamps, err := NewTimestamps(&fakeDriver{}, 2.5, 16, Options{Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bits above validBits are undefined, and are masked off when decoding
+	results := timestamps.Decode(encode(8, 0xabcd0010, 0x12340020))
+	if results[0].Value != 0x10 || results[1].Value != 0x20 {
+		t.Errorf("decoded %#x and %#x, want the low 16 bits", results[0].Value, results[1].Value)
+	}
+
+	if elapsed := timestamps.Elapsed(0x10, 0x20); elapsed != 40*time.Nanosecond {
+		t.Errorf("16 ticks of 2.5ns took %s", elapsed)
+	}
+	if elapsed := timestamps.Elapsed(0xfff0, 0x10); elapsed != 80*time.Nanosecond {
+		t.Errorf("32 ticks across the wraparound took %s", elapsed)
+	}
+
+	full, err := NewTimestamps(&fakeDriver{}, 1, 64, Options{Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := full.Elapsed(^uint64(0), 4); elapsed != 5*time.Nanosecond {
+		t.Errorf("5 ticks across a 64-bit wraparound took %s", elapsed)
+	}
+}
@@ -0,0 +1,142 @@
+package queries
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Statistics selects which counters a pipeline statistics pool collects
+type Statistics struct {
+	InputAssemblyVertices                   bool
+	InputAssemblyPrimitives                 bool
+	VertexShaderInvocations                 bool
+	GeometryShaderInvocations               bool
+	GeometryShaderPrimitives                bool
+	ClippingInvocations                     bool
+	ClippingPrimitives                      bool
+	FragmentShaderInvocations               bool
+	TessellationControlShaderPatches        bool
+	TessellationEvaluationShaderInvocations bool
+	ComputeShaderInvocations                bool
+}
+
+// PipelineStatistics is the result of a pipeline statistics query. Counters that the
+// pool doesn't collect are left at zero.
+type PipelineStatistics struct {
+	InputAssemblyVertices                   uint64
+	InputAssemblyPrimitives                 uint64
+	VertexShaderInvocations                 uint64
+	GeometryShaderInvocations               uint64
+	GeometryShaderPrimitives                uint64
+	ClippingInvocations                     uint64
+	ClippingPrimitives                      uint64
+	FragmentShaderInvocations               uint64
+	TessellationControlShaderPatches        uint64
+	TessellationEvaluationShaderInvocations uint64
+	ComputeShaderInvocations                uint64
+}
+
+type statistic struct {
+	flag     core1_0.QueryPipelineStatisticFlags
+	selected func(s *Statistics) bool
+	value    func(p *PipelineStatistics) *uint64
+}
+
+// statistics is in flag bit order, which is the order Vulkan writes counters in
+var statistics = []statistic{
+	{
+		flag:     core1_0.QueryPipelineStatisticInputAssemblyVertices,
+		selected: func(s *Statistics) bool { return s.InputAssemblyVertices },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.InputAssemblyVertices },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticInputAssemblyPrimitives,
+		selected: func(s *Statistics) bool { return s.InputAssemblyPrimitives },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.InputAssemblyPrimitives },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticVertexShaderInvocations,
+		selected: func(s *Statistics) bool { return s.VertexShaderInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.VertexShaderInvocations },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticGeometryShaderInvocations,
+		selected: func(s *Statistics) bool { return s.GeometryShaderInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.GeometryShaderInvocations },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticGeometryShaderPrimitives,
+		selected: func(s *Statistics) bool { return s.GeometryShaderPrimitives },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.GeometryShaderPrimitives },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticClippingInvocations,
+		selected: func(s *Statistics) bool { return s.ClippingInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.ClippingInvocations },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticClippingPrimitives,
+		selected: func(s *Statistics) bool { return s.ClippingPrimitives },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.ClippingPrimitives },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticFragmentShaderInvocations,
+		selected: func(s *Statistics) bool { return s.FragmentShaderInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.FragmentShaderInvocations },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticTessellationControlShaderPatches,
+		selected: func(s *Statistics) bool { return s.TessellationControlShaderPatches },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.TessellationControlShaderPatches },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticTessellationEvaluationShaderInvocations,
+		selected: func(s *Statistics) bool { return s.TessellationEvaluationShaderInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.TessellationEvaluationShaderInvocations },
+	},
+	{
+		flag:     core1_0.QueryPipelineStatisticComputeShaderInvocations,
+		selected: func(s *Statistics) bool { return s.ComputeShaderInvocations },
+		value:    func(p *PipelineStatistics) *uint64 { return &p.ComputeShaderInvocations },
+	},
+}
+
+// StatisticsOptions configures a pipeline statistics query pool
+type StatisticsOptions struct {
+	Options
+	// Statistics are the counters to collect, at least one of which must be selected
+	Statistics Statistics
+}
+
+// NewPipelineStatistics creates a pool of pipeline statistics queries, which needs the
+// pipelineStatisticsQuery feature. enabledFeatures are the features the device was
+// created with, and may be nil if it was created with none.
+func NewPipelineStatistics(deviceDriver core1_0.DeviceDriver, enabledFeatures *core1_0.PhysicalDeviceFeatures, options StatisticsOptions) (*Pool[PipelineStatistics], error) {
+	if enabledFeatures == nil || !enabledFeatures.PipelineStatisticsQuery {
+		return nil, errors.New("pipeline statistics queries need the pipelineStatisticsQuery feature")
+	}
+
+	var flags core1_0.QueryPipelineStatisticFlags
+	var collected []statistic
+	for _, stat := range statistics {
+		if stat.selected(&options.Statistics) {
+			flags |= stat.flag
+			collected = append(collected, stat)
+		}
+	}
+
+	if len(collected) == 0 {
+		return nil, errors.New("a pipeline statistics pool needs at least one statistic")
+	}
+
+	return newPool(deviceDriver, core1_0.QueryPoolCreateInfo{
+		QueryType:          core1_0.QueryTypePipelineStatistics,
+		PipelineStatistics: flags,
+	}, options.Options, len(collected), func(values []uint64) PipelineStatistics {
+		var result PipelineStatistics
+		for index, stat := range collected {
+			*stat.value(&result) = values[index]
+		}
+		return result
+	})
+}
@@ -0,0 +1,57 @@
+package queries
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// Timestamps is a pool of timestamp queries, whose results are GPU clock ticks
+type Timestamps struct {
+	*Pool[uint64]
+
+	period float64
+	mask   uint64
+}
+
+// NewTimestamps creates a pool of timestamp queries for a queue family. period is the
+// physical device's TimestampPeriod limit, and validBits is the queue family's
+// TimestampValidBits, which is zero if the family doesn't support timestamps.
+func NewTimestamps(deviceDriver core1_0.DeviceDriver, period float32, validBits uint32, options Options) (*Timestamps, error) {
+	if validBits == 0 {
+		return nil, errors.New("the queue family does not support timestamps")
+	}
+
+	mask := ^uint64(0)
+	if validBits < 64 {
+		mask = uint64(1)<<validBits - 1
+	}
+
+	pool, err := newPool(deviceDriver, core1_0.QueryPoolCreateInfo{
+		QueryType: core1_0.QueryTypeTimestamp,
+	}, options, 1, func(values []uint64) uint64 {
+		return values[0] & mask
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Timestamps{
+		Pool:   pool,
+		period: float64(period),
+		mask:   mask,
+	}, nil
+}
+
+// Write records a timestamp into query once every command before it has reached stage
+func (t *Timestamps) Write(commandBuffer core1_0.CommandBuffer, stage core1_0.PipelineStageFlags, query int) {
+	t.deviceDriver.CmdWriteTimestamp(commandBuffer, stage, t.pool, query)
+}
+
+// Elapsed is the time between two timestamps, allowing for the counter wrapping around
+// between them
+func (t *Timestamps) Elapsed(start, end uint64) time.Duration {
+	ticks := (end - start) & t.mask
+	return time.Duration(float64(ticks) * t.period)
+}
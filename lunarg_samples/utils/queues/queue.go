@@ -0,0 +1,397 @@
+package queues
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
+)
+
+// pollInterval is the longest Batch.Wait blocks inside Vulkan before checking whether its
+// context is done
+const pollInterval = 10 * time.Millisecond
+
+// Driver is the part of core1_0.DeviceDriver that a Queue uses
+type Driver interface {
+	QueueSubmit(queue core1_0.Queue, fence *core1_0.Fence, o ...core1_0.SubmitInfo) (common.VkResult, error)
+	QueueWaitIdle(queue core1_0.Queue) (common.VkResult, error)
+
+	CreateFence(allocationCallbacks *loader.AllocationCallbacks, o core1_0.FenceCreateInfo) (core1_0.Fence, common.VkResult, error)
+	GetFenceStatus(fence core1_0.Fence) (common.VkResult, error)
+	WaitForFences(waitForAll bool, timeout time.Duration, fences ...core1_0.Fence) (common.VkResult, error)
+	ResetFences(fences ...core1_0.Fence) (common.VkResult, error)
+	DestroyFence(fence core1_0.Fence, callbacks *loader.AllocationCallbacks)
+}
+
+// Presenter is the part of khr_swapchain.ExtensionDriver that a Queue uses
+type Presenter interface {
+	QueuePresent(queue core1_0.Queue, o khr_swapchain.PresentInfo) (common.VkResult, error)
+}
+
+// Registry hands out one Queue per Vulkan queue, so that queues retrieved more than once,
+// like a graphics queue that is also the present queue, share a lock
+type Registry struct {
+	driver Driver
+
+	lock   sync.Mutex
+	queues map[loader.VkQueue]*Queue
+}
+
+// New creates an empty Registry
+func New(driver Driver) *Registry {
+	return &Registry{
+		driver: driver,
+		queues: make(map[loader.VkQueue]*Queue),
+	}
+}
+
+// Get returns the Queue for queue, creating it the first time queue is seen
+func (r *Registry) Get(queue core1_0.Queue) *Queue {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	existing, found := r.queues[queue.Handle()]
+	if found {
+		return existing
+	}
+
+	wrapped := &Queue{
+		driver: r.driver,
+		queue:  queue,
+	}
+	r.queues[queue.Handle()] = wrapped
+	return wrapped
+}
+
+// Destroy destroys the fences of every Queue in the registry. The GPU must be done with
+// all of their work. It is safe to call on a nil Registry.
+func (r *Registry) Destroy() {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, queue := range r.queues {
+		queue.destroy()
+	}
+}
+
+// Queue serializes everything done with a Vulkan queue, which the specification requires
+// to be externally synchronized. Every method is safe to call from any goroutine.
+//
+// Submissions can also be batched: SubmitInfos enqueued by any number of goroutines are
+// sent in a single vkQueueSubmit by the next Submit or Flush. The Batch that Enqueue
+// returns reports how that submit went and when the GPU finished the work.
+type Queue struct {
+	driver Driver
+	queue  core1_0.Queue
+
+	// lock is held for the whole of every call on the queue
+	lock sync.Mutex
+
+	// pendingLock guards pending and batch separately, so Enqueue doesn't wait on a
+	// submit in progress
+	pendingLock sync.Mutex
+	pending     []core1_0.SubmitInfo
+	batch       *Batch
+
+	// fenceLock guards the batch fences: inFlight, free, and each Batch's fence, complete
+	// and waiters
+	fenceLock sync.Mutex
+	inFlight  []*Batch
+	free      []core1_0.Fence
+}
+
+// Batch is work enqueued with Enqueue. Every Enqueue between two submissions of the
+// queue shares one Batch, which is signaled by a fence the Queue owns.
+type Batch struct {
+	queue     *Queue
+	submitted chan struct{}
+	res       common.VkResult
+	err       error
+
+	fence    core1_0.Fence
+	complete bool
+	waiters  int
+}
+
+// Submitted is closed once the batch has been passed to vkQueueSubmit, whether or not
+// that succeeded
+func (b *Batch) Submitted() <-chan struct{} {
+	return b.submitted
+}
+
+// Result blocks until the batch has been submitted and returns the result of the
+// vkQueueSubmit that carried it
+func (b *Batch) Result() (common.VkResult, error) {
+	<-b.submitted
+	return b.res, b.err
+}
+
+// Done reports whether the GPU has finished the batch, without blocking. It returns the
+// submit's error if the batch failed to submit.
+func (b *Batch) Done() (bool, error) {
+	select {
+	case <-b.submitted:
+	default:
+		return false, nil
+	}
+	if b.err != nil {
+		return false, b.err
+	}
+
+	q := b.queue
+	q.fenceLock.Lock()
+	defer q.fenceLock.Unlock()
+
+	err := q.poll()
+	return b.complete, err
+}
+
+// Wait blocks until the GPU has finished the batch, or ctx is done. It returns the
+// submit's result and error if the batch failed to submit.
+func (b *Batch) Wait(ctx context.Context) (common.VkResult, error) {
+	select {
+	case <-b.submitted:
+	case <-ctx.Done():
+		return core1_0.VKTimeout, errors.Wrap(ctx.Err(), "waiting for a batch to be submitted")
+	}
+	if b.err != nil {
+		return b.res, b.err
+	}
+
+	q := b.queue
+	for {
+		q.fenceLock.Lock()
+		err := q.poll()
+		if err != nil || b.complete {
+			q.fenceLock.Unlock()
+			return core1_0.VKSuccess, err
+		}
+
+		// The fence isn't recycled while anyone is waiting on it
+		b.waiters++
+		fence := b.fence
+		q.fenceLock.Unlock()
+
+		res, err := q.driver.WaitForFences(true, pollInterval, fence)
+
+		q.fenceLock.Lock()
+		b.waiters--
+		if res == core1_0.VKSuccess && err == nil {
+			b.complete = true
+		}
+		recycleErr := q.recycleIfUnused(b)
+		q.fenceLock.Unlock()
+
+		if err != nil {
+			return res, err
+		}
+		if recycleErr != nil {
+			return core1_0.VKSuccess, recycleErr
+		}
+
+		if res == core1_0.VKTimeout {
+			select {
+			case <-ctx.Done():
+				return res, errors.Wrap(ctx.Err(), "waiting for a batch")
+			default:
+			}
+		}
+	}
+}
+
+// Queue is the Vulkan queue. Anything done with it directly should happen inside Do.
+func (q *Queue) Queue() core1_0.Queue {
+	return q.queue
+}
+
+// Do calls f with the queue locked, for queue operations that Queue has no method for
+func (q *Queue) Do(f func(queue core1_0.Queue) error) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return f(q.queue)
+}
+
+// Enqueue adds submitInfos to the next batch. They are submitted, in the order they were
+// enqueued, by the next call to Submit or Flush on any goroutine, and the returned Batch
+// reports when that has happened and when the GPU has finished them.
+func (q *Queue) Enqueue(submitInfos ...core1_0.SubmitInfo) *Batch {
+	q.pendingLock.Lock()
+	defer q.pendingLock.Unlock()
+
+	if q.batch == nil {
+		q.batch = &Batch{
+			queue:     q,
+			submitted: make(chan struct{}),
+		}
+	}
+
+	q.pending = append(q.pending, submitInfos...)
+	return q.batch
+}
+
+// Submit submits every enqueued SubmitInfo followed by submitInfos in a single
+// vkQueueSubmit. fence, which may be nil, is signaled once all of them complete. When
+// there is enqueued work, the submit signals the batch's fence instead, and fence is
+// signaled by an empty submit straight after it.
+func (q *Queue) Submit(fence *core1_0.Fence, submitInfos ...core1_0.SubmitInfo) (common.VkResult, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.pendingLock.Lock()
+	batch := q.batch
+	submitInfos = append(q.pending, submitInfos...)
+	q.pending = nil
+	q.batch = nil
+	q.pendingLock.Unlock()
+
+	if batch == nil {
+		if len(submitInfos) == 0 && fence == nil {
+			return core1_0.VKSuccess, nil
+		}
+
+		return q.driver.QueueSubmit(q.queue, fence, submitInfos...)
+	}
+
+	res, err := q.submitBatch(batch, submitInfos)
+	batch.res, batch.err = res, err
+	close(batch.submitted)
+	if err != nil || fence == nil {
+		return res, err
+	}
+
+	// An empty submit signals its fence once all earlier work on the queue completes
+	return q.driver.QueueSubmit(q.queue, fence)
+}
+
+func (q *Queue) submitBatch(batch *Batch, submitInfos []core1_0.SubmitInfo) (common.VkResult, error) {
+	q.fenceLock.Lock()
+	defer q.fenceLock.Unlock()
+
+	// Reclaim the fences of finished batches before taking one
+	err := q.poll()
+	if err != nil {
+		return core1_0.VKErrorUnknown, err
+	}
+
+	var fence core1_0.Fence
+	if len(q.free) > 0 {
+		fence = q.free[len(q.free)-1]
+		q.free = q.free[:len(q.free)-1]
+	} else {
+		var res common.VkResult
+		fence, res, err = q.driver.CreateFence(nil, core1_0.FenceCreateInfo{})
+		if err != nil {
+			return res, err
+		}
+	}
+
+	res, err := q.driver.QueueSubmit(q.queue, &fence, submitInfos...)
+	if err != nil {
+		q.free = append(q.free, fence)
+		return res, err
+	}
+
+	batch.fence = fence
+	q.inFlight = append(q.inFlight, batch)
+	return res, nil
+}
+
+// poll marks the batches whose fences have signaled as complete and recycles their
+// fences. fenceLock must be held.
+func (q *Queue) poll() error {
+	var stillInFlight []*Batch
+	var firstErr error
+	for _, batch := range q.inFlight {
+		// A waiter may already have seen the fence signal
+		if !batch.complete && firstErr == nil {
+			res, err := q.driver.GetFenceStatus(batch.fence)
+			if err != nil {
+				firstErr = err
+			} else if res == core1_0.VKSuccess {
+				batch.complete = true
+			}
+		}
+
+		if !batch.complete {
+			stillInFlight = append(stillInFlight, batch)
+			continue
+		}
+
+		err := q.recycleIfUnused(batch)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	q.inFlight = stillInFlight
+
+	return firstErr
+}
+
+// recycleIfUnused returns a complete batch's fence to the pool once nothing is waiting
+// on it. fenceLock must be held.
+func (q *Queue) recycleIfUnused(batch *Batch) error {
+	if !batch.complete || batch.waiters > 0 || !batch.fence.Initialized() {
+		return nil
+	}
+
+	fence := batch.fence
+	batch.fence = core1_0.Fence{}
+
+	_, err := q.driver.ResetFences(fence)
+	if err != nil {
+		q.driver.DestroyFence(fence, nil)
+		return err
+	}
+
+	q.free = append(q.free, fence)
+	return nil
+}
+
+// Flush submits every enqueued SubmitInfo, signaling fence, which may be nil, once they
+// complete
+func (q *Queue) Flush(fence *core1_0.Fence) (common.VkResult, error) {
+	return q.Submit(fence)
+}
+
+// Present presents swapchain images with presenter, usually a khr_swapchain.ExtensionDriver
+func (q *Queue) Present(presenter Presenter, presentInfo khr_swapchain.PresentInfo) (common.VkResult, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return presenter.QueuePresent(q.queue, presentInfo)
+}
+
+// WaitIdle waits for everything submitted to the queue to finish. Work that is enqueued
+// but not yet submitted is not waited for.
+func (q *Queue) WaitIdle() (common.VkResult, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.driver.QueueWaitIdle(q.queue)
+}
+
+func (q *Queue) destroy() {
+	q.fenceLock.Lock()
+	defer q.fenceLock.Unlock()
+
+	for _, batch := range q.inFlight {
+		q.driver.DestroyFence(batch.fence, nil)
+		batch.fence = core1_0.Fence{}
+		batch.complete = true
+	}
+	for _, fence := range q.free {
+		q.driver.DestroyFence(fence, nil)
+	}
+	q.inFlight = nil
+	q.free = nil
+}
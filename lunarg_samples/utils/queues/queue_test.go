@@ -0,0 +1,385 @@
+package queues
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/extensions/v3/khr_swapchain"
+)
+
+type fakeSubmit struct {
+	queue loader.VkQueue
+	fence loader.VkFence
+	tags  []int
+}
+
+// fakeDriver records queue operations, and fails the test if two of them overlap on the
+// same queue
+type fakeDriver struct {
+	t *testing.T
+
+	active sync.Map
+
+	lock          sync.Mutex
+	submits       []fakeSubmit
+	signaled      map[loader.VkFence]bool
+	nextFence     loader.VkFence
+	createdFences int
+	destroyed     int
+	submitErr     error
+}
+
+func newFakeDriver(t *testing.T) *fakeDriver {
+	return &fakeDriver{
+		t:        t,
+		signaled: make(map[loader.VkFence]bool),
+	}
+}
+
+func (d *fakeDriver) enter(queue core1_0.Queue) func() {
+	counter, _ := d.active.LoadOrStore(queue.Handle(), new(int32))
+	if atomic.AddInt32(counter.(*int32), 1) > 1 {
+		d.t.Errorf("queue %#x used by two goroutines at once", queue.Handle())
+	}
+
+	// Give overlapping calls a chance to show up
+	time.Sleep(10 * time.Microsecond)
+	return func() {
+		atomic.AddInt32(counter.(*int32), -1)
+	}
+}
+
+func (d *fakeDriver) QueueSubmit(queue core1_0.Queue, fence *core1_0.Fence, o ...core1_0.SubmitInfo) (common.VkResult, error) {
+	defer d.enter(queue)()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.submitErr != nil {
+		return core1_0.VKErrorDeviceLost, d.submitErr
+	}
+
+	submit := fakeSubmit{queue: queue.Handle()}
+	if fence != nil {
+		submit.fence = fence.Handle()
+	}
+	for _, info := range o {
+		submit.tags = append(submit.tags, int(info.WaitDstStageMask[0]))
+	}
+	d.submits = append(d.submits, submit)
+
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) QueueWaitIdle(queue core1_0.Queue) (common.VkResult, error) {
+	defer d.enter(queue)()
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) QueuePresent(queue core1_0.Queue, o khr_swapchain.PresentInfo) (common.VkResult, error) {
+	defer d.enter(queue)()
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) CreateFence(allocationCallbacks *loader.AllocationCallbacks, o core1_0.FenceCreateInfo) (core1_0.Fence, common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.nextFence++
+	d.createdFences++
+	return core1_0.InternalFence(0, d.nextFence, common.Vulkan1_0), core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) GetFenceStatus(fence core1_0.Fence) (common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.signaled[fence.Handle()] {
+		return core1_0.VKSuccess, nil
+	}
+	return core1_0.VKNotReady, nil
+}
+
+func (d *fakeDriver) WaitForFences(waitForAll bool, timeout time.Duration, fences ...core1_0.Fence) (common.VkResult, error) {
+	d.lock.Lock()
+	signaled := d.signaled[fences[0].Handle()]
+	d.lock.Unlock()
+
+	if signaled {
+		return core1_0.VKSuccess, nil
+	}
+
+	time.Sleep(time.Millisecond)
+	return core1_0.VKTimeout, nil
+}
+
+func (d *fakeDriver) ResetFences(fences ...core1_0.Fence) (common.VkResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, fence := range fences {
+		delete(d.signaled, fence.Handle())
+	}
+	return core1_0.VKSuccess, nil
+}
+
+func (d *fakeDriver) DestroyFence(fence core1_0.Fence, callbacks *loader.AllocationCallbacks) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.destroyed++
+}
+
+// signalAll signals every fence that has been submitted, as if the GPU had caught up
+func (d *fakeDriver) signalAll() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, submit := range d.submits {
+		if submit.fence != 0 {
+			d.signaled[submit.fence] = true
+		}
+	}
+}
+
+func (d *fakeDriver) recorded() []fakeSubmit {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return append([]fakeSubmit{}, d.submits...)
+}
+
+func fakeQueue(handle int) core1_0.Queue {
+	return core1_0.InternalQueue(0, loader.VkQueue(handle), common.Vulkan1_0)
+}
+
+func tagged(tag int) core1_0.SubmitInfo {
+	return core1_0.SubmitInfo{WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageFlags(tag)}}
+}
+
+func TestAliasedQueuesShareLock(t *testing.T) {
+	driver := newFakeDriver(t)
+	registry := New(driver)
+
+	graphics := registry.Get(fakeQueue(1))
+	present := registry.Get(fakeQueue(1))
+	if graphics != present {
+		t.Fatal("the same Vulkan queue was wrapped twice")
+	}
+	if registry.Get(fakeQueue(2)) == graphics {
+		t.Fatal("different Vulkan queues share a wrapper")
+	}
+
+	var group sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			for iteration := 0; iteration < 20; iteration++ {
+				switch (worker + iteration) % 4 {
+				case 0:
+					_, err := graphics.Submit(nil, tagged(worker))
+					if err != nil {
+						t.Error(err)
+					}
+				case 1:
+					_, err := present.Present(driver, khr_swapchain.PresentInfo{})
+					if err != nil {
+						t.Error(err)
+					}
+				case 2:
+					_, err := graphics.WaitIdle()
+					if err != nil {
+						t.Error(err)
+					}
+				case 3:
+					present.Enqueue(tagged(worker))
+					_, err := graphics.Flush(nil)
+					if err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}()
+	}
+	group.Wait()
+}
+
+func TestEnqueueBatchesIntoOneSubmit(t *testing.T) {
+	driver := newFakeDriver(t)
+	queue := New(driver).Get(fakeQueue(1))
+
+	const workers = 16
+	batches := make([]*Batch, workers)
+	var group sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			batches[worker] = queue.Enqueue(tagged(worker))
+		}()
+	}
+	group.Wait()
+
+	for _, batch := range batches {
+		if batch != batches[0] {
+			t.Fatal("work enqueued before one submit was split across batches")
+		}
+	}
+
+	select {
+	case <-batches[0].Submitted():
+		t.Fatal("batch reported submitted before Flush")
+	default:
+	}
+
+	_, err := queue.Flush(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submits := driver.recorded()
+	if len(submits) != 1 || len(submits[0].tags) != workers {
+		t.Fatalf("want one submit of %d infos, got %+v", workers, submits)
+	}
+	if submits[0].fence == 0 {
+		t.Fatal("batch was submitted without a fence")
+	}
+
+	res, err := batches[0].Result()
+	if err != nil || res != core1_0.VKSuccess {
+		t.Fatalf("batch result is %s, %v", res, err)
+	}
+
+	done, err := batches[0].Done()
+	if err != nil || done {
+		t.Fatalf("batch is done before its fence signaled: %v, %v", done, err)
+	}
+
+	driver.signalAll()
+	_, err = batches[0].Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The next batch reuses the first batch's fence
+	next := queue.Enqueue(tagged(0))
+	if next == batches[0] {
+		t.Fatal("work enqueued after a submit joined the submitted batch")
+	}
+	_, err = queue.Flush(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if driver.createdFences != 1 {
+		t.Errorf("created %d fences, want the first to be reused", driver.createdFences)
+	}
+}
+
+func TestSubmitSignalsCallerFenceAfterBatch(t *testing.T) {
+	driver := newFakeDriver(t)
+	queue := New(driver).Get(fakeQueue(1))
+
+	queue.Enqueue(tagged(1))
+	callerFence := core1_0.InternalFence(0, 100, common.Vulkan1_0)
+	_, err := queue.Submit(&callerFence, tagged(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	submits := driver.recorded()
+	if len(submits) != 2 {
+		t.Fatalf("want the batch and an empty fence submit, got %+v", submits)
+	}
+	if len(submits[0].tags) != 2 || submits[0].tags[0] != 1 || submits[0].tags[1] != 2 {
+		t.Errorf("enqueued work must be submitted before the caller's, got %v", submits[0].tags)
+	}
+	if submits[1].fence != 100 || len(submits[1].tags) != 0 {
+		t.Errorf("caller's fence was not signaled by an empty submit after the batch: %+v", submits[1])
+	}
+}
+
+func TestBatchReportsSubmitError(t *testing.T) {
+	driver := newFakeDriver(t)
+	driver.submitErr = errors.New("device lost")
+	queue := New(driver).Get(fakeQueue(1))
+
+	batch := queue.Enqueue(tagged(1))
+	_, err := queue.Flush(nil)
+	if err == nil {
+		t.Fatal("Flush hid the submit error")
+	}
+
+	res, err := batch.Result()
+	if err == nil || res != core1_0.VKErrorDeviceLost {
+		t.Errorf("batch result is %s, %v, want the submit's error", res, err)
+	}
+
+	_, err = batch.Wait(context.Background())
+	if err == nil {
+		t.Error("Wait succeeded for a batch that never submitted")
+	}
+}
+
+func TestBatchWaitHonorsContext(t *testing.T) {
+	driver := newFakeDriver(t)
+	queue := New(driver).Get(fakeQueue(1))
+
+	batch := queue.Enqueue(tagged(1))
+	_, err := queue.Flush(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = batch.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait returned %v, want the context's deadline", err)
+	}
+}
+
+func TestConcurrentWaitersAndSubmits(t *testing.T) {
+	driver := newFakeDriver(t)
+	registry := New(driver)
+	queue := registry.Get(fakeQueue(1))
+
+	var group sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+
+			for iteration := 0; iteration < 10; iteration++ {
+				batch := queue.Enqueue(tagged(worker))
+				_, err := queue.Flush(nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				driver.signalAll()
+				_, err = batch.Wait(context.Background())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	group.Wait()
+
+	registry.Destroy()
+	if driver.destroyed != driver.createdFences {
+		t.Errorf("destroyed %d of %d fences", driver.destroyed, driver.createdFences)
+	}
+}
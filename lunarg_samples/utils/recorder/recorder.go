@@ -0,0 +1,257 @@
+package recorder
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+	"golang.org/x/sync/errgroup"
+)
+
+// FenceTimeout is how long BeginFrame waits for a frame's previous submission to finish
+const FenceTimeout = 5 * time.Second
+
+// Draw records one item of a draw list into a secondary command buffer that continues a
+// render pass. It runs on a worker goroutine, alongside other Draws recording into other
+// command buffers.
+type Draw func(commandBuffer core1_0.CommandBuffer) error
+
+// worker is the command pool a single goroutine records into during one frame. Command
+// pools must be externally synchronized, so each worker only ever belongs to one
+// goroutine at a time.
+type worker struct {
+	pool    core1_0.CommandPool
+	buffers []core1_0.CommandBuffer
+	used    int
+}
+
+type frame struct {
+	workers []*worker
+	// fence is signaled when the GPU is done with the frame's command buffers
+	fence    core1_0.Fence
+	inFlight bool
+}
+
+// Recorder records draw lists on several goroutines at once. It owns one command pool per
+// worker goroutine per frame, so no pool is ever shared between goroutines or reset while
+// the GPU may still be executing a command buffer from it.
+//
+// Each frame, call BeginFrame, then Execute (or Record) any number of times, then
+// EndFrame with the fence the frame's submission will signal.
+type Recorder struct {
+	// Names is used to attach debug names to allocated command buffers, and may be nil
+	Names *debugnames.Namer
+	// Name prefixes the debug names
+	Name string
+
+	deviceDriver core1_0.CoreDeviceDriver
+	frames       []*frame
+	current      *frame
+	currentIndex int
+}
+
+// New creates a Recorder with workers goroutines for each of frames frames in flight.
+// The command pools are created for queueFamilyIndex.
+func New(deviceDriver core1_0.CoreDeviceDriver, queueFamilyIndex int, frames int, workers int) (*Recorder, error) {
+	if frames < 1 || workers < 1 {
+		return nil, errors.Errorf("a recorder needs at least one frame and worker, not %d and %d", frames, workers)
+	}
+
+	r := &Recorder{
+		Name:         "Recorder",
+		deviceDriver: deviceDriver,
+	}
+
+	for frameIndex := 0; frameIndex < frames; frameIndex++ {
+		f := &frame{}
+		r.frames = append(r.frames, f)
+
+		for workerIndex := 0; workerIndex < workers; workerIndex++ {
+			pool, _, err := deviceDriver.CreateCommandPool(nil, core1_0.CommandPoolCreateInfo{
+				Flags:            core1_0.CommandPoolCreateTransient,
+				QueueFamilyIndex: queueFamilyIndex,
+			})
+			if err != nil {
+				r.Destroy()
+				return nil, err
+			}
+
+			f.workers = append(f.workers, &worker{pool: pool})
+		}
+	}
+
+	return r, nil
+}
+
+// Workers is the number of goroutines each draw list is split across
+func (r *Recorder) Workers() int {
+	return len(r.frames[0].workers)
+}
+
+// BeginFrame makes frameIndex the current frame. If the frame was submitted before, it
+// waits for that submission's fence and then recycles the frame's pools with
+// ResetCommandPool. Call it before resetting the fence passed to EndFrame, so the
+// recorder never waits on a fence that was reset.
+func (r *Recorder) BeginFrame(frameIndex int) error {
+	if frameIndex < 0 || frameIndex >= len(r.frames) {
+		return errors.Errorf("frame %d is out of range for a recorder with %d frames", frameIndex, len(r.frames))
+	}
+
+	f := r.frames[frameIndex]
+	if f.inFlight {
+		res, err := r.deviceDriver.WaitForFences(true, FenceTimeout, f.fence)
+		if err != nil {
+			return errors.Wrapf(err, "waiting for frame %d", frameIndex)
+		} else if res != core1_0.VKSuccess {
+			return errors.Errorf("waiting for frame %d: %s", frameIndex, res)
+		}
+		f.inFlight = false
+	}
+
+	for _, w := range f.workers {
+		if w.used == 0 {
+			continue
+		}
+
+		_, err := r.deviceDriver.ResetCommandPool(w.pool, 0)
+		if err != nil {
+			return err
+		}
+		w.used = 0
+	}
+
+	r.current = f
+	r.currentIndex = frameIndex
+	return nil
+}
+
+// EndFrame marks the end of the current frame. Its pools are recycled by the next
+// BeginFrame for the same frame index, once fence is signaled.
+func (r *Recorder) EndFrame(fence core1_0.Fence) {
+	if r.current == nil {
+		return
+	}
+
+	r.current.fence = fence
+	r.current.inFlight = true
+	r.current = nil
+}
+
+// Record splits draws into one contiguous run per worker, and records each run into a
+// secondary command buffer on its own goroutine. The secondary command buffers continue
+// the render pass and subpass described by inheritance, and are returned in draw order.
+func (r *Recorder) Record(inheritance core1_0.CommandBufferInheritanceInfo, draws []Draw) ([]core1_0.CommandBuffer, error) {
+	if r.current == nil {
+		return nil, errors.New("Record called outside of BeginFrame and EndFrame")
+	}
+
+	workers := r.current.workers
+	runLength := (len(draws) + len(workers) - 1) / len(workers)
+	if runLength == 0 {
+		return nil, nil
+	}
+
+	var runs [][]Draw
+	for start := 0; start < len(draws); start += runLength {
+		end := min(start+runLength, len(draws))
+		runs = append(runs, draws[start:end])
+	}
+
+	secondaries := make([]core1_0.CommandBuffer, len(runs))
+	var group errgroup.Group
+	for runIndex, run := range runs {
+		w := workers[runIndex]
+		group.Go(func() error {
+			commandBuffer, err := r.record(w, runIndex, inheritance, run)
+			secondaries[runIndex] = commandBuffer
+			return err
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return secondaries, nil
+}
+
+// Execute records draws with Record and executes the secondary command buffers in
+// primary, which must be inside the render pass described by inheritance, begun with
+// core1_0.SubpassContentsSecondaryCommandBuffers
+func (r *Recorder) Execute(primary core1_0.CommandBuffer, inheritance core1_0.CommandBufferInheritanceInfo, draws []Draw) error {
+	secondaries, err := r.Record(inheritance, draws)
+	if err != nil {
+		return err
+	}
+
+	if len(secondaries) > 0 {
+		r.deviceDriver.CmdExecuteCommands(primary, secondaries...)
+	}
+	return nil
+}
+
+func (r *Recorder) record(w *worker, workerIndex int, inheritance core1_0.CommandBufferInheritanceInfo, run []Draw) (core1_0.CommandBuffer, error) {
+	commandBuffer, err := r.nextBuffer(w, workerIndex)
+	if err != nil {
+		return commandBuffer, err
+	}
+
+	_, err = r.deviceDriver.BeginCommandBuffer(commandBuffer, core1_0.CommandBufferBeginInfo{
+		Flags:           core1_0.CommandBufferUsageOneTimeSubmit | core1_0.CommandBufferUsageRenderPassContinue,
+		InheritanceInfo: &inheritance,
+	})
+	if err != nil {
+		return commandBuffer, err
+	}
+
+	for _, draw := range run {
+		err = draw(commandBuffer)
+		if err != nil {
+			return commandBuffer, err
+		}
+	}
+
+	_, err = r.deviceDriver.EndCommandBuffer(commandBuffer)
+	return commandBuffer, err
+}
+
+// nextBuffer returns a secondary command buffer from w's pool that hasn't been used this
+// frame, allocating one if needed
+func (r *Recorder) nextBuffer(w *worker, workerIndex int) (core1_0.CommandBuffer, error) {
+	if w.used < len(w.buffers) {
+		w.used++
+		return w.buffers[w.used-1], nil
+	}
+
+	buffers, _, err := r.deviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
+		CommandPool:        w.pool,
+		Level:              core1_0.CommandBufferLevelSecondary,
+		CommandBufferCount: 1,
+	})
+	if err != nil {
+		return core1_0.CommandBuffer{}, err
+	}
+
+	err = r.Names.Namef(buffers[0], "%s Frame %d Worker %d Buffer %d", r.Name, r.currentIndex, workerIndex, len(w.buffers))
+	if err != nil {
+		r.deviceDriver.FreeCommandBuffers(buffers[0])
+		return core1_0.CommandBuffer{}, err
+	}
+
+	w.buffers = append(w.buffers, buffers[0])
+	w.used++
+	return buffers[0], nil
+}
+
+// Destroy destroys every command pool, which frees their command buffers. The GPU must be
+// done with every frame.
+func (r *Recorder) Destroy() {
+	for _, f := range r.frames {
+		for _, w := range f.workers {
+			r.deviceDriver.DestroyCommandPool(w.pool, nil)
+		}
+	}
+	r.frames = nil
+}
@@ -1,11 +1,11 @@
 package utils
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"log"
 	"math"
+	"reflect"
 	"unsafe"
 
 	"github.com/loov/hrtime"
@@ -13,7 +13,21 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/core1_1"
+	"github.com/vkngwrapper/core/v3/core1_2"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/breadcrumbs"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/descriptors"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/framesync"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queues"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/upload"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/validation"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/vertexinput"
+	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_get_physical_device_properties2"
+	"github.com/vkngwrapper/extensions/v3/khr_maintenance1"
 	"github.com/vkngwrapper/extensions/v3/khr_portability_enumeration"
 	"github.com/vkngwrapper/extensions/v3/khr_portability_subset"
 	"github.com/vkngwrapper/extensions/v3/khr_surface"
@@ -43,6 +57,51 @@ type SampleInfo struct {
 	Prepared         bool
 	UseStagingBuffer bool
 	SaveImages       bool
+	Validation       validation.Settings
+	// Printf collects shader debugPrintfEXT output when Validation.DebugPrintf is set and
+	// the debug messenger was created from DebugMessengerOptions
+	Printf validation.PrintfCapture
+	// DebugNames is only set when ext_debug_utils is in InstanceExtensionNames, but it is
+	// safe to use either way
+	DebugNames *debugnames.Namer
+	// Breadcrumbs is created in InitDevice. BeginRenderPass, EndRenderPass, Draw and
+	// DrawIndexed leave breadcrumbs, submissions made with ExecuteQueueCmdBuf are tracked
+	// automatically, and a report is written if the device is lost.
+	Breadcrumbs *breadcrumbs.Tracker
+	// Frames is created in InitDevice, and numbers submissions made with its Submit. It
+	// uses a timeline semaphore when the device supports them, and fences otherwise.
+	Frames *framesync.Counter
+	// onDestroy are the hooks added with OnDestroy
+	onDestroy []func(object any)
+
+	// DesiredAPIVersion and MinimumAPIVersion are set before InitInstance to choose the
+	// Vulkan version. The newest version up to DesiredAPIVersion that both the loader and
+	// the device support is used, and InitInstance and InitEnumerateDevice fail if that
+	// would be older than MinimumAPIVersion. Both default to Vulkan 1.0.
+	DesiredAPIVersion common.APIVersion
+	MinimumAPIVersion common.APIVersion
+	// InstanceAPIVersion is the version InitInstance negotiated with the loader, and
+	// DeviceAPIVersion the version InitEnumerateDevice negotiated with Gpus[0]
+	InstanceAPIVersion common.APIVersion
+	DeviceAPIVersion   common.APIVersion
+	// InstanceDriver11 and InstanceDriver12 are InstanceDriver when the instance was
+	// created for Vulkan 1.1 or 1.2, and nil otherwise. DeviceDriver11 and DeviceDriver12
+	// are set the same way by InitDevice. Samples can check them to use a newer core
+	// feature, and fall back to its extension when they are nil.
+	InstanceDriver11 core1_1.CoreInstanceDriver
+	InstanceDriver12 core1_2.CoreInstanceDriver
+	DeviceDriver11   core1_1.CoreDeviceDriver
+	DeviceDriver12   core1_2.CoreDeviceDriver
+
+	// DeviceRequirements is set before InitEnumerateDevice to choose the device features
+	// and extensions to enable. InitEnumerateDevice skips physical devices that lack the
+	// required ones.
+	DeviceRequirements DeviceRequirements
+	// EnabledFeatures are the core features InitDevice enabled, and
+	// EnabledExtendedFeatures the feature structures it chained onto the device create
+	// info, which ExtendedFeaturesEnabled looks up
+	EnabledFeatures         core1_0.PhysicalDeviceFeatures
+	EnabledExtendedFeatures []any
 
 	InstanceLayerNames          []string
 	InstanceExtensionNames      []string
@@ -59,6 +118,16 @@ type SampleInfo struct {
 	GpuProps                  *core1_0.PhysicalDeviceProperties
 	QueueProps                []*core1_0.QueueFamilyProperties
 	MemoryProperties          *core1_0.PhysicalDeviceMemoryProperties
+	// DeviceNext is chained onto the DeviceCreateInfo used by InitDevice, for extension
+	// feature structures
+	DeviceNext common.Options
+
+	// SyncGraphicsQueue and SyncPresentQueue serialize use of GraphicsQueue and
+	// PresentQueue across goroutines, and are the same Queue when the two alias. Queues
+	// is the registry they come from.
+	Queues            *queues.Registry
+	SyncGraphicsQueue *queues.Queue
+	SyncPresentQueue  *queues.Queue
 
 	Framebuffer   []core1_0.Framebuffer
 	Width, Height int
@@ -97,8 +166,20 @@ type SampleInfo struct {
 		BufferInfo core1_0.DescriptorBufferInfo
 	}
 
+	// IndexBuffer is set by InitIndexBuffer, for samples that draw with CmdDrawIndexed.
+	// IndexType is the narrowest type that can address the vertex buffer.
+	IndexBuffer struct {
+		Buf   core1_0.Buffer
+		Mem   core1_0.DeviceMemory
+		Count int
+		Type  core1_0.IndexType
+	}
+
 	VertexBinding    core1_0.VertexInputBindingDescription
 	VertexAttributes []core1_0.VertexInputAttributeDescription
+	// VertexLayout is the layout VertexBinding and VertexAttributes were derived from,
+	// when they were set by InitVertexLayout
+	VertexLayout *vertexinput.Layout
 
 	Projection vkngmath.Mat4x4[float32]
 	View       vkngmath.Mat4x4[float32]
@@ -113,9 +194,19 @@ type SampleInfo struct {
 	Pipeline       core1_0.Pipeline
 
 	ShaderStages []core1_0.PipelineShaderStageCreateInfo
-
-	DescPool core1_0.DescriptorPool
-	DescSet  []core1_0.DescriptorSet
+	// VertexShaderCode is the SPIR-V passed to the last InitShaders, which InitPipeline
+	// checks VertexLayout against
+	VertexShaderCode []uint32
+
+	// Descriptors is created by InitDescriptorPool, and InitDescriptorSet allocates from
+	// it. Samples that manage their own pool use DescPool instead.
+	Descriptors *descriptors.Allocator
+	DescWriter  *descriptors.Writer
+	// SetLayout describes DescLayout[0] when it was created by
+	// InitDescriptorAndPipelineLayouts. Its bindings are named "uniforms" and "texture".
+	SetLayout *descriptors.Layout
+	DescPool  core1_0.DescriptorPool
+	DescSet   []core1_0.DescriptorSet
 
 	//PFN_vkCreateDebugReportCallbackEXT dbgCreateDebugReportCallback;
 	//PFN_vkDestroyDebugReportCallbackEXT dbgDestroyDebugReportCallback;
@@ -153,9 +244,9 @@ func (i *SampleInfo) InitWindow() error {
 }
 
 func (i *SampleInfo) InitGlobalLayerProperties() error {
-	layers, _, err := i.GlobalDriver.AvailableLayers()
-	if err != nil {
-		return err
+	layers, res, err := i.GlobalDriver.AvailableLayers()
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateInstanceLayerProperties", "", res, err)
 	}
 	for _, properties := range layers {
 		props := &LayerProperties{
@@ -172,9 +263,9 @@ func (i *SampleInfo) InitGlobalLayerProperties() error {
 }
 
 func (i *SampleInfo) InitGlobalExtensionProperties(layerProps *LayerProperties) error {
-	instanceExtensions, _, err := i.GlobalDriver.AvailableExtensionsForLayer(layerProps.Properties.LayerName)
-	if err != nil {
-		return err
+	instanceExtensions, res, err := i.GlobalDriver.AvailableExtensionsForLayer(layerProps.Properties.LayerName)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateInstanceExtensionProperties", layerProps.Properties.LayerName, res, err)
 	}
 
 	for _, props := range instanceExtensions {
@@ -187,9 +278,9 @@ func (i *SampleInfo) InitGlobalExtensionProperties(layerProps *LayerProperties)
 func (i *SampleInfo) InitInstanceExtensionNames() error {
 	i.InstanceExtensionNames = i.Window.VulkanGetInstanceExtensions()
 
-	instanceExtensions, _, err := i.GlobalDriver.AvailableExtensions()
-	if err != nil {
-		return err
+	instanceExtensions, res, err := i.GlobalDriver.AvailableExtensions()
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateInstanceExtensionProperties", "", res, err)
 	}
 
 	_, ok := instanceExtensions[khr_portability_enumeration.ExtensionName]
@@ -203,13 +294,42 @@ func (i *SampleInfo) InitInstanceExtensionNames() error {
 	return nil
 }
 
+// DebugMessengerOptions returns the samples' debug messenger options, which send warnings
+// and errors to callback. With debug printf enabled, the messenger also receives info
+// messages; shader output goes to Printf, which logs it, and everything else to callback.
+func (i *SampleInfo) DebugMessengerOptions(callback ext_debug_utils.CallbackFunction) ext_debug_utils.DebugUtilsMessengerCreateInfo {
+	options := ext_debug_utils.DebugUtilsMessengerCreateInfo{
+		MessageSeverity: ext_debug_utils.SeverityWarning | ext_debug_utils.SeverityError,
+		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
+		UserCallback:    callback,
+	}
+
+	if i.Validation.DebugPrintf {
+		i.Printf.Forward = callback
+		if i.Printf.Logger == nil {
+			i.Printf.Logger = log.Default()
+		}
+
+		// Shader printf output is delivered at info severity
+		options.MessageSeverity |= ext_debug_utils.SeverityInfo
+		options.UserCallback = i.Printf.Callback
+	}
+
+	return options
+}
+
 func (i *SampleInfo) InitInstance(appShortName string, next common.Options) error {
 	var err error
 	var flags core1_0.InstanceCreateFlags
 
-	instanceExtensions, _, err := i.GlobalDriver.AvailableExtensions()
+	i.InstanceAPIVersion, err = negotiateVersion(i.DesiredAPIVersion, i.MinimumAPIVersion, i.GlobalDriver.Loader().Version())
 	if err != nil {
-		return err
+		return errors.Wrap(err, "negotiating the instance version")
+	}
+
+	instanceExtensions, res, err := i.GlobalDriver.AvailableExtensions()
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateInstanceExtensionProperties", "", res, err)
 	}
 
 	_, ok := instanceExtensions[khr_portability_enumeration.ExtensionName]
@@ -218,12 +338,33 @@ func (i *SampleInfo) InitInstance(appShortName string, next common.Options) erro
 		flags = khr_portability_enumeration.InstanceCreateEnumeratePortability
 	}
 
-	i.InstanceDriver, _, err = i.GlobalDriver.CreateInstance(nil, core1_0.InstanceCreateInfo{
+	if i.Validation.Enabled() {
+		var validationExtension string
+		validationExtension, next, err = i.Validation.InstanceOptions(i.GlobalDriver, next)
+		if err != nil {
+			return err
+		}
+		i.InstanceExtensionNames = append(i.InstanceExtensionNames, validationExtension)
+
+		hasLayer := false
+		for _, layerName := range i.InstanceLayerNames {
+			if layerName == validation.LayerName {
+				hasLayer = true
+				break
+			}
+		}
+
+		if !hasLayer {
+			i.InstanceLayerNames = append(i.InstanceLayerNames, validation.LayerName)
+		}
+	}
+
+	i.InstanceDriver, res, err = i.GlobalDriver.CreateInstance(nil, core1_0.InstanceCreateInfo{
 		ApplicationName:       appShortName,
 		ApplicationVersion:    common.CreateVersion(0, 0, 1),
 		EngineName:            appShortName,
 		EngineVersion:         common.CreateVersion(0, 0, 1),
-		APIVersion:            common.Vulkan1_0,
+		APIVersion:            i.InstanceAPIVersion,
 		EnabledExtensionNames: i.InstanceExtensionNames,
 		EnabledLayerNames:     i.InstanceLayerNames,
 		Flags:                 flags,
@@ -231,8 +372,13 @@ func (i *SampleInfo) InitInstance(appShortName string, next common.Options) erro
 			Next: next,
 		},
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateInstance", appShortName, res, err)
+	}
 
-	return err
+	i.InstanceDriver11, _ = i.InstanceDriver.(core1_1.CoreInstanceDriver)
+	i.InstanceDriver12, _ = i.InstanceDriver.(core1_2.CoreInstanceDriver)
+	return nil
 }
 
 func (i *SampleInfo) InitDeviceExtensionNames() error {
@@ -242,10 +388,24 @@ func (i *SampleInfo) InitDeviceExtensionNames() error {
 }
 
 func (i *SampleInfo) InitEnumerateDevice() error {
+	var res common.VkResult
 	var err error
-	i.Gpus, _, err = i.InstanceDriver.EnumeratePhysicalDevices()
-	if err != nil {
+	i.Gpus, res, err = i.InstanceDriver.EnumeratePhysicalDevices()
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumeratePhysicalDevices", "", res, err)
+	}
+
+	i.DeviceAPIVersion, err = chooseDevice(i.Gpus, i.InstanceAPIVersion, i.MinimumAPIVersion, func(gpu core1_0.PhysicalDevice, version common.APIVersion) error {
+		extensions, res, err := i.InstanceDriver.EnumerateDeviceExtensionProperties(gpu)
+		if res != core1_0.VKSuccess || err != nil {
+			return vulkanError("vkEnumerateDeviceExtensionProperties", "", res, err)
+		}
+
+		_, err = i.resolveRequirements(gpu, version, extensions)
 		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "choosing a physical device")
 	}
 
 	i.QueueProps = i.InstanceDriver.GetPhysicalDeviceQueueFamilyProperties(i.Gpus[0])
@@ -254,7 +414,7 @@ func (i *SampleInfo) InitEnumerateDevice() error {
 	i.MemoryProperties = i.InstanceDriver.GetPhysicalDeviceMemoryProperties(i.Gpus[0])
 	i.GpuProps, err = i.InstanceDriver.GetPhysicalDeviceProperties(i.Gpus[0])
 	if err != nil {
-		return err
+		return vulkanError("vkGetPhysicalDeviceProperties", "", core1_0.VKSuccess, err)
 	}
 
 	for _, layerProps := range i.InstanceLayerProperties {
@@ -268,9 +428,9 @@ func (i *SampleInfo) InitEnumerateDevice() error {
 }
 
 func (i *SampleInfo) InitDeviceExtensionProperties(layerProps *LayerProperties) error {
-	deviceExtensions, _, err := i.InstanceDriver.EnumerateDeviceExtensionPropertiesForLayer(i.Gpus[0], layerProps.Properties.LayerName)
-	if err != nil {
-		return err
+	deviceExtensions, res, err := i.InstanceDriver.EnumerateDeviceExtensionPropertiesForLayer(i.Gpus[0], layerProps.Properties.LayerName)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateDeviceExtensionProperties", layerProps.Properties.LayerName, res, err)
 	}
 
 	for _, deviceExtension := range deviceExtensions {
@@ -287,15 +447,15 @@ func (i *SampleInfo) InitSwapchainExtension() error {
 	var err error
 	i.Surface, err = vkng_sdl2.CreateSurface(i.InstanceDriver.Instance(), i.SurfaceDriver, i.Window)
 	if err != nil {
-		return err
+		return vulkanError("vkCreateSurface", "Surface", core1_0.VKSuccess, err)
 	}
 
 	// Iterate over each queue to learn whether it supports presenting:
 	var presentSupport []bool
 	for queueIndex := range i.QueueProps {
-		support, _, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceSupport(i.Surface, i.Gpus[0], queueIndex)
-		if err != nil {
-			return err
+		support, res, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceSupport(i.Surface, i.Gpus[0], queueIndex)
+		if res != core1_0.VKSuccess || err != nil {
+			return vulkanError("vkGetPhysicalDeviceSurfaceSupportKHR", "", res, err)
 		}
 		presentSupport = append(presentSupport, support)
 	}
@@ -336,9 +496,9 @@ func (i *SampleInfo) InitSwapchainExtension() error {
 	}
 
 	// Get the list of VkFormats that are supported:
-	formats, _, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceFormats(i.Surface, i.Gpus[0])
-	if err != nil {
-		return err
+	formats, res, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceFormats(i.Surface, i.Gpus[0])
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkGetPhysicalDeviceSurfaceFormatsKHR", "", res, err)
 	}
 
 	// If the device supports our preferred surface format, use it.
@@ -358,9 +518,9 @@ func (i *SampleInfo) InitSwapchainExtension() error {
 func (i *SampleInfo) InitDevice() error {
 	var err error
 
-	extensions, _, err := i.InstanceDriver.EnumerateDeviceExtensionProperties(i.Gpus[0])
-	if err != nil {
-		return err
+	extensions, res, err := i.InstanceDriver.EnumerateDeviceExtensionProperties(i.Gpus[0])
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkEnumerateDeviceExtensionProperties", "", res, err)
 	}
 
 	_, ok := extensions[khr_portability_subset.ExtensionName]
@@ -368,7 +528,23 @@ func (i *SampleInfo) InitDevice() error {
 		i.DeviceExtensionNames = append(i.DeviceExtensionNames, khr_portability_subset.ExtensionName)
 	}
 
-	i.DeviceDriver, _, err = i.InstanceDriver.CreateDevice(i.Gpus[0], nil, core1_0.DeviceCreateInfo{
+	markerExtension := breadcrumbs.DeviceExtension(extensions)
+	if markerExtension != "" {
+		i.DeviceExtensionNames = append(i.DeviceExtensionNames, markerExtension)
+	}
+
+	requirements, err := i.resolveRequirements(i.Gpus[0], i.DeviceAPIVersion, extensions)
+	if err != nil {
+		return err
+	}
+	i.applyRequirements(requirements)
+
+	frameSetup := framesync.Configure(i.Gpus[0], extensions)
+	if frameSetup.Extension != "" {
+		i.DeviceExtensionNames = append(i.DeviceExtensionNames, frameSetup.Extension)
+	}
+
+	i.DeviceDriver, res, err = i.InstanceDriver.CreateDevice(i.Gpus[0], nil, core1_0.DeviceCreateInfo{
 		QueueCreateInfos: []core1_0.DeviceQueueCreateInfo{
 			{
 				QueueFamilyIndex: i.GraphicsQueueFamilyIndex,
@@ -376,64 +552,95 @@ func (i *SampleInfo) InitDevice() error {
 			},
 		},
 		EnabledExtensionNames: i.DeviceExtensionNames,
+		EnabledFeatures:       &i.EnabledFeatures,
+		NextOptions:           common.NextOptions{Next: frameSetup.Features(requirements.next)},
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateDevice", "", res, err)
+	}
+
+	i.DeviceDriver11, _ = i.DeviceDriver.(core1_1.CoreDeviceDriver)
+	i.DeviceDriver12, _ = i.DeviceDriver.(core1_2.CoreDeviceDriver)
+
+	i.Frames, err = framesync.New(i.DeviceDriver, frameSetup)
+	if err != nil {
+		return err
+	}
 
-	return err
+	i.DebugNames = debugnames.New(i.InstanceDriver, i.DeviceDriver)
+
+	i.Breadcrumbs = breadcrumbs.New(0)
+	return i.Breadcrumbs.AttachDevice(i.DeviceDriver, i.MemoryProperties, i.DeviceExtensionNames)
 }
 
 func (i *SampleInfo) InitCommandPool() error {
+	var res common.VkResult
 	var err error
-	i.CmdPool, _, err = i.DeviceDriver.CreateCommandPool(nil, core1_0.CommandPoolCreateInfo{
+	i.CmdPool, res, err = i.DeviceDriver.CreateCommandPool(nil, core1_0.CommandPoolCreateInfo{
 		QueueFamilyIndex: i.GraphicsQueueFamilyIndex,
 		Flags:            core1_0.CommandPoolCreateResetBuffer,
 	})
-	return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateCommandPool", "Sample Command Pool", res, err)
+	}
+
+	return i.DebugNames.Name(i.CmdPool, "Sample Command Pool")
 }
 
 func (i *SampleInfo) InitCommandBuffer() error {
-	buffers, _, err := i.DeviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
+	buffers, res, err := i.DeviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
 		CommandPool:        i.CmdPool,
 		Level:              core1_0.CommandBufferLevelPrimary,
 		CommandBufferCount: 1,
 	})
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateCommandBuffers", "Sample Command Buffer", res, err)
 	}
 	i.Cmd = buffers[0]
 
-	return nil
+	return i.DebugNames.Name(i.Cmd, "Sample Command Buffer")
 }
 
 func (i *SampleInfo) ExecuteBeginCommandBuffer() error {
-	_, err := i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
+	res, err := i.DeviceDriver.BeginCommandBuffer(i.Cmd, core1_0.CommandBufferBeginInfo{})
+	i.Breadcrumbs.Reset(i.Cmd)
 
-	return err
+	return vulkanError("vkBeginCommandBuffer", "Sample Command Buffer", res, err)
 }
 
 func (i *SampleInfo) ExecuteEndCommandBuffer() error {
 	t := hrtime.Now()
-	_, err := i.DeviceDriver.EndCommandBuffer(i.Cmd)
+	res, err := i.DeviceDriver.EndCommandBuffer(i.Cmd)
 	n := hrtime.Now()
 	fmt.Println(n - t)
-	return err
+	return vulkanError("vkEndCommandBuffer", "Sample Command Buffer", res, err)
 }
 
 func (i *SampleInfo) InitDeviceQueue() error {
+	i.Queues = queues.New(i.DeviceDriver)
 	i.GraphicsQueue = i.DeviceDriver.GetQueue(i.GraphicsQueueFamilyIndex, 0)
+	i.SyncGraphicsQueue = i.Queues.Get(i.GraphicsQueue)
 
 	if i.PresentQueueFamilyIndex == i.GraphicsQueueFamilyIndex {
 		i.PresentQueue = i.GraphicsQueue
-		return nil
+		i.SyncPresentQueue = i.SyncGraphicsQueue
+		return i.DebugNames.Name(i.GraphicsQueue, "Graphics/Present Queue")
+	}
+
+	err := i.DebugNames.Name(i.GraphicsQueue, "Graphics Queue")
+	if err != nil {
+		return err
 	}
 
 	i.PresentQueue = i.DeviceDriver.GetQueue(i.PresentQueueFamilyIndex, 0)
-	return nil
+	i.SyncPresentQueue = i.Queues.Get(i.PresentQueue)
+	return i.DebugNames.Name(i.PresentQueue, "Present Queue")
 }
 
 func (i *SampleInfo) InitSwapchain(usage core1_0.ImageUsageFlags) error {
-	surfaceCaps, _, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceCapabilities(i.Surface, i.Gpus[0])
-	if err != nil {
-		return err
+	surfaceCaps, res, err := i.SurfaceDriver.GetPhysicalDeviceSurfaceCapabilities(i.Surface, i.Gpus[0])
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkGetPhysicalDeviceSurfaceCapabilitiesKHR", "Surface", res, err)
 	}
 
 	var swapchainExtent core1_0.Extent2D
@@ -520,19 +727,29 @@ func (i *SampleInfo) InitSwapchain(usage core1_0.ImageUsageFlags) error {
 		}
 	}
 
-	i.Swapchain, _, err = i.SwapchainExtension.CreateSwapchain(nil, swapchainOptions)
-	if err != nil {
-		return err
+	i.Swapchain, res, err = i.SwapchainExtension.CreateSwapchain(nil, swapchainOptions)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateSwapchainKHR", "Swapchain", res, err)
 	}
 
-	images, _, err := i.SwapchainExtension.GetSwapchainImages(i.Swapchain)
+	err = i.DebugNames.Name(i.Swapchain, "Swapchain")
 	if err != nil {
 		return err
 	}
+
+	images, res, err := i.SwapchainExtension.GetSwapchainImages(i.Swapchain)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkGetSwapchainImagesKHR", "Swapchain", res, err)
+	}
 	i.SwapchainImageCount = len(images)
 
-	for _, image := range images {
-		view, _, err := i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
+	for imageIndex, image := range images {
+		err = i.DebugNames.Namef(image, "Swapchain Image %d", imageIndex)
+		if err != nil {
+			return err
+		}
+
+		view, res, err := i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
 			Image:    image,
 			ViewType: core1_0.ImageViewType2D,
 			Format:   i.Format,
@@ -550,6 +767,11 @@ func (i *SampleInfo) InitSwapchain(usage core1_0.ImageUsageFlags) error {
 				LayerCount:     1,
 			},
 		})
+		if res != core1_0.VKSuccess || err != nil {
+			return vulkanError("vkCreateImageView", fmt.Sprintf("Swapchain Image View %d", imageIndex), res, err)
+		}
+
+		err = i.DebugNames.Namef(view, "Swapchain Image View %d", imageIndex)
 		if err != nil {
 			return err
 		}
@@ -592,11 +814,17 @@ func (i *SampleInfo) InitDepthBuffer() error {
 	} else if (props.OptimalTilingFeatures & core1_0.FormatFeatureDepthStencilAttachment) != 0 {
 		imageOptions.Tiling = core1_0.ImageTilingOptimal
 	} else {
-		return errors.Errorf("depth format %s unsupported", depthFormat)
+		return errors.Wrapf(ErrFormatUnsupported, "depth format %s", depthFormat)
 	}
 
+	var res common.VkResult
 	var err error
-	i.Depth.Image, _, err = i.DeviceDriver.CreateImage(nil, imageOptions)
+	i.Depth.Image, res, err = i.DeviceDriver.CreateImage(nil, imageOptions)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateImage", "Depth Image", res, err)
+	}
+
+	err = i.DebugNames.Name(i.Depth.Image, "Depth Image")
 	if err != nil {
 		return err
 	}
@@ -607,20 +835,25 @@ func (i *SampleInfo) InitDepthBuffer() error {
 		return err
 	}
 
-	i.Depth.Mem, _, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	i.Depth.Mem, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  imageMemoryReqs.Size,
 		MemoryTypeIndex: typeIndex,
 	})
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", "Depth Image Memory", res, err)
 	}
 
-	_, err = i.DeviceDriver.BindImageMemory(i.Depth.Image, i.Depth.Mem, 0)
+	err = i.DebugNames.Name(i.Depth.Mem, "Depth Image Memory")
 	if err != nil {
 		return err
 	}
 
-	i.Depth.View, _, err = i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
+	res, err = i.DeviceDriver.BindImageMemory(i.Depth.Image, i.Depth.Mem, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBindImageMemory", "Depth Image", res, err)
+	}
+
+	i.Depth.View, res, err = i.DeviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
 		Image:  i.Depth.Image,
 		Format: depthFormat,
 		Components: core1_0.ComponentMapping{
@@ -638,7 +871,11 @@ func (i *SampleInfo) InitDepthBuffer() error {
 		},
 		ViewType: core1_0.ImageViewType2D,
 	})
-	return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateImageView", "Depth Image View", res, err)
+	}
+
+	return i.DebugNames.Name(i.Depth.View, "Depth Image View")
 }
 
 func (i *SampleInfo) MemoryTypeFromProperties(memoryType uint32, flags core1_0.MemoryPropertyFlags) (int, error) {
@@ -672,12 +909,23 @@ func (i *SampleInfo) InitUniformBuffer() error {
 	i.MVP.SetApplyTransform(&i.Model, &i.View)
 	i.MVP.ApplyTransform(&i.Projection)
 
-	var err error
-	i.UniformData.Buf, _, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+	// The shaders declare the matrix in a std140 uniform block
+	mvpLayout, err := blocklayout.TypeOf(blocklayout.Std140, &i.MVP)
+	if err != nil {
+		return err
+	}
+
+	var res common.VkResult
+	i.UniformData.Buf, res, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
 		Usage:       core1_0.BufferUsageUniformBuffer,
-		Size:        int(unsafe.Sizeof(i.MVP)),
+		Size:        mvpLayout.Size,
 		SharingMode: core1_0.SharingModeExclusive,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateBuffer", "Uniform Buffer", res, err)
+	}
+
+	err = i.DebugNames.Name(i.UniformData.Buf, "Uniform Buffer")
 	if err != nil {
 		return err
 	}
@@ -688,43 +936,40 @@ func (i *SampleInfo) InitUniformBuffer() error {
 		return err
 	}
 
-	i.UniformData.Mem, _, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	i.UniformData.Mem, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryTypeIndex,
 	})
-	if err != nil {
-		return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", "Uniform Buffer Memory", res, err)
 	}
 
-	memPtr, _, err := i.DeviceDriver.MapMemory(i.UniformData.Mem, 0, memReqs.Size, 0)
+	err = i.DebugNames.Name(i.UniformData.Mem, "Uniform Buffer Memory")
 	if err != nil {
 		return err
 	}
 
-	dataBuffer := unsafe.Slice((*byte)(memPtr), memReqs.Size)
-
-	buf := &bytes.Buffer{}
-	err = binary.Write(buf, common.ByteOrder, i.MVP)
-	if err != nil {
-		i.DeviceDriver.UnmapMemory(i.UniformData.Mem)
-		return err
+	memPtr, res, err := i.DeviceDriver.MapMemory(i.UniformData.Mem, 0, memReqs.Size, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkMapMemory", "Uniform Buffer Memory", res, err)
 	}
 
-	copy(dataBuffer, buf.Bytes())
+	dataBuffer := unsafe.Slice((*byte)(memPtr), memReqs.Size)
+	err = mvpLayout.EncodeInto(dataBuffer, &i.MVP)
 
 	i.DeviceDriver.UnmapMemory(i.UniformData.Mem)
 	if err != nil {
 		return err
 	}
 
-	_, err = i.DeviceDriver.BindBufferMemory(i.UniformData.Buf, i.UniformData.Mem, 0)
-	if err != nil {
-		return err
+	res, err = i.DeviceDriver.BindBufferMemory(i.UniformData.Buf, i.UniformData.Mem, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBindBufferMemory", "Uniform Buffer", res, err)
 	}
 
 	i.UniformData.BufferInfo.Buffer = i.UniformData.Buf
 	i.UniformData.BufferInfo.Offset = 0
-	i.UniformData.BufferInfo.Range = int(unsafe.Sizeof(i.MVP))
+	i.UniformData.BufferInfo.Range = mvpLayout.Size
 	return nil
 }
 
@@ -737,6 +982,7 @@ func (i *SampleInfo) InitDescriptorAndPipelineLayouts(useTexture bool) error {
 			StageFlags:      core1_0.StageVertex,
 		},
 	}
+	bindingNames := []string{"uniforms"}
 	if useTexture {
 		layoutBindings = append(layoutBindings, core1_0.DescriptorSetLayoutBinding{
 			Binding:         1,
@@ -744,20 +990,36 @@ func (i *SampleInfo) InitDescriptorAndPipelineLayouts(useTexture bool) error {
 			DescriptorCount: 1,
 			StageFlags:      core1_0.StageFragment,
 		})
+		bindingNames = append(bindingNames, "texture")
 	}
 
-	layout, _, err := i.DeviceDriver.CreateDescriptorSetLayout(nil, core1_0.DescriptorSetLayoutCreateInfo{
+	layoutCreateInfo := core1_0.DescriptorSetLayoutCreateInfo{
 		Bindings: layoutBindings,
-	})
+	}
+	layout, res, err := i.DeviceDriver.CreateDescriptorSetLayout(nil, layoutCreateInfo)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateDescriptorSetLayout", "Sample Descriptor Set Layout", res, err)
+	}
+
+	err = i.DebugNames.Name(layout, "Sample Descriptor Set Layout")
+	if err != nil {
+		return err
+	}
+
+	i.SetLayout, err = descriptors.NewLayout(layout, layoutCreateInfo, bindingNames...)
 	if err != nil {
 		return err
 	}
 
 	i.DescLayout = []core1_0.DescriptorSetLayout{layout}
-	i.PipelineLayout, _, err = i.DeviceDriver.CreatePipelineLayout(nil, core1_0.PipelineLayoutCreateInfo{
+	i.PipelineLayout, res, err = i.DeviceDriver.CreatePipelineLayout(nil, core1_0.PipelineLayoutCreateInfo{
 		SetLayouts: []core1_0.DescriptorSetLayout{layout},
 	})
-	return err
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreatePipelineLayout", "Sample Pipeline Layout", res, err)
+	}
+
+	return i.DebugNames.Name(i.PipelineLayout, "Sample Pipeline Layout")
 }
 
 func (i *SampleInfo) InitRenderPass(depthPresent, clear bool, finalLayout, initialLayout core1_0.ImageLayout) error {
@@ -827,9 +1089,14 @@ func (i *SampleInfo) InitRenderPass(depthPresent, clear bool, finalLayout, initi
 		}
 	}
 
+	var res common.VkResult
 	var err error
-	i.RenderPass, _, err = i.DeviceDriver.CreateRenderPass(nil, renderPassOptions)
-	return err
+	i.RenderPass, res, err = i.DeviceDriver.CreateRenderPass(nil, renderPassOptions)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateRenderPass", "Sample Render Pass", res, err)
+	}
+
+	return i.DebugNames.Name(i.RenderPass, "Sample Render Pass")
 }
 
 func bytesToBytecode(b []byte) []uint32 {
@@ -847,16 +1114,27 @@ func bytesToBytecode(b []byte) []uint32 {
 }
 
 func (i *SampleInfo) InitShaders(vertShaderBytes []byte, fragShaderBytes []byte) error {
-	vertShaderModule, _, err := i.DeviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
-		Code: bytesToBytecode(vertShaderBytes),
+	i.VertexShaderCode = bytesToBytecode(vertShaderBytes)
+	vertShaderModule, res, err := i.DeviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
+		Code: i.VertexShaderCode,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateShaderModule", "Vertex Shader", res, err)
+	}
+
+	err = i.DebugNames.Name(vertShaderModule, "Vertex Shader")
 	if err != nil {
 		return err
 	}
 
-	fragShaderModule, _, err := i.DeviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
+	fragShaderModule, res, err := i.DeviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
 		Code: bytesToBytecode(fragShaderBytes),
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateShaderModule", "Fragment Shader", res, err)
+	}
+
+	err = i.DebugNames.Name(fragShaderModule, "Fragment Shader")
 	if err != nil {
 		return err
 	}
@@ -893,8 +1171,12 @@ func (i *SampleInfo) InitFramebuffers(depthPresent bool) error {
 	for swapchainInd := 0; swapchainInd < i.SwapchainImageCount; swapchainInd++ {
 		framebufferOptions.Attachments[0] = i.Buffers[swapchainInd].View
 
-		var err error
-		frameBuffer, _, err := i.DeviceDriver.CreateFramebuffer(nil, framebufferOptions)
+		frameBuffer, res, err := i.DeviceDriver.CreateFramebuffer(nil, framebufferOptions)
+		if res != core1_0.VKSuccess || err != nil {
+			return vulkanError("vkCreateFramebuffer", fmt.Sprintf("Framebuffer %d", swapchainInd), res, err)
+		}
+
+		err = i.DebugNames.Namef(frameBuffer, "Framebuffer %d", swapchainInd)
 		if err != nil {
 			return err
 		}
@@ -905,13 +1187,34 @@ func (i *SampleInfo) InitFramebuffers(depthPresent bool) error {
 	return nil
 }
 
-func (i *SampleInfo) InitVertexBuffers(vertexData any, dataSize int, dataStride int, useTexture bool) error {
-	var err error
-	i.VertexBuffer.Buf, _, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+// InitVertexBuffers uploads vertexData, which must be a slice of structs with vertex
+// input tags, and sets up a per-vertex binding for it at binding 0
+func (i *SampleInfo) InitVertexBuffers(vertexData any, dataSize int) error {
+	vertexType := reflect.TypeOf(vertexData)
+	if vertexType.Kind() != reflect.Slice {
+		return errors.Errorf("vertex data must be a slice, not %s", vertexType)
+	}
+
+	err := i.InitVertexLayout(vertexinput.Binding{
+		Binding:   0,
+		Type:      vertexType.Elem(),
+		InputRate: core1_0.VertexInputRateVertex,
+	})
+	if err != nil {
+		return err
+	}
+
+	var res common.VkResult
+	i.VertexBuffer.Buf, res, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
 		Size:        dataSize,
 		Usage:       core1_0.BufferUsageVertexBuffer,
 		SharingMode: core1_0.SharingModeExclusive,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateBuffer", "Vertex Buffer", res, err)
+	}
+
+	err = i.DebugNames.Name(i.VertexBuffer.Buf, "Vertex Buffer")
 	if err != nil {
 		return err
 	}
@@ -922,10 +1225,15 @@ func (i *SampleInfo) InitVertexBuffers(vertexData any, dataSize int, dataStride
 		return err
 	}
 
-	i.VertexBuffer.Mem, _, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+	i.VertexBuffer.Mem, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryIndex,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", "Vertex Buffer Memory", res, err)
+	}
+
+	err = i.DebugNames.Name(i.VertexBuffer.Mem, "Vertex Buffer Memory")
 	if err != nil {
 		return err
 	}
@@ -933,124 +1241,155 @@ func (i *SampleInfo) InitVertexBuffers(vertexData any, dataSize int, dataStride
 	i.VertexBuffer.BufferInfo.Range = memReqs.Size
 	i.VertexBuffer.BufferInfo.Offset = 0
 
-	vertexPtr, _, err := i.DeviceDriver.MapMemory(i.VertexBuffer.Mem, 0, memReqs.Size, 0)
+	vertexPtr, res, err := i.DeviceDriver.MapMemory(i.VertexBuffer.Mem, 0, memReqs.Size, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkMapMemory", "Vertex Buffer Memory", res, err)
+	}
+
+	_, err = upload.CopyValue(unsafe.Slice((*byte)(vertexPtr), memReqs.Size), vertexData)
+	i.DeviceDriver.UnmapMemory(i.VertexBuffer.Mem)
 	if err != nil {
 		return err
 	}
 
-	dataBuffer := unsafe.Slice((*byte)(vertexPtr), memReqs.Size)
+	res, err = i.DeviceDriver.BindBufferMemory(i.VertexBuffer.Buf, i.VertexBuffer.Mem, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBindBufferMemory", "Vertex Buffer", res, err)
+	}
 
-	buf := &bytes.Buffer{}
-	err = binary.Write(buf, common.ByteOrder, vertexData)
+	return nil
+}
+
+// InitIndexBuffer uploads the indices of a mesh with vertexCount vertices, stored as
+// 16-bit indices when vertexCount allows it and 32-bit indices otherwise
+func (i *SampleInfo) InitIndexBuffer(indices []uint32, vertexCount int) error {
+	i.IndexBuffer.Type = mesh.IndexTypeFor(vertexCount)
+	i.IndexBuffer.Count = len(indices)
+	indexData := mesh.Narrow(indices, i.IndexBuffer.Type)
+	dataSize := binary.Size(indexData)
+
+	var res common.VkResult
+	var err error
+	i.IndexBuffer.Buf, res, err = i.DeviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+		Size:        dataSize,
+		Usage:       core1_0.BufferUsageIndexBuffer,
+		SharingMode: core1_0.SharingModeExclusive,
+	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateBuffer", "Index Buffer", res, err)
+	}
+
+	err = i.DebugNames.Name(i.IndexBuffer.Buf, "Index Buffer")
 	if err != nil {
-		i.DeviceDriver.UnmapMemory(i.VertexBuffer.Mem)
 		return err
 	}
 
-	copy(dataBuffer, buf.Bytes())
+	memReqs := i.DeviceDriver.GetBufferMemoryRequirements(i.IndexBuffer.Buf)
+	memoryIndex, err := i.MemoryTypeFromProperties(memReqs.MemoryTypeBits, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+	if err != nil {
+		return err
+	}
 
-	i.DeviceDriver.UnmapMemory(i.VertexBuffer.Mem)
+	i.IndexBuffer.Mem, res, err = i.DeviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+		AllocationSize:  memReqs.Size,
+		MemoryTypeIndex: memoryIndex,
+	})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkAllocateMemory", "Index Buffer Memory", res, err)
+	}
 
-	_, err = i.DeviceDriver.BindBufferMemory(i.VertexBuffer.Buf, i.VertexBuffer.Mem, 0)
+	err = i.DebugNames.Name(i.IndexBuffer.Mem, "Index Buffer Memory")
 	if err != nil {
 		return err
 	}
 
-	i.VertexBinding = core1_0.VertexInputBindingDescription{
-		InputRate: core1_0.VertexInputRateVertex,
-		Binding:   0,
-		Stride:    dataStride,
+	indexPtr, res, err := i.DeviceDriver.MapMemory(i.IndexBuffer.Mem, 0, memReqs.Size, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkMapMemory", "Index Buffer Memory", res, err)
 	}
-	i.VertexAttributes = []core1_0.VertexInputAttributeDescription{
-		{
-			Binding:  0,
-			Location: 0,
-			Offset:   0,
-			Format:   core1_0.FormatR32G32B32A32SignedFloat,
-		},
-		{
-			Binding:  0,
-			Location: 1,
-			Offset:   16,
-		},
+
+	_, err = upload.CopyValue(unsafe.Slice((*byte)(indexPtr), dataSize), indexData)
+	i.DeviceDriver.UnmapMemory(i.IndexBuffer.Mem)
+	if err != nil {
+		return err
 	}
 
-	if useTexture {
-		i.VertexAttributes[1].Format = core1_0.FormatR32G32SignedFloat
-	} else {
-		i.VertexAttributes[1].Format = core1_0.FormatR32G32B32A32SignedFloat
+	res, err = i.DeviceDriver.BindBufferMemory(i.IndexBuffer.Buf, i.IndexBuffer.Mem, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkBindBufferMemory", "Index Buffer", res, err)
 	}
 
 	return nil
 }
 
+// InitVertexLayout derives VertexBinding and VertexAttributes from the vertex input tags
+// of a single binding's struct
+func (i *SampleInfo) InitVertexLayout(binding vertexinput.Binding) error {
+	layout, err := vertexinput.New(binding)
+	if err != nil {
+		return err
+	}
+
+	i.VertexLayout = layout
+	i.VertexBinding = layout.Bindings[0]
+	i.VertexAttributes = layout.AttributeDescriptions()
+	return nil
+}
+
 func (i *SampleInfo) InitDescriptorPool(useTexture bool) error {
-	poolSizes := []core1_0.DescriptorPoolSize{
-		{
-			Type:            core1_0.DescriptorTypeUniformBuffer,
-			DescriptorCount: 1,
-		},
+	ratios := []descriptors.PoolRatio{
+		{Type: core1_0.DescriptorTypeUniformBuffer, PerSet: 1},
 	}
 
 	if useTexture {
-		poolSizes = append(poolSizes, core1_0.DescriptorPoolSize{
-			Type:            core1_0.DescriptorTypeCombinedImageSampler,
-			DescriptorCount: 1,
+		ratios = append(ratios, descriptors.PoolRatio{
+			Type:   core1_0.DescriptorTypeCombinedImageSampler,
+			PerSet: 1,
 		})
 	}
 
-	var err error
-	i.DescPool, _, err = i.DeviceDriver.CreateDescriptorPool(nil, core1_0.DescriptorPoolCreateInfo{
-		MaxSets:   1,
-		PoolSizes: poolSizes,
-	})
+	// Pools are created as sets are allocated, growing when they run out
+	i.Descriptors = descriptors.NewAllocator(i.DeviceDriver, NumDescriptorSets, ratios)
+	i.Descriptors.Names = i.DebugNames
+	i.Descriptors.Name = "Sample Descriptor Pool"
+	i.Descriptors.ReportsOutOfPoolMemory = i.DeviceAPIVersion.IsAtLeast(common.Vulkan1_1) ||
+		i.DeviceExtensionEnabled(khr_maintenance1.ExtensionName)
+	i.DescWriter = descriptors.NewWriter(i.DeviceDriver, i.Descriptors)
 
-	return err
+	return nil
 }
 
 func (i *SampleInfo) InitDescriptorSet(useTexture bool) error {
-	descSet, _, err := i.DeviceDriver.AllocateDescriptorSets(core1_0.DescriptorSetAllocateInfo{
-		DescriptorPool: i.DescPool,
-		SetLayouts:     i.DescLayout,
-	})
-	if err != nil {
-		log.Fatalln(err)
+	group := i.SetLayout.Group().Buffer(descriptors.Named("uniforms"), i.UniformData.BufferInfo)
+	if useTexture {
+		group.Image(descriptors.Named("texture"), i.TextureData.ImageInfo)
 	}
 
-	i.DescSet = descSet
-
-	writes := []core1_0.WriteDescriptorSet{
-		{
-			DstSet:          i.DescSet[0],
-			DstBinding:      0,
-			DstArrayElement: 0,
-
-			DescriptorType: core1_0.DescriptorTypeUniformBuffer,
-			BufferInfo:     []core1_0.DescriptorBufferInfo{i.UniformData.BufferInfo},
-		},
+	descSet, err := i.DescWriter.WriteAll(group)
+	if err != nil {
+		return err
 	}
 
-	if useTexture {
-		writes = append(writes, core1_0.WriteDescriptorSet{
-			DstSet:          i.DescSet[0],
-			DstBinding:      1,
-			DstArrayElement: 0,
-
-			DescriptorType: core1_0.DescriptorTypeCombinedImageSampler,
-			ImageInfo:      []core1_0.DescriptorImageInfo{i.TextureData.ImageInfo},
-		})
-	}
+	i.DescSet = descSet
 
-	return i.DeviceDriver.UpdateDescriptorSets(writes, nil)
+	return i.DebugNames.Name(i.DescSet[0], "Sample Descriptor Set")
 }
 
 func (i *SampleInfo) InitPipelineCache() error {
+	var res common.VkResult
 	var err error
-	i.PipelineCache, _, err = i.DeviceDriver.CreatePipelineCache(nil, core1_0.PipelineCacheCreateInfo{})
-	return err
+	i.PipelineCache, res, err = i.DeviceDriver.CreatePipelineCache(nil, core1_0.PipelineCacheCreateInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreatePipelineCache", "Sample Pipeline Cache", res, err)
+	}
+
+	return i.DebugNames.Name(i.PipelineCache, "Sample Pipeline Cache")
 }
 
-func (i *SampleInfo) InitPipeline(depthPresent bool, vertexPresent bool) error {
+// GraphicsPipelineCreateInfo returns the create info InitPipeline uses, so that variants
+// of the sample pipeline can be built from it. Every call returns new state structs,
+// which can be modified without affecting other calls.
+func (i *SampleInfo) GraphicsPipelineCreateInfo(depthPresent bool, vertexPresent bool) core1_0.GraphicsPipelineCreateInfo {
 	pipelineOptions := core1_0.GraphicsPipelineCreateInfo{
 		Stages:           i.ShaderStages,
 		VertexInputState: &core1_0.PipelineVertexInputStateCreateInfo{},
@@ -1142,27 +1481,46 @@ func (i *SampleInfo) InitPipeline(depthPresent bool, vertexPresent bool) error {
 		pipelineOptions.VertexInputState.VertexAttributeDescriptions = i.VertexAttributes
 	}
 
-	pipelines, _, err := i.DeviceDriver.CreateGraphicsPipelines(&i.PipelineCache, nil,
-		pipelineOptions,
+	return pipelineOptions
+}
+
+func (i *SampleInfo) InitPipeline(depthPresent bool, vertexPresent bool) error {
+	if vertexPresent && i.VertexLayout != nil && i.VertexShaderCode != nil {
+		err := i.VertexLayout.Check(i.VertexShaderCode)
+		if err != nil {
+			return errors.Wrap(err, "vertex shader inputs")
+		}
+	}
+
+	pipelines, res, err := i.DeviceDriver.CreateGraphicsPipelines(&i.PipelineCache, nil,
+		i.GraphicsPipelineCreateInfo(depthPresent, vertexPresent),
 	)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateGraphicsPipelines", "Sample Graphics Pipeline", res, err)
+	}
 
 	i.Pipeline = pipelines[0]
-	return err
+	return i.DebugNames.Name(i.Pipeline, "Sample Graphics Pipeline")
 }
 
 func (i *SampleInfo) InitPresentableImage() error {
+	var res common.VkResult
 	var err error
-	i.ImageAcquiredSemaphore, _, err = i.DeviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
+	i.ImageAcquiredSemaphore, res, err = i.DeviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkCreateSemaphore", "Image Acquired Semaphore", res, err)
+	}
+
+	err = i.DebugNames.Name(i.ImageAcquiredSemaphore, "Image Acquired Semaphore")
 	if err != nil {
 		return err
 	}
 
-	// Get the index of the next available swapchain image:
-	i.CurrentBuffer, _, err = i.SwapchainExtension.AcquireNextImage(i.Swapchain, common.NoTimeout, &i.ImageAcquiredSemaphore, nil)
-
-	// TODO: Deal with the VK_SUBOPTIMAL_KHR and VK_ERROR_OUT_OF_DATE_KHR
-	// return codes
-	return err
+	// Get the index of the next available swapchain image. VK_SUBOPTIMAL_KHR and
+	// VK_ERROR_OUT_OF_DATE_KHR come back as ErrSuboptimal and ErrOutOfDate; CurrentBuffer
+	// is still usable after ErrSuboptimal.
+	i.CurrentBuffer, res, err = i.SwapchainExtension.AcquireNextImage(i.Swapchain, common.NoTimeout, &i.ImageAcquiredSemaphore, nil)
+	return vulkanError("vkAcquireNextImageKHR", "Swapchain", res, err)
 }
 
 func (i *SampleInfo) InitClearColorAndDepth() []core1_0.ClearValue {
@@ -1203,9 +1561,41 @@ func (i *SampleInfo) InitScissors() {
 	i.DeviceDriver.CmdSetScissor(i.Cmd, i.Scissor)
 }
 
+// BeginRenderPass begins a render pass in cmd, leaving a breadcrumb labeled label just
+// before it. End it with EndRenderPass and the same label.
+func (i *SampleInfo) BeginRenderPass(cmd core1_0.CommandBuffer, label string, contents core1_0.SubpassContents, beginInfo core1_0.RenderPassBeginInfo) error {
+	i.Breadcrumbs.Pass(cmd, label+" (begin)")
+	return i.DeviceDriver.CmdBeginRenderPass(cmd, contents, beginInfo)
+}
+
+// EndRenderPass ends the render pass in cmd, leaving a breadcrumb just after it
+func (i *SampleInfo) EndRenderPass(cmd core1_0.CommandBuffer, label string) {
+	i.DeviceDriver.CmdEndRenderPass(cmd)
+	i.Breadcrumbs.Pass(cmd, label+" (end)")
+}
+
+// Draw records a draw in cmd between two breadcrumbs labeled label, so a device loss
+// report shows whether the GPU got past it
+func (i *SampleInfo) Draw(cmd core1_0.CommandBuffer, label string, vertexCount, instanceCount int, firstVertex, firstInstance uint32) {
+	i.Breadcrumbs.Draw(cmd, label+" (begin)")
+	i.DeviceDriver.CmdDraw(cmd, vertexCount, instanceCount, firstVertex, firstInstance)
+	i.Breadcrumbs.Draw(cmd, label+" (end)")
+}
+
+// DrawIndexed is Draw for an indexed draw
+func (i *SampleInfo) DrawIndexed(cmd core1_0.CommandBuffer, label string, indexCount, instanceCount int, firstIndex uint32, vertexOffset int, firstInstance uint32) {
+	i.Breadcrumbs.Draw(cmd, label+" (begin)")
+	i.DeviceDriver.CmdDrawIndexed(cmd, indexCount, instanceCount, firstIndex, vertexOffset, firstInstance)
+	i.Breadcrumbs.Draw(cmd, label+" (end)")
+}
+
 func (i *SampleInfo) InitFence() (core1_0.Fence, error) {
-	fence, _, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
-	return fence, err
+	fence, res, err := i.DeviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{})
+	if res != core1_0.VKSuccess || err != nil {
+		return fence, vulkanError("vkCreateFence", "Sample Fence", res, err)
+	}
+
+	return fence, i.DebugNames.Name(fence, "Sample Fence")
 }
 
 func (i *SampleInfo) InitSubmitInfo(stageFlags core1_0.PipelineStageFlags) *core1_0.SubmitInfo {
@@ -1223,7 +1613,7 @@ func (i *SampleInfo) InitPresentInfo() khr_swapchain.PresentInfo {
 	}
 }
 func (i *SampleInfo) InitSampler() (core1_0.Sampler, error) {
-	sampler, _, err := i.DeviceDriver.CreateSampler(nil, core1_0.SamplerCreateInfo{
+	sampler, res, err := i.DeviceDriver.CreateSampler(nil, core1_0.SamplerCreateInfo{
 		MagFilter:        core1_0.FilterNearest,
 		MinFilter:        core1_0.FilterNearest,
 		MipmapMode:       core1_0.SamplerMipmapModeNearest,
@@ -1239,32 +1629,56 @@ func (i *SampleInfo) InitSampler() (core1_0.Sampler, error) {
 		CompareEnable:    false,
 		BorderColor:      core1_0.BorderColorFloatOpaqueWhite,
 	})
+	if res != core1_0.VKSuccess || err != nil {
+		return sampler, vulkanError("vkCreateSampler", "Sample Sampler", res, err)
+	}
 
-	return sampler, err
+	return sampler, i.DebugNames.Name(sampler, "Sample Sampler")
 }
 
 func (i *SampleInfo) ExecuteQueueCmdBuf(cmdBufs []core1_0.CommandBuffer, fence core1_0.Fence) error {
 	/* Queue the command buffer for execution */
-	_, err := i.DeviceDriver.QueueSubmit(i.GraphicsQueue, &fence,
+	res, err := i.SyncGraphicsQueue.Submit(&fence,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{i.ImageAcquiredSemaphore},
 			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
 			CommandBuffers:   cmdBufs,
 		},
 	)
-	return err
+	i.Breadcrumbs.Check(res)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkQueueSubmit", "Graphics Queue", res, err)
+	}
+
+	i.Breadcrumbs.Submitted(i.GraphicsQueue, fence, cmdBufs...)
+	return nil
 }
 
 func (i *SampleInfo) ExecutePresentImage() error {
-	_, err := i.SwapchainExtension.QueuePresent(i.PresentQueue, khr_swapchain.PresentInfo{
+	res, err := i.SyncPresentQueue.Present(i.SwapchainExtension, khr_swapchain.PresentInfo{
 		Swapchains:   []khr_swapchain.Swapchain{i.Swapchain},
 		ImageIndices: []int{i.CurrentBuffer},
 	})
-	return err
+	i.Breadcrumbs.Check(res)
+	return vulkanError("vkQueuePresentKHR", "Present Queue", res, err)
+}
+
+// OnDestroy adds a hook that is called with each framebuffer, render pass and pipeline the
+// SampleInfo destroys, including the framebuffers RecreateSwapchain replaces. Hooks run
+// before anything is created that could reuse the destroyed object's handle.
+func (i *SampleInfo) OnDestroy(hook func(object any)) {
+	i.onDestroy = append(i.onDestroy, hook)
+}
+
+func (i *SampleInfo) destroyed(object any) {
+	for _, hook := range i.onDestroy {
+		hook(object)
+	}
 }
 
 func (i *SampleInfo) DestroyPipeline() {
 	i.DeviceDriver.DestroyPipeline(i.Pipeline, nil)
+	i.destroyed(i.Pipeline)
 }
 
 func (i *SampleInfo) DestroyPipelineCache() {
@@ -1281,9 +1695,15 @@ func (i *SampleInfo) DestroyVertexBuffer() {
 	i.DeviceDriver.FreeMemory(i.VertexBuffer.Mem, nil)
 }
 
+func (i *SampleInfo) DestroyIndexBuffer() {
+	i.DeviceDriver.DestroyBuffer(i.IndexBuffer.Buf, nil)
+	i.DeviceDriver.FreeMemory(i.IndexBuffer.Mem, nil)
+}
+
 func (i *SampleInfo) DestroyFramebuffers() {
 	for ind := 0; ind < i.SwapchainImageCount; ind++ {
 		i.DeviceDriver.DestroyFramebuffer(i.Framebuffer[ind], nil)
+		i.destroyed(i.Framebuffer[ind])
 	}
 }
 
@@ -1294,6 +1714,7 @@ func (i *SampleInfo) DestroyShaders() {
 
 func (i *SampleInfo) DestroyRenderpass() {
 	i.DeviceDriver.DestroyRenderPass(i.RenderPass, nil)
+	i.destroyed(i.RenderPass)
 }
 
 func (i *SampleInfo) DestroyDepthBuffer() {
@@ -1310,6 +1731,43 @@ func (i *SampleInfo) DestroySwapchain() {
 	i.SwapchainExtension.DestroySwapchain(i.Swapchain, nil)
 }
 
+// RecreateSwapchain replaces the swapchain, and the depth buffer and framebuffers sized to
+// it, at the window's current size. Call it when acquire or present returns
+// VK_ERROR_OUT_OF_DATE_KHR or VK_SUBOPTIMAL_KHR. It idles the device first, so no frame
+// in flight still uses the old images.
+func (i *SampleInfo) RecreateSwapchain(usage core1_0.ImageUsageFlags, depthPresent bool) error {
+	res, err := i.DeviceDriver.DeviceWaitIdle()
+	i.Breadcrumbs.Check(res)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkDeviceWaitIdle", "", res, err)
+	}
+
+	width, height := i.Window.VulkanGetDrawableSize()
+	i.Width, i.Height = int(width), int(height)
+
+	i.DestroyFramebuffers()
+	i.Framebuffer = nil
+	if depthPresent {
+		i.DestroyDepthBuffer()
+	}
+	i.DestroySwapchain()
+	i.Buffers = nil
+
+	err = i.InitSwapchain(usage)
+	if err != nil {
+		return err
+	}
+
+	if depthPresent {
+		err = i.InitDepthBuffer()
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.InitFramebuffers(depthPresent)
+}
+
 func (i *SampleInfo) DestroyCommandBuffer() {
 	i.DeviceDriver.FreeCommandBuffers(i.Cmd)
 }
@@ -1319,11 +1777,15 @@ func (i *SampleInfo) DestroyCommandPool() {
 }
 
 func (i *SampleInfo) DestroyDevice() error {
-	_, err := i.DeviceDriver.DeviceWaitIdle()
-	if err != nil {
-		return err
+	res, err := i.DeviceDriver.DeviceWaitIdle()
+	i.Breadcrumbs.Check(res)
+	if res != core1_0.VKSuccess || err != nil {
+		return vulkanError("vkDeviceWaitIdle", "", res, err)
 	}
 
+	i.Frames.Destroy()
+	i.Queues.Destroy()
+	i.Breadcrumbs.Destroy()
 	i.DeviceDriver.DestroyDevice(nil)
 	return nil
 }
@@ -1333,7 +1795,14 @@ func (i *SampleInfo) DestroyInstance() {
 }
 
 func (i *SampleInfo) DestroyDescriptorPool() {
-	i.DeviceDriver.DestroyDescriptorPool(i.DescPool, nil)
+	if i.Descriptors != nil {
+		i.Descriptors.Destroy()
+		i.Descriptors = nil
+	}
+
+	if i.DescPool.Initialized() {
+		i.DeviceDriver.DestroyDescriptorPool(i.DescPool, nil)
+	}
 }
 
 func (i *SampleInfo) DestroyDescriptorAndPipelineLayouts() {
@@ -0,0 +1,355 @@
+package secondarycache
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/framesync"
+)
+
+// DefaultMaxIdleFrames is how many frames New lets an entry go unused before evicting it
+const DefaultMaxIdleFrames = 8
+
+// Key identifies a recording. Secondary command buffers that continue a render pass can
+// only be executed in a render pass and subpass compatible with the ones they were
+// recorded for, so those are part of the key. Framebuffer may be left uninitialized to
+// record for any framebuffer, at some cost to performance on some drivers.
+type Key struct {
+	Name        string
+	RenderPass  core1_0.RenderPass
+	Subpass     int
+	Framebuffer core1_0.Framebuffer
+}
+
+// Dependency is an object a recording uses, along with the version of it the recording
+// was made against. The object's owner bumps Version whenever it rebuilds the object or,
+// for a descriptor set, updates its contents: a rebuilt object may reuse the old handle,
+// and an update leaves the handle alone.
+type Dependency struct {
+	Object  any
+	Version uint64
+}
+
+// handleKey identifies a Vulkan object by its type and handle
+type handleKey struct {
+	objectType reflect.Type
+	handle     uint64
+}
+
+func handleOf(object any) (handleKey, error) {
+	value := reflect.ValueOf(object)
+	method := value.MethodByName("Handle")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return handleKey{}, errors.Errorf("%T is not a Vulkan object", object)
+	}
+
+	handle := method.Call(nil)[0]
+	switch handle.Kind() {
+	case reflect.Uintptr, reflect.Uint64, reflect.Uint32:
+		return handleKey{objectType: value.Type(), handle: handle.Uint()}, nil
+	}
+
+	return handleKey{}, errors.Errorf("%T is not a Vulkan object", object)
+}
+
+type dependencyKey struct {
+	handle  handleKey
+	version uint64
+}
+
+type entryKey struct {
+	name        string
+	renderPass  handleKey
+	subpass     int
+	framebuffer handleKey
+}
+
+type entry struct {
+	commandBuffer core1_0.CommandBuffer
+	dependencies  []dependencyKey
+	// lastUsed is the number of the last frame the command buffer was returned for
+	lastUsed uint64
+}
+
+type retiredBuffer struct {
+	commandBuffer core1_0.CommandBuffer
+	lastUsed      uint64
+}
+
+// Cache holds pre-recorded secondary command buffers for static geometry, so they can be
+// recorded once and replayed every frame with CmdExecuteCommands.
+//
+// Each recording lists the objects it uses, such as pipelines, buffers and descriptor
+// sets, with their versions. When a Get passes a different version of any of them, it
+// records again. Recordings are dropped as soon as an Owner the Cache watches destroys
+// an object they use, since a driver may give a new object the handle of a destroyed
+// one. Code that rebuilds or updates an object some other way should call Invalidate
+// with it.
+//
+// Command buffers are numbered by the frames of a framesync.Counter. Replaced and evicted
+// ones are freed once the counter has completed the last frame that used them.
+type Cache struct {
+	// Names is used to attach debug names to recorded command buffers, and may be nil
+	Names *debugnames.Namer
+	// Name prefixes the debug names
+	Name string
+	// MaxIdleFrames is how many frames an entry may go without being used before it is
+	// freed
+	MaxIdleFrames int
+
+	deviceDriver core1_0.CoreDeviceDriver
+	frames       *framesync.Counter
+	pool         core1_0.CommandPool
+
+	lock    sync.Mutex
+	entries map[entryKey]*entry
+	retired []retiredBuffer
+	hits    int
+	misses  int
+}
+
+// Owner destroys objects that recordings may use, and passes each one to the hooks added
+// with OnDestroy. *utils.SampleInfo and *pipelineregistry.Registry are Owners.
+type Owner interface {
+	OnDestroy(hook func(object any))
+}
+
+// New creates an empty Cache that allocates command buffers for queueFamilyIndex. The
+// primary command buffers that execute its recordings must be submitted with frames.
+func New(deviceDriver core1_0.CoreDeviceDriver, frames *framesync.Counter, queueFamilyIndex int) (*Cache, error) {
+	pool, _, err := deviceDriver.CreateCommandPool(nil, core1_0.CommandPoolCreateInfo{
+		QueueFamilyIndex: queueFamilyIndex,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{
+		Name:          "Secondary Cache",
+		MaxIdleFrames: DefaultMaxIdleFrames,
+		deviceDriver:  deviceDriver,
+		frames:        frames,
+		pool:          pool,
+		entries:       make(map[entryKey]*entry),
+	}, nil
+}
+
+// Get returns the command buffer recorded for key, calling record to record it if there
+// is no recording yet or any of dependencies has changed since the last one. record
+// receives a secondary command buffer that has already been begun to continue key's
+// render pass, and must not end it or have a watched Owner destroy anything. The command
+// buffer is taken to be used by the next frame submitted to the Cache's counter.
+func (c *Cache) Get(key Key, dependencies []Dependency, record func(commandBuffer core1_0.CommandBuffer) error) (core1_0.CommandBuffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	lookup, err := makeEntryKey(key)
+	if err != nil {
+		return core1_0.CommandBuffer{}, err
+	}
+
+	dependencyKeys := make([]dependencyKey, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		handle, err := handleOf(dependency.Object)
+		if err != nil {
+			return core1_0.CommandBuffer{}, errors.Wrapf(err, "dependency of %s", key.Name)
+		}
+		dependencyKeys = append(dependencyKeys, dependencyKey{handle: handle, version: dependency.Version})
+	}
+
+	frame := c.frames.Submitted() + 1
+	existing, found := c.entries[lookup]
+	if found && slices.Equal(existing.dependencies, dependencyKeys) {
+		c.hits++
+		existing.lastUsed = frame
+		return existing.commandBuffer, nil
+	}
+
+	c.misses++
+	if found {
+		c.retire(lookup, existing)
+	}
+
+	commandBuffer, err := c.record(key, record)
+	if err != nil {
+		return commandBuffer, err
+	}
+
+	c.entries[lookup] = &entry{
+		commandBuffer: commandBuffer,
+		dependencies:  dependencyKeys,
+		lastUsed:      frame,
+	}
+	return commandBuffer, nil
+}
+
+func makeEntryKey(key Key) (entryKey, error) {
+	renderPass, err := handleOf(key.RenderPass)
+	if err != nil {
+		return entryKey{}, err
+	}
+
+	framebuffer, err := handleOf(key.Framebuffer)
+	if err != nil {
+		return entryKey{}, err
+	}
+
+	return entryKey{
+		name:        key.Name,
+		renderPass:  renderPass,
+		subpass:     key.Subpass,
+		framebuffer: framebuffer,
+	}, nil
+}
+
+func (c *Cache) record(key Key, record func(commandBuffer core1_0.CommandBuffer) error) (core1_0.CommandBuffer, error) {
+	buffers, _, err := c.deviceDriver.AllocateCommandBuffers(core1_0.CommandBufferAllocateInfo{
+		CommandPool:        c.pool,
+		Level:              core1_0.CommandBufferLevelSecondary,
+		CommandBufferCount: 1,
+	})
+	if err != nil {
+		return core1_0.CommandBuffer{}, err
+	}
+	commandBuffer := buffers[0]
+
+	err = c.Names.Namef(commandBuffer, "%s %s", c.Name, key.Name)
+	if err != nil {
+		c.deviceDriver.FreeCommandBuffers(commandBuffer)
+		return core1_0.CommandBuffer{}, err
+	}
+
+	// Replayed buffers may be pending in more than one frame at a time
+	_, err = c.deviceDriver.BeginCommandBuffer(commandBuffer, core1_0.CommandBufferBeginInfo{
+		Flags: core1_0.CommandBufferUsageRenderPassContinue | core1_0.CommandBufferUsageSimultaneousUse,
+		InheritanceInfo: &core1_0.CommandBufferInheritanceInfo{
+			RenderPass:  key.RenderPass,
+			Subpass:     key.Subpass,
+			Framebuffer: key.Framebuffer,
+		},
+	})
+	if err == nil {
+		err = record(commandBuffer)
+	}
+	if err == nil {
+		_, err = c.deviceDriver.EndCommandBuffer(commandBuffer)
+	}
+	if err != nil {
+		c.deviceDriver.FreeCommandBuffers(commandBuffer)
+		return core1_0.CommandBuffer{}, errors.Wrapf(err, "recording %s", key.Name)
+	}
+
+	return commandBuffer, nil
+}
+
+func (c *Cache) retire(lookup entryKey, existing *entry) {
+	delete(c.entries, lookup)
+	c.retired = append(c.retired, retiredBuffer{
+		commandBuffer: existing.commandBuffer,
+		lastUsed:      existing.lastUsed,
+	})
+}
+
+// Invalidate drops every recording whose key or dependencies include object, whatever
+// its version. Call it when rebuilding, updating or destroying an object recordings use.
+func (c *Cache) Invalidate(object any) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	invalid, err := handleOf(object)
+	if err != nil {
+		return err
+	}
+
+	for lookup, existing := range c.entries {
+		uses := lookup.renderPass == invalid || lookup.framebuffer == invalid
+		for _, dependency := range existing.dependencies {
+			uses = uses || dependency.handle == invalid
+		}
+
+		if uses {
+			c.retire(lookup, existing)
+		}
+	}
+
+	return nil
+}
+
+// Watch makes the Cache drop recordings that use an object whenever owner destroys it,
+// such as the framebuffers a swapchain recreation replaces or a pipeline a registry
+// evicts
+func (c *Cache) Watch(owner Owner) {
+	owner.OnDestroy(func(object any) {
+		// Owners only destroy Vulkan objects, which Invalidate can't fail on
+		_ = c.Invalidate(object)
+	})
+}
+
+// InvalidateAll drops every recording
+func (c *Cache) InvalidateAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for lookup, existing := range c.entries {
+		c.retire(lookup, existing)
+	}
+}
+
+// EndFrame is called once a frame after submitting it. It evicts recordings that haven't
+// been used recently, and frees command buffers the counter shows the GPU is finished
+// with.
+func (c *Cache) EndFrame() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	submitted := c.frames.Submitted()
+	for lookup, existing := range c.entries {
+		if submitted > existing.lastUsed && submitted-existing.lastUsed >= uint64(c.MaxIdleFrames) {
+			c.retire(lookup, existing)
+		}
+	}
+
+	completed, err := c.frames.Completed()
+	if err != nil {
+		return err
+	}
+
+	var stillInFlight []retiredBuffer
+	var finished []core1_0.CommandBuffer
+	for _, retired := range c.retired {
+		if retired.lastUsed <= completed {
+			finished = append(finished, retired.commandBuffer)
+		} else {
+			stillInFlight = append(stillInFlight, retired)
+		}
+	}
+	c.retired = stillInFlight
+
+	if len(finished) > 0 {
+		c.deviceDriver.FreeCommandBuffers(finished...)
+	}
+	return nil
+}
+
+// Stats returns how many calls to Get replayed a recording and how many recorded
+func (c *Cache) Stats() (hits, misses int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.hits, c.misses
+}
+
+// Destroy destroys the cache's command pool, freeing every command buffer. The GPU must
+// be done with all of them.
+func (c *Cache) Destroy() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.deviceDriver.DestroyCommandPool(c.pool, nil)
+	c.entries = nil
+	c.retired = nil
+}
@@ -0,0 +1,276 @@
+package uniformring
+
+import (
+	"context"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/framesync"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/internal/vkerror"
+)
+
+// FenceTimeout is how long Allocate waits for an old frame to finish before giving up
+const FenceTimeout = 5 * time.Second
+
+// Allocation is a region of the ring that can be written by the host and read by shaders
+type Allocation struct {
+	// Offset is the start of the region in the ring's buffer, suitable for use as a dynamic
+	// offset
+	Offset int
+	// Data is the mapped memory of the region
+	Data []byte
+}
+
+type frame struct {
+	end   uint64
+	fence core1_0.Fence
+	// counter and counterFrame are set instead of fence by EndFrameCounter
+	counter      *framesync.Counter
+	counterFrame uint64
+}
+
+// Ring is a persistently-mapped, host-coherent buffer that hands out aligned regions for
+// per-draw uniform or storage data. Regions are handed out in order, and the space used
+// by a frame is reclaimed once its fence or framesync.Counter shows the GPU is done with
+// it, so a sample can push as many per-draw values as it likes without creating a buffer
+// for each.
+//
+// Positions in the ring are tracked as ever-increasing virtual offsets, which are reduced
+// modulo the capacity to find the physical offset in the buffer.
+type Ring struct {
+	deviceDriver core1_0.CoreDeviceDriver
+	buffer       core1_0.Buffer
+	memory       core1_0.DeviceMemory
+	mapped       []byte
+	alignment    int
+	capacity     uint64
+
+	head uint64
+	// tail is the virtual offset of the oldest data the GPU may still be reading
+	tail     uint64
+	inFlight []frame
+}
+
+// New creates a Ring of at least size bytes. usage should contain
+// core1_0.BufferUsageUniformBuffer, core1_0.BufferUsageStorageBuffer, or both, and
+// determines the alignment of each allocation. Failed Vulkan commands are reported as
+// *utils.VulkanError.
+func New(deviceDriver core1_0.CoreDeviceDriver, memoryProperties *core1_0.PhysicalDeviceMemoryProperties, limits *core1_0.PhysicalDeviceLimits, usage core1_0.BufferUsageFlags, size int) (*Ring, error) {
+	alignment := 1
+	if usage&core1_0.BufferUsageUniformBuffer != 0 && limits.MinUniformBufferOffsetAlignment > alignment {
+		alignment = limits.MinUniformBufferOffsetAlignment
+	}
+	if usage&core1_0.BufferUsageStorageBuffer != 0 && limits.MinStorageBufferOffsetAlignment > alignment {
+		alignment = limits.MinStorageBufferOffsetAlignment
+	}
+
+	// Every allocation starts on an alignment boundary, so the capacity must be a
+	// multiple of the alignment for wrapped offsets to stay aligned
+	size = alignUp(size, alignment)
+
+	ring := &Ring{
+		deviceDriver: deviceDriver,
+		alignment:    alignment,
+		capacity:     uint64(size),
+	}
+
+	var res common.VkResult
+	var err error
+	ring.buffer, res, err = deviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
+		Size:        size,
+		Usage:       usage,
+		SharingMode: core1_0.SharingModeExclusive,
+	})
+	if res != core1_0.VKSuccess || err != nil {
+		return nil, vkerror.New("vkCreateBuffer", "Uniform Ring", res, err)
+	}
+
+	memReqs := deviceDriver.GetBufferMemoryRequirements(ring.buffer)
+	memoryTypeIndex := -1
+	flags := core1_0.MemoryPropertyHostVisible | core1_0.MemoryPropertyHostCoherent
+	for typeIndex, memType := range memoryProperties.MemoryTypes {
+		if memReqs.MemoryTypeBits&(1<<typeIndex) != 0 && memType.PropertyFlags&flags == flags {
+			memoryTypeIndex = typeIndex
+			break
+		}
+	}
+	if memoryTypeIndex < 0 {
+		deviceDriver.DestroyBuffer(ring.buffer, nil)
+		return nil, errors.New("no host-coherent memory type for the uniform ring")
+	}
+
+	ring.memory, res, err = deviceDriver.AllocateMemory(nil, core1_0.MemoryAllocateInfo{
+		AllocationSize:  memReqs.Size,
+		MemoryTypeIndex: memoryTypeIndex,
+	})
+	if res != core1_0.VKSuccess || err != nil {
+		deviceDriver.DestroyBuffer(ring.buffer, nil)
+		return nil, vkerror.New("vkAllocateMemory", "Uniform Ring", res, err)
+	}
+
+	res, err = deviceDriver.BindBufferMemory(ring.buffer, ring.memory, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		ring.Destroy()
+		return nil, vkerror.New("vkBindBufferMemory", "Uniform Ring", res, err)
+	}
+
+	data, res, err := deviceDriver.MapMemory(ring.memory, 0, size, 0)
+	if res != core1_0.VKSuccess || err != nil {
+		ring.Destroy()
+		return nil, vkerror.New("vkMapMemory", "Uniform Ring", res, err)
+	}
+	ring.mapped = unsafe.Slice((*byte)(data), size)
+
+	return ring, nil
+}
+
+func alignUp(value int, alignment int) int {
+	return (value + alignment - 1) / alignment * alignment
+}
+
+// Buffer is the buffer every allocation lives in
+func (r *Ring) Buffer() core1_0.Buffer {
+	return r.buffer
+}
+
+// Alignment is the alignment of every allocation's Offset
+func (r *Ring) Alignment() int {
+	return r.alignment
+}
+
+// BufferInfo describes a window of size bytes at the start of the buffer, for binding to
+// a dynamic uniform or storage buffer descriptor. The dynamic offset of each allocation
+// then slides the window over it.
+func (r *Ring) BufferInfo(size int) core1_0.DescriptorBufferInfo {
+	return core1_0.DescriptorBufferInfo{
+		Buffer: r.buffer,
+		Offset: 0,
+		Range:  size,
+	}
+}
+
+// Allocate reserves size bytes. If the ring is full, it waits for the oldest frame still
+// in flight to finish.
+func (r *Ring) Allocate(size int) (Allocation, error) {
+	if uint64(size) > r.capacity {
+		return Allocation{}, errors.Errorf("%d byte allocation is larger than the %d byte ring", size, r.capacity)
+	}
+
+	alignment := uint64(r.alignment)
+	start := (r.head + alignment - 1) / alignment * alignment
+	if start%r.capacity+uint64(size) > r.capacity {
+		// Skip the end of the buffer rather than splitting the allocation
+		start += r.capacity - start%r.capacity
+	}
+	end := start + uint64(size)
+
+	for end-r.tail > r.capacity {
+		if len(r.inFlight) == 0 {
+			return Allocation{}, errors.Errorf("frame has used all %d bytes of the ring", r.capacity)
+		}
+
+		oldest := r.inFlight[0]
+		if oldest.counter != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), FenceTimeout)
+			_, err := oldest.counter.Wait(ctx, oldest.counterFrame)
+			cancel()
+			if err != nil {
+				return Allocation{}, errors.Wrap(err, "waiting for a frame to release ring space")
+			}
+			r.retireThrough(0)
+			continue
+		}
+
+		res, err := r.deviceDriver.WaitForFences(true, FenceTimeout, oldest.fence)
+		if err != nil {
+			return Allocation{}, errors.Wrap(err, "waiting for a frame to release ring space")
+		} else if res != core1_0.VKSuccess {
+			return Allocation{}, errors.Errorf("waiting for a frame to release ring space: %s", res)
+		}
+		r.retireThrough(0)
+	}
+
+	r.head = end
+	offset := int(start % r.capacity)
+	return Allocation{
+		Offset: offset,
+		Data:   r.mapped[offset : offset+size],
+	}, nil
+}
+
+// Push allocates space for value and encodes it with the std140 rules uniform blocks
+// use, returning the allocation
+func (r *Ring) Push(value any) (Allocation, error) {
+	layout, err := blocklayout.TypeOf(blocklayout.Std140, value)
+	if err != nil {
+		return Allocation{}, err
+	}
+
+	allocation, err := r.Allocate(layout.Size)
+	if err != nil {
+		return allocation, err
+	}
+
+	err = layout.EncodeInto(allocation.Data, value)
+	return allocation, err
+}
+
+// EndFrame marks the end of the current frame's allocations, which stay reserved until
+// fence is signaled
+func (r *Ring) EndFrame(fence core1_0.Fence) {
+	r.inFlight = append(r.inFlight, frame{end: r.head, fence: fence})
+}
+
+// Retire tells the ring that fence has been waited on, freeing the frame it was passed to
+// EndFrame with and every frame before it. Call it after waiting on a frame's fence and
+// before resetting it, so the ring never waits on a fence that was reset.
+func (r *Ring) Retire(fence core1_0.Fence) {
+	for frameIndex, inFlight := range r.inFlight {
+		if inFlight.fence.Handle() == fence.Handle() {
+			r.retireThrough(frameIndex)
+			return
+		}
+	}
+}
+
+// EndFrameCounter marks the end of the current frame's allocations, which stay reserved
+// until counter completes number, the frame number its Submit returned
+func (r *Ring) EndFrameCounter(counter *framesync.Counter, number uint64) {
+	r.inFlight = append(r.inFlight, frame{end: r.head, counter: counter, counterFrame: number})
+}
+
+// RetireCompleted frees every frame passed to EndFrameCounter whose number is at most
+// completed, usually the counter's Completed value. Unlike Retire, it can be called at
+// any time, not just after waiting on a particular frame.
+func (r *Ring) RetireCompleted(completed uint64) {
+	last := -1
+	for frameIndex, inFlight := range r.inFlight {
+		if inFlight.counter == nil || inFlight.counterFrame > completed {
+			break
+		}
+		last = frameIndex
+	}
+
+	if last >= 0 {
+		r.retireThrough(last)
+	}
+}
+
+func (r *Ring) retireThrough(frameIndex int) {
+	r.tail = r.inFlight[frameIndex].end
+	r.inFlight = r.inFlight[frameIndex+1:]
+}
+
+// Destroy unmaps and destroys the ring. The GPU must be done with every frame.
+func (r *Ring) Destroy() {
+	if r.mapped != nil {
+		r.deviceDriver.UnmapMemory(r.memory)
+		r.mapped = nil
+	}
+	r.deviceDriver.DestroyBuffer(r.buffer, nil)
+	r.deviceDriver.FreeMemory(r.memory, nil)
+}
@@ -0,0 +1,290 @@
+package upload
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+)
+
+var trivialTypes sync.Map
+
+// IsTrivial reports whether values of goType can be copied into mapped memory byte for
+// byte: fixed-size numbers and bools, and arrays and structs of them. Struct padding is
+// copied along with the fields, so the data keeps Go's memory layout, which is the layout
+// vertexinput derives strides and offsets from. Types that also hold int, uint or
+// uintptr fall back to a reflective encoder that writes the same layout.
+func IsTrivial(goType reflect.Type) bool {
+	cached, found := trivialTypes.Load(goType)
+	if found {
+		return cached.(bool)
+	}
+
+	trivial := isTrivial(goType)
+	trivialTypes.Store(goType, trivial)
+	return trivial
+}
+
+func isTrivial(goType reflect.Type) bool {
+	switch goType.Kind() {
+	case reflect.Bool,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isTrivial(goType.Elem())
+	case reflect.Struct:
+		for fieldIndex := 0; fieldIndex < goType.NumField(); fieldIndex++ {
+			if !isTrivial(goType.Field(fieldIndex).Type) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// int, uint, pointers, slices, strings and the like have no fixed layout
+	return false
+}
+
+// checkEncodable returns an error if values of goType can't be uploaded at all. Types
+// that aren't trivial only because they hold int, uint or uintptr are encoded instead.
+func checkEncodable(goType reflect.Type) error {
+	switch goType.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return nil
+	case reflect.Array:
+		return checkEncodable(goType.Elem())
+	case reflect.Struct:
+		for fieldIndex := 0; fieldIndex < goType.NumField(); fieldIndex++ {
+			err := checkEncodable(goType.Field(fieldIndex).Type)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if isTrivial(goType) {
+		return nil
+	}
+	return errors.Errorf("%s has no fixed memory layout to upload; uniform data can be laid out with CopyBlock", goType)
+}
+
+// Size is the number of bytes Copy or CopyValue writes for data, as accepted by CopyValue
+func Size(data any) (int, error) {
+	value, err := dereference(data)
+	if err != nil {
+		return 0, err
+	}
+
+	goType := value.Type()
+	count := 1
+	if value.Kind() == reflect.Slice {
+		goType = goType.Elem()
+		count = value.Len()
+	}
+
+	if !IsTrivial(goType) {
+		err = checkEncodable(goType)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count * int(goType.Size()), nil
+}
+
+// Copy copies data into dst, which is usually mapped memory, and returns the number of
+// bytes written. Trivial types are copied byte for byte; others are encoded field by
+// field into the same layout, which is much slower.
+func Copy[T any](dst []byte, data []T) (int, error) {
+	goType := reflect.TypeFor[T]()
+	if !IsTrivial(goType) {
+		slice := reflect.ValueOf(data)
+		return encodeValues(dst, goType, len(data), slice.Index)
+	}
+
+	return copyBytes(dst, unsafe.Pointer(unsafe.SliceData(data)), len(data)*int(goType.Size()))
+}
+
+// CopyValue is Copy for data whose type is only known at runtime. data may be a slice, an
+// array, a single value, or a pointer to any of them.
+func CopyValue(dst []byte, data any) (int, error) {
+	value, err := dereference(data)
+	if err != nil {
+		return 0, err
+	}
+
+	if value.Kind() == reflect.Slice {
+		elemType := value.Type().Elem()
+		if !IsTrivial(elemType) {
+			return encodeValues(dst, elemType, value.Len(), value.Index)
+		}
+		return copyBytes(dst, value.UnsafePointer(), value.Len()*int(elemType.Size()))
+	}
+
+	if !IsTrivial(value.Type()) {
+		return encodeValues(dst, value.Type(), 1, func(int) reflect.Value { return value })
+	}
+
+	if !value.CanAddr() {
+		// Copy the value somewhere it can be read from directly
+		addressable := reflect.New(value.Type()).Elem()
+		addressable.Set(value)
+		value = addressable
+	}
+	return copyBytes(dst, value.Addr().UnsafePointer(), int(value.Type().Size()))
+}
+
+// CopyBlock lays value out into dst under a shader block layout such as std140, and
+// returns the size of the block. Use it for uniform and storage data, whose layout
+// differs from Go's.
+func CopyBlock(dst []byte, rules blocklayout.Rules, value any) (int, error) {
+	layout, err := blocklayout.TypeOf(rules, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return layout.Size, layout.EncodeInto(dst, value)
+}
+
+// encodeValues is the slow path of Copy and CopyValue, for types that aren't trivial. It
+// writes count values of goType, each returned by element, in Go's memory layout with
+// zeroed padding, reading every field through reflection.
+func encodeValues(dst []byte, goType reflect.Type, count int, element func(index int) reflect.Value) (int, error) {
+	err := checkEncodable(goType)
+	if err != nil {
+		return 0, err
+	}
+
+	stride := int(goType.Size())
+	size := count * stride
+	if len(dst) < size {
+		return 0, errors.Errorf("%d bytes is too small to hold %d bytes of data", len(dst), size)
+	}
+
+	clear(dst[:size])
+	for index := 0; index < count; index++ {
+		encode(dst[index*stride:], element(index))
+	}
+	return size, nil
+}
+
+// encode writes value at the start of dst. int, uint and uintptr are written at their
+// size on this platform.
+func encode(dst []byte, value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			dst[0] = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		putUint(dst, uint64(value.Int()), value.Type().Size())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		putUint(dst, value.Uint(), value.Type().Size())
+	case reflect.Float32:
+		common.ByteOrder.PutUint32(dst, math.Float32bits(float32(value.Float())))
+	case reflect.Float64:
+		common.ByteOrder.PutUint64(dst, math.Float64bits(value.Float()))
+	case reflect.Complex64:
+		common.ByteOrder.PutUint32(dst, math.Float32bits(float32(real(value.Complex()))))
+		common.ByteOrder.PutUint32(dst[4:], math.Float32bits(float32(imag(value.Complex()))))
+	case reflect.Complex128:
+		common.ByteOrder.PutUint64(dst, math.Float64bits(real(value.Complex())))
+		common.ByteOrder.PutUint64(dst[8:], math.Float64bits(imag(value.Complex())))
+	case reflect.Array:
+		stride := int(value.Type().Elem().Size())
+		for index := 0; index < value.Len(); index++ {
+			encode(dst[index*stride:], value.Index(index))
+		}
+	case reflect.Struct:
+		for fieldIndex := 0; fieldIndex < value.NumField(); fieldIndex++ {
+			encode(dst[value.Type().Field(fieldIndex).Offset:], value.Field(fieldIndex))
+		}
+	}
+}
+
+func putUint(dst []byte, value uint64, size uintptr) {
+	switch size {
+	case 1:
+		dst[0] = byte(value)
+	case 2:
+		common.ByteOrder.PutUint16(dst, uint16(value))
+	case 4:
+		common.ByteOrder.PutUint32(dst, uint32(value))
+	default:
+		common.ByteOrder.PutUint64(dst, value)
+	}
+}
+
+func dereference(data any) (reflect.Value, error) {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return value, errors.Errorf("cannot upload nil %T", data)
+		}
+		value = value.Elem()
+	}
+
+	if !value.IsValid() {
+		return value, errors.New("cannot upload nil")
+	}
+	return value, nil
+}
+
+func copyBytes(dst []byte, src unsafe.Pointer, size int) (int, error) {
+	if len(dst) < size {
+		return 0, errors.Errorf("%d bytes is too small to hold %d bytes of data", len(dst), size)
+	}
+
+	if size > 0 {
+		copy(dst, unsafe.Slice((*byte)(src), size))
+	}
+	return size, nil
+}
+
+// Write maps the range of memory at offset that data will fill, copies data into it, and
+// unmaps the memory again
+func Write[T any](driver core1_0.DeviceDriver, memory core1_0.DeviceMemory, offset int, data []T) error {
+	size, err := Size(data)
+	if err != nil {
+		return err
+	}
+
+	return mapAndCopy(driver, memory, offset, size, func(dst []byte) (int, error) {
+		return Copy(dst, data)
+	})
+}
+
+// WriteValue is Write for data whose type is only known at runtime, as accepted by
+// CopyValue
+func WriteValue(driver core1_0.DeviceDriver, memory core1_0.DeviceMemory, offset int, data any) error {
+	size, err := Size(data)
+	if err != nil {
+		return err
+	}
+
+	return mapAndCopy(driver, memory, offset, size, func(dst []byte) (int, error) {
+		return CopyValue(dst, data)
+	})
+}
+
+func mapAndCopy(driver core1_0.DeviceDriver, memory core1_0.DeviceMemory, offset int, size int, copyInto func(dst []byte) (int, error)) error {
+	if size == 0 {
+		return nil
+	}
+
+	memoryPtr, _, err := driver.MapMemory(memory, offset, size, 0)
+	if err != nil {
+		return err
+	}
+	defer driver.UnmapMemory(memory)
+
+	_, err = copyInto(unsafe.Slice((*byte)(memoryPtr), size))
+	return err
+}
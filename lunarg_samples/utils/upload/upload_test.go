@@ -0,0 +1,277 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+)
+
+type vertex struct {
+	Position [4]float32
+	Color    [4]float32
+}
+
+type paddedVertex struct {
+	Position [3]float32
+	Flags    uint8
+	Color    [4]float32
+}
+
+const meshVertices = 1 << 20
+
+func TestIsTrivial(t *testing.T) {
+	tests := []struct {
+		name    string
+		goType  reflect.Type
+		trivial bool
+	}{
+		{"float32", reflect.TypeFor[float32](), true},
+		{"array", reflect.TypeFor[[4]uint16](), true},
+		{"struct", reflect.TypeFor[vertex](), true},
+		{"padded struct", reflect.TypeFor[paddedVertex](), true},
+		{"int", reflect.TypeFor[int](), false},
+		{"pointer", reflect.TypeFor[*float32](), false},
+		{"slice field", reflect.TypeFor[struct{ Values []float32 }](), false},
+		{"string field", reflect.TypeFor[struct{ Name string }](), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsTrivial(test.goType); got != test.trivial {
+				t.Errorf("IsTrivial(%s) = %v, want %v", test.goType, got, test.trivial)
+			}
+		})
+	}
+}
+
+func TestCopyKeepsGoLayout(t *testing.T) {
+	data := []paddedVertex{
+		{Position: [3]float32{1, 2, 3}, Flags: 7, Color: [4]float32{4, 5, 6, 7}},
+		{Position: [3]float32{8, 9, 10}, Flags: 1, Color: [4]float32{11, 12, 13, 14}},
+	}
+	stride := int(unsafe.Sizeof(paddedVertex{}))
+
+	dst := make([]byte, len(data)*stride)
+	written, err := Copy(dst, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(dst) {
+		t.Fatalf("wrote %d bytes, want %d", written, len(dst))
+	}
+
+	colorOffset := int(unsafe.Offsetof(paddedVertex{}.Color))
+	for index, want := range data {
+		base := dst[index*stride:]
+		if base[unsafe.Offsetof(paddedVertex{}.Flags)] != want.Flags {
+			t.Errorf("vertex %d flags are %d, want %d", index, base[unsafe.Offsetof(paddedVertex{}.Flags)], want.Flags)
+		}
+
+		for component, value := range want.Color {
+			got := common.ByteOrder.Uint32(base[colorOffset+component*4:])
+			if got != *(*uint32)(unsafe.Pointer(&value)) {
+				t.Errorf("vertex %d color %d was not copied at its Go offset", index, component)
+			}
+		}
+	}
+}
+
+func TestCopyValue(t *testing.T) {
+	value := vertex{Position: [4]float32{1, 2, 3, 4}}
+	for _, data := range []any{value, &value, []vertex{value}, [1]vertex{value}} {
+		size, err := Size(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := make([]byte, size)
+		written, err := CopyValue(dst, data)
+		if err != nil {
+			t.Fatalf("%T: %v", data, err)
+		}
+		if written != int(unsafe.Sizeof(value)) || !bytes.Equal(dst, unsafe.Slice((*byte)(unsafe.Pointer(&value)), unsafe.Sizeof(value))) {
+			t.Errorf("%T was not copied byte for byte", data)
+		}
+	}
+}
+
+func TestCopyRejects(t *testing.T) {
+	dst := make([]byte, 64)
+	if _, err := CopyValue(dst, []*float32{nil}); err == nil {
+		t.Error("copied a slice of pointers")
+	}
+	if _, err := CopyValue(dst, struct{ Name string }{"x"}); err == nil {
+		t.Error("copied a struct with a string")
+	}
+	if _, err := CopyValue(dst, (*vertex)(nil)); err == nil {
+		t.Error("copied a nil pointer")
+	}
+	if _, err := Copy(dst[:8], []vertex{{}}); err == nil {
+		t.Error("copied into a destination that is too small")
+	}
+}
+
+// indexedVertex isn't trivial because of its int field, so it is encoded reflectively
+type indexedVertex struct {
+	Position [3]float32
+	Flags    uint8
+	Index    int
+}
+
+func TestCopyEncodesNonTrivialTypes(t *testing.T) {
+	data := []indexedVertex{
+		{Position: [3]float32{1, 2, 3}, Flags: 7, Index: -2},
+		{Position: [3]float32{4, 5, 6}, Flags: 1, Index: 1 << 40},
+	}
+	stride := int(unsafe.Sizeof(indexedVertex{}))
+
+	size, err := Size(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != len(data)*stride {
+		t.Fatalf("size is %d, want %d", size, len(data)*stride)
+	}
+
+	for _, copyInto := range []func(dst []byte) (int, error){
+		func(dst []byte) (int, error) { return Copy(dst, data) },
+		func(dst []byte) (int, error) { return CopyValue(dst, &data) },
+	} {
+		dst := bytes.Repeat([]byte{0xff}, size)
+		written, err := copyInto(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written != size {
+			t.Fatalf("wrote %d bytes, want %d", written, size)
+		}
+
+		// Decoding the bytes in place gives back the values, padding and all
+		decoded := unsafe.Slice((*indexedVertex)(unsafe.Pointer(&dst[0])), len(data))
+		for index, want := range data {
+			if decoded[index] != want {
+				t.Errorf("vertex %d decoded as %+v, want %+v", index, decoded[index], want)
+			}
+		}
+
+		paddingOffset := int(unsafe.Offsetof(indexedVertex{}.Flags)) + 1
+		if dst[paddingOffset] != 0 {
+			t.Error("padding was not zeroed")
+		}
+	}
+
+	single := make([]byte, stride)
+	_, err = CopyValue(single, data[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *(*indexedVertex)(unsafe.Pointer(&single[0])) != data[1] {
+		t.Error("a single non-trivial value was not encoded")
+	}
+
+	if _, err := Copy(make([]byte, stride), data); err == nil {
+		t.Error("encoded into a destination that is too small")
+	}
+}
+
+func TestCopyBlock(t *testing.T) {
+	type block struct {
+		Scale  float32
+		Offset [3]float32
+	}
+
+	dst := make([]byte, 64)
+	size, err := CopyBlock(dst, blocklayout.Std140, block{Scale: 2, Offset: [3]float32{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Array elements are 16 bytes apart in std140, where Go packs them
+	if size != 64 {
+		t.Errorf("block size is %d, want 64", size)
+	}
+	for index, want := range []float32{1, 2, 3} {
+		if !bytes.Equal(dst[16+index*16:20+index*16], float32Bytes(want)) {
+			t.Errorf("Offset[%d] was not laid out at %d", index, 16+index*16)
+		}
+	}
+}
+
+func float32Bytes(value float32) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(&value)), 4)
+}
+
+func benchmarkMesh() []vertex {
+	vertices := make([]vertex, meshVertices)
+	for index := range vertices {
+		vertices[index].Position = [4]float32{float32(index), 1, 2, 1}
+		vertices[index].Color = [4]float32{1, 0, 0, 1}
+	}
+	return vertices
+}
+
+// BenchmarkCopy uploads a 1M-vertex mesh with Copy
+func BenchmarkCopy(b *testing.B) {
+	vertices := benchmarkMesh()
+	dst := make([]byte, len(vertices)*int(unsafe.Sizeof(vertex{})))
+	b.SetBytes(int64(len(dst)))
+
+	for b.Loop() {
+		_, err := Copy(dst, vertices)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyValue uploads a 1M-vertex mesh with CopyValue
+func BenchmarkCopyValue(b *testing.B) {
+	vertices := benchmarkMesh()
+	dst := make([]byte, len(vertices)*int(unsafe.Sizeof(vertex{})))
+	b.SetBytes(int64(len(dst)))
+
+	for b.Loop() {
+		_, err := CopyValue(dst, vertices)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyEncoded uploads a 1M-vertex mesh through the reflective encoder that Copy
+// falls back to for types that aren't trivial
+func BenchmarkCopyEncoded(b *testing.B) {
+	vertices := benchmarkMesh()
+	dst := make([]byte, len(vertices)*int(unsafe.Sizeof(vertex{})))
+	b.SetBytes(int64(len(dst)))
+
+	slice := reflect.ValueOf(vertices)
+	for b.Loop() {
+		_, err := encodeValues(dst, slice.Type().Elem(), slice.Len(), slice.Index)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBinaryWrite uploads a 1M-vertex mesh the way the samples did before Copy, for
+// comparison
+func BenchmarkBinaryWrite(b *testing.B) {
+	vertices := benchmarkMesh()
+	dst := make([]byte, len(vertices)*int(unsafe.Sizeof(vertex{})))
+	b.SetBytes(int64(len(dst)))
+
+	for b.Loop() {
+		buffer := &bytes.Buffer{}
+		err := binary.Write(buffer, common.ByteOrder, vertices)
+		if err != nil {
+			b.Fatal(err)
+		}
+		copy(dst, buffer.Bytes())
+	}
+}
@@ -0,0 +1,187 @@
+package validation
+
+import (
+	"unsafe"
+
+	"github.com/CannibalVox/cgoparam"
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+)
+
+// The vulkan headers bundled with core predate VK_EXT_layer_settings, so the structures
+// are laid out by hand here
+
+const structureTypeLayerSettingsCreateInfo uint32 = 1000496000
+
+type LayerSettingType uint32
+
+const (
+	LayerSettingTypeBool32 LayerSettingType = iota
+	LayerSettingTypeInt32
+	LayerSettingTypeInt64
+	LayerSettingTypeUInt32
+	LayerSettingTypeUInt64
+	LayerSettingTypeFloat32
+	LayerSettingTypeFloat64
+	LayerSettingTypeString
+)
+
+type vkLayerSettingsCreateInfo struct {
+	sType        uint32
+	pNext        unsafe.Pointer
+	settingCount uint32
+	pSettings    unsafe.Pointer
+}
+
+type vkLayerSetting struct {
+	pLayerName   unsafe.Pointer
+	pSettingName unsafe.Pointer
+	settingType  uint32
+	valueCount   uint32
+	pValues      unsafe.Pointer
+}
+
+// LayerSetting is a single named setting for a layer. Values must be a slice of the Go
+// type matching Type: []bool, []int32, []int64, []uint32, []uint64, []float32, []float64
+// or []string
+type LayerSetting struct {
+	LayerName   string
+	SettingName string
+	Type        LayerSettingType
+	Values      any
+}
+
+func BoolSetting(layerName, settingName string, value bool) LayerSetting {
+	return LayerSetting{LayerName: layerName, SettingName: settingName, Type: LayerSettingTypeBool32, Values: []bool{value}}
+}
+
+func StringSetting(layerName, settingName string, values ...string) LayerSetting {
+	return LayerSetting{LayerName: layerName, SettingName: settingName, Type: LayerSettingTypeString, Values: values}
+}
+
+func UInt32Setting(layerName, settingName string, values ...uint32) LayerSetting {
+	return LayerSetting{LayerName: layerName, SettingName: settingName, Type: LayerSettingTypeUInt32, Values: values}
+}
+
+// LayerSettingsCreateInfo is VkLayerSettingsCreateInfoEXT, chained onto InstanceCreateInfo
+type LayerSettingsCreateInfo struct {
+	Settings []LayerSetting
+
+	common.NextOptions
+}
+
+func (o LayerSettingsCreateInfo) PopulateCPointer(allocator *cgoparam.Allocator, preallocatedPointer unsafe.Pointer, next unsafe.Pointer) (unsafe.Pointer, error) {
+	if preallocatedPointer == nil {
+		preallocatedPointer = allocator.Malloc(int(unsafe.Sizeof(vkLayerSettingsCreateInfo{})))
+	}
+
+	createInfo := (*vkLayerSettingsCreateInfo)(preallocatedPointer)
+	createInfo.sType = structureTypeLayerSettingsCreateInfo
+	createInfo.pNext = next
+	createInfo.settingCount = uint32(len(o.Settings))
+	createInfo.pSettings = nil
+
+	if len(o.Settings) == 0 {
+		return preallocatedPointer, nil
+	}
+
+	settingsPtr := allocator.Malloc(len(o.Settings) * int(unsafe.Sizeof(vkLayerSetting{})))
+	settings := unsafe.Slice((*vkLayerSetting)(settingsPtr), len(o.Settings))
+
+	for settingIndex, setting := range o.Settings {
+		valueCount, values, err := setting.allocValues(allocator)
+		if err != nil {
+			return nil, err
+		}
+
+		settings[settingIndex] = vkLayerSetting{
+			pLayerName:   allocator.CString(setting.LayerName),
+			pSettingName: allocator.CString(setting.SettingName),
+			settingType:  uint32(setting.Type),
+			valueCount:   uint32(valueCount),
+			pValues:      values,
+		}
+	}
+	createInfo.pSettings = settingsPtr
+
+	return preallocatedPointer, nil
+}
+
+func allocSlice[T any](allocator *cgoparam.Allocator, values []T) (int, unsafe.Pointer) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	var zero T
+	ptr := allocator.Malloc(len(values) * int(unsafe.Sizeof(zero)))
+	copy(unsafe.Slice((*T)(ptr), len(values)), values)
+
+	return len(values), ptr
+}
+
+func (s LayerSetting) allocValues(allocator *cgoparam.Allocator) (int, unsafe.Pointer, error) {
+	switch values := s.Values.(type) {
+	case []bool:
+		if s.Type != LayerSettingTypeBool32 {
+			break
+		}
+
+		bools := make([]uint32, len(values))
+		for index, value := range values {
+			if value {
+				bools[index] = 1
+			}
+		}
+		count, ptr := allocSlice(allocator, bools)
+		return count, ptr, nil
+	case []int32:
+		if s.Type != LayerSettingTypeInt32 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []int64:
+		if s.Type != LayerSettingTypeInt64 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []uint32:
+		if s.Type != LayerSettingTypeUInt32 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []uint64:
+		if s.Type != LayerSettingTypeUInt64 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []float32:
+		if s.Type != LayerSettingTypeFloat32 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []float64:
+		if s.Type != LayerSettingTypeFloat64 {
+			break
+		}
+		count, ptr := allocSlice(allocator, values)
+		return count, ptr, nil
+	case []string:
+		if s.Type != LayerSettingTypeString {
+			break
+		}
+
+		strings := make([]unsafe.Pointer, len(values))
+		for index, value := range values {
+			strings[index] = allocator.CString(value)
+		}
+		count, ptr := allocSlice(allocator, strings)
+		return count, ptr, nil
+	}
+
+	return 0, nil, errors.Errorf("layer setting %s.%s: values of type %T do not match setting type %d", s.LayerName, s.SettingName, s.Values, s.Type)
+}
@@ -0,0 +1,240 @@
+package validation
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/core/v3/loader"
+	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
+)
+
+const defaultRetainedFrames = 16
+
+var (
+	stagePattern     = regexp.MustCompile(`Stage = ([A-Za-z]+)`)
+	drawIndexPattern = regexp.MustCompile(`Draw Index (0x[0-9a-fA-F]+|\d+)`)
+	// numberPattern finds number candidates; parseValues drops the ones that are part of
+	// an identifier like vec4 or buffer2, which RE2 has no lookbehind to rule out
+	numberPattern = regexp.MustCompile(`0x[0-9a-fA-F]+|[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+)
+
+// PrintfMessage is a single line of debugPrintfEXT output from a shader
+type PrintfMessage struct {
+	Frame uint64
+	// Stage is the shader stage that produced the message, if the layer reported one
+	Stage string
+	// DrawIndex is the index of the draw or dispatch within its command buffer, or -1
+	// if the layer did not report one
+	DrawIndex int
+	// Text is the formatted printf output, without any of the layer's decoration
+	Text string
+	// Values contains every number that appears in Text, in order
+	Values []float64
+}
+
+// IsPrintfMessage reports whether a debug messenger callback came from the validation
+// layer's debug printf implementation
+func IsPrintfMessage(data *ext_debug_utils.DebugUtilsMessengerCallbackData) bool {
+	return strings.Contains(strings.ToUpper(data.MessageIDName), "DEBUG-PRINTF")
+}
+
+// ParsePrintfMessage extracts the shader output from a debug printf callback. The stage
+// and draw index are only available when the layer is emitting verbose messages.
+func ParsePrintfMessage(data *ext_debug_utils.DebugUtilsMessengerCallbackData) (PrintfMessage, bool) {
+	if !IsPrintfMessage(data) {
+		return PrintfMessage{}, false
+	}
+
+	message := data.Message
+	// Older layers prefix the message with the object list and message ID
+	if idIndex := strings.LastIndex(message, "| MessageID = "); idIndex >= 0 {
+		if separator := strings.Index(message[idIndex+1:], "|"); separator >= 0 {
+			message = message[idIndex+1+separator+1:]
+		}
+	}
+
+	parsed := PrintfMessage{
+		DrawIndex: -1,
+	}
+
+	if match := stagePattern.FindStringSubmatch(message); match != nil {
+		parsed.Stage = match[1]
+	}
+
+	if match := drawIndexPattern.FindStringSubmatch(message); match != nil {
+		drawIndex, err := strconv.ParseInt(match[1], 0, 64)
+		if err == nil {
+			parsed.DrawIndex = int(drawIndex)
+		}
+	}
+
+	// In verbose mode the layer's description comes first and the shader's output is
+	// on the final line
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	parsed.Text = strings.TrimSpace(lines[len(lines)-1])
+	parsed.Values = parseValues(parsed.Text)
+
+	return parsed, true
+}
+
+func parseValues(text string) []float64 {
+	var values []float64
+
+	for _, match := range numberPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		if start > 0 && isIdentifierByte(text[start-1]) || end < len(text) && isIdentifierByte(text[end]) {
+			continue
+		}
+
+		token := text[start:end]
+		if strings.HasPrefix(token, "0x") {
+			value, err := strconv.ParseUint(token[2:], 16, 64)
+			if err == nil {
+				values = append(values, float64(value))
+			}
+			continue
+		}
+
+		value, err := strconv.ParseFloat(token, 64)
+		if err == nil {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// PrintfCapture collects debug printf output by frame.  Its Callback method should be used
+// as the UserCallback of a debug messenger; messages which did not come from debug printf
+// are passed to Forward. Printf output is delivered when the layer processes a completed
+// submission, which may be well after the frame that recorded it, so command buffers
+// should be registered with TrackCommandBuffers to attribute output correctly.
+type PrintfCapture struct {
+	Forward ext_debug_utils.CallbackFunction
+	// Logger receives every printf message as it arrives, if set
+	Logger *log.Logger
+	// RetainedFrames is the number of frames of output kept before the oldest is discarded
+	RetainedFrames int
+
+	lock           sync.Mutex
+	currentFrame   uint64
+	frames         map[uint64][]PrintfMessage
+	commandBuffers map[loader.VulkanHandle]uint64
+}
+
+func (c *PrintfCapture) Callback(msgType ext_debug_utils.DebugUtilsMessageTypeFlags, severity ext_debug_utils.DebugUtilsMessageSeverityFlags, data *ext_debug_utils.DebugUtilsMessengerCallbackData) bool {
+	message, isPrintf := ParsePrintfMessage(data)
+	if !isPrintf {
+		if c.Forward != nil {
+			return c.Forward(msgType, severity, data)
+		}
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	message.Frame = c.currentFrame
+	for _, object := range data.Objects {
+		if object.ObjectType != core1_0.ObjectTypeCommandBuffer {
+			continue
+		}
+
+		frame, tracked := c.commandBuffers[object.ObjectHandle]
+		if tracked {
+			message.Frame = frame
+			break
+		}
+	}
+
+	if c.frames == nil {
+		c.frames = make(map[uint64][]PrintfMessage)
+	}
+	c.frames[message.Frame] = append(c.frames[message.Frame], message)
+
+	if c.Logger != nil {
+		c.Logger.Printf("[printf frame %d, %s, draw %d] %s", message.Frame, message.Stage, message.DrawIndex, message.Text)
+	}
+
+	return false
+}
+
+// BeginFrame sets the frame that untracked output is attributed to and discards output
+// that has aged out
+func (c *PrintfCapture) BeginFrame(frame uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.currentFrame = frame
+
+	retained := uint64(c.RetainedFrames)
+	if retained == 0 {
+		retained = defaultRetainedFrames
+	}
+	if frame < retained {
+		return
+	}
+
+	for oldFrame := range c.frames {
+		if oldFrame <= frame-retained {
+			delete(c.frames, oldFrame)
+		}
+	}
+
+	for buffer, oldFrame := range c.commandBuffers {
+		if oldFrame <= frame-retained {
+			delete(c.commandBuffers, buffer)
+		}
+	}
+}
+
+// TrackCommandBuffers attributes any printf output produced by the provided command
+// buffers to frame, until they are tracked again for a later frame
+func (c *PrintfCapture) TrackCommandBuffers(frame uint64, commandBuffers ...core1_0.CommandBuffer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.commandBuffers == nil {
+		c.commandBuffers = make(map[loader.VulkanHandle]uint64)
+	}
+
+	for _, commandBuffer := range commandBuffers {
+		c.commandBuffers[loader.VulkanHandle(commandBuffer.Handle())] = frame
+	}
+}
+
+// Frame returns a copy of the output captured for a frame so far
+func (c *PrintfCapture) Frame(frame uint64) []PrintfMessage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	messages := make([]PrintfMessage, len(c.frames[frame]))
+	copy(messages, c.frames[frame])
+	return messages
+}
+
+// TakeFrame returns the output captured for a frame and stops retaining it
+func (c *PrintfCapture) TakeFrame(frame uint64) []PrintfMessage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	messages := c.frames[frame]
+	delete(c.frames, frame)
+	return messages
+}
+
+func (c *PrintfCapture) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.frames = nil
+	c.commandBuffers = nil
+}
@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
+)
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		text   string
+		values []float64
+	}{
+		{"position 1.5 -2 3e2", []float64{1.5, -2, 300}},
+		{"id 0x1F", []float64{31}},
+		{"vec4(0.25, .5, 1., +4)", []float64{0.25, 0.5, 1, 4}},
+		{"buffer2 = 7", []float64{7}},
+		{"mat4x4 f32vec3", nil},
+		{"index_3 is 9", []float64{9}},
+		{"2nd pass", nil},
+		{"x=-1,y=2", []float64{-1, 2}},
+		{"value: 1e-3;", []float64{0.001}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			values := parseValues(test.text)
+			if !slices.Equal(values, test.values) {
+				t.Errorf("parseValues(%q) = %v, want %v", test.text, values, test.values)
+			}
+		})
+	}
+}
+
+func TestParsePrintfMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      ext_debug_utils.DebugUtilsMessengerCallbackData
+		isPrintf  bool
+		stage     string
+		drawIndex int
+		text      string
+		values    []float64
+	}{
+		{
+			name: "plain",
+			data: ext_debug_utils.DebugUtilsMessengerCallbackData{
+				MessageIDName: "WARNING-DEBUG-PRINTF",
+				Message:       "color vec4 0.5 1",
+			},
+			isPrintf:  true,
+			drawIndex: -1,
+			text:      "color vec4 0.5 1",
+			values:    []float64{0.5, 1},
+		},
+		{
+			name: "verbose",
+			data: ext_debug_utils.DebugUtilsMessengerCallbackData{
+				MessageIDName: "UNASSIGNED-DEBUG-PRINTF",
+				Message: "Validation Information: [ UNASSIGNED-DEBUG-PRINTF ] | MessageID = 0x4fe1fef9 | " +
+					"Command buffer (0x1)(Draw Buffer 0). Draw Index 0x3. Pipeline (0x2). Shader Module (0x3). " +
+					"Shader Instruction Index = 42. Stage = Fragment. Fragment coord (x,y) = (1.5, 2.5).\n" +
+					"uv2 = 0.25",
+			},
+			isPrintf:  true,
+			stage:     "Fragment",
+			drawIndex: 3,
+			text:      "uv2 = 0.25",
+			values:    []float64{0.25},
+		},
+		{
+			name: "not printf",
+			data: ext_debug_utils.DebugUtilsMessengerCallbackData{
+				MessageIDName: "VUID-vkCmdDraw-None-02699",
+				Message:       "descriptor 0 was never updated",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message, isPrintf := ParsePrintfMessage(&test.data)
+			if isPrintf != test.isPrintf {
+				t.Fatalf("ParsePrintfMessage reported printf %v, want %v", isPrintf, test.isPrintf)
+			}
+			if !isPrintf {
+				return
+			}
+
+			if message.Stage != test.stage || message.DrawIndex != test.drawIndex || message.Text != test.text {
+				t.Errorf("parsed stage %q, draw %d, text %q; want %q, %d, %q",
+					message.Stage, message.DrawIndex, message.Text, test.stage, test.drawIndex, test.text)
+			}
+			if !slices.Equal(message.Values, test.values) {
+				t.Errorf("parsed values %v, want %v", message.Values, test.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/common"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+const (
+	LayerName = "VK_LAYER_KHRONOS_validation"
+
+	LayerSettingsExtensionName      = "VK_EXT_layer_settings"
+	ValidationFeaturesExtensionName = "VK_EXT_validation_features"
+)
+
+// Settings selects optional features of the Khronos validation layer that are normally
+// only reachable through vk_layer_settings.txt or environment variables
+type Settings struct {
+	SynchronizationValidation bool
+	BestPractices             bool
+	GPUAssisted               bool
+	DebugPrintf               bool
+}
+
+// ErrPrintfWithGPUAssisted is returned for Settings that enable both GPU-assisted
+// validation and debug printf, which the validation layer does not allow together
+var ErrPrintfWithGPUAssisted = errors.New("GPU-assisted validation and debug printf cannot be enabled together")
+
+func (s Settings) Enabled() bool {
+	return s.SynchronizationValidation || s.BestPractices || s.GPUAssisted || s.DebugPrintf
+}
+
+// Validate returns an error if the settings combine features the layer can't run together
+func (s Settings) Validate() error {
+	if s.GPUAssisted && s.DebugPrintf {
+		return ErrPrintfWithGPUAssisted
+	}
+
+	return nil
+}
+
+// ParseArg consumes a single command line argument, returning false if the argument
+// isn't one of the validation flags
+func (s *Settings) ParseArg(arg string) bool {
+	switch arg {
+	case "--validate-sync":
+		s.SynchronizationValidation = true
+	case "--validate-best-practices":
+		s.BestPractices = true
+	case "--validate-gpu-assisted":
+		s.GPUAssisted = true
+	case "--debug-printf":
+		s.DebugPrintf = true
+	default:
+		return false
+	}
+
+	return true
+}
+
+func PrintUsage() {
+	fmt.Println("\t--validate-sync")
+	fmt.Println("\t\tEnable synchronization validation in the validation layer")
+	fmt.Println("\t--validate-best-practices")
+	fmt.Println("\t\tEnable best practices checks in the validation layer")
+	fmt.Println("\t--validate-gpu-assisted")
+	fmt.Println("\t\tEnable GPU-assisted validation in the validation layer; not with --debug-printf")
+	fmt.Println("\t--debug-printf")
+	fmt.Println("\t\tEnable shader debugPrintfEXT output through the debug messenger; not with")
+	fmt.Println("\t\t--validate-gpu-assisted")
+}
+
+// InstanceOptions determines which of VK_EXT_layer_settings and VK_EXT_validation_features
+// the validation layer exposes and builds an instance creation chain for these settings
+// in front of next.  The returned extension name must be added to the instance's enabled
+// extensions. It fails if the settings don't pass Validate.
+func (s Settings) InstanceOptions(driver core1_0.GlobalDriver, next common.Options) (string, common.Options, error) {
+	err := s.Validate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	extensions, _, err := driver.AvailableExtensionsForLayer(LayerName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, hasLayerSettings := extensions[LayerSettingsExtensionName]
+	if hasLayerSettings {
+		return LayerSettingsExtensionName, s.layerSettings(next), nil
+	}
+
+	_, hasValidationFeatures := extensions[ValidationFeaturesExtensionName]
+	if hasValidationFeatures {
+		return ValidationFeaturesExtensionName, s.validationFeatures(next), nil
+	}
+
+	return "", nil, errors.Errorf("%s exposes neither %s nor %s", LayerName, LayerSettingsExtensionName, ValidationFeaturesExtensionName)
+}
+
+func (s Settings) layerSettings(next common.Options) LayerSettingsCreateInfo {
+	createInfo := LayerSettingsCreateInfo{
+		NextOptions: common.NextOptions{Next: next},
+	}
+
+	if s.SynchronizationValidation {
+		createInfo.Settings = append(createInfo.Settings, BoolSetting(LayerName, "validate_sync", true))
+	}
+
+	if s.BestPractices {
+		createInfo.Settings = append(createInfo.Settings, BoolSetting(LayerName, "validate_best_practices", true))
+	}
+
+	if s.GPUAssisted {
+		createInfo.Settings = append(createInfo.Settings, BoolSetting(LayerName, "gpuav_enable", true))
+	}
+
+	if s.DebugPrintf {
+		// Send printf output to the debug messenger rather than stdout so that it can be captured,
+		// and include the stage and draw index for PrintfCapture
+		createInfo.Settings = append(createInfo.Settings,
+			BoolSetting(LayerName, "printf_enable", true),
+			BoolSetting(LayerName, "printf_to_stdout", false),
+			BoolSetting(LayerName, "printf_verbose", true),
+		)
+	}
+
+	return createInfo
+}
+
+func (s Settings) validationFeatures(next common.Options) ValidationFeatures {
+	features := ValidationFeatures{
+		NextOptions: common.NextOptions{Next: next},
+	}
+
+	if s.SynchronizationValidation {
+		features.EnabledValidationFeatures = append(features.EnabledValidationFeatures, FeatureEnableSynchronizationValidation)
+	}
+
+	if s.BestPractices {
+		features.EnabledValidationFeatures = append(features.EnabledValidationFeatures, FeatureEnableBestPractices)
+	}
+
+	if s.GPUAssisted {
+		features.EnabledValidationFeatures = append(features.EnabledValidationFeatures, FeatureEnableGPUAssisted)
+	}
+
+	if s.DebugPrintf {
+		features.EnabledValidationFeatures = append(features.EnabledValidationFeatures, FeatureEnableDebugPrintf)
+	}
+
+	return features
+}
@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"unsafe"
+
+	"github.com/CannibalVox/cgoparam"
+	"github.com/vkngwrapper/core/v3/common"
+)
+
+// VK_EXT_validation_features is deprecated in favor of VK_EXT_layer_settings, but older
+// SDKs only expose this one
+
+const structureTypeValidationFeatures uint32 = 1000247000
+
+type ValidationFeatureEnable uint32
+
+const (
+	FeatureEnableGPUAssisted ValidationFeatureEnable = iota
+	FeatureEnableGPUAssistedReserveBindingSlot
+	FeatureEnableBestPractices
+	FeatureEnableDebugPrintf
+	FeatureEnableSynchronizationValidation
+)
+
+type ValidationFeatureDisable uint32
+
+const (
+	FeatureDisableAll ValidationFeatureDisable = iota
+	FeatureDisableShaders
+	FeatureDisableThreadSafety
+	FeatureDisableAPIParameters
+	FeatureDisableObjectLifetimes
+	FeatureDisableCoreChecks
+	FeatureDisableUniqueHandles
+	FeatureDisableShaderValidationCache
+)
+
+type vkValidationFeatures struct {
+	sType                          uint32
+	pNext                          unsafe.Pointer
+	enabledValidationFeatureCount  uint32
+	pEnabledValidationFeatures     unsafe.Pointer
+	disabledValidationFeatureCount uint32
+	pDisabledValidationFeatures    unsafe.Pointer
+}
+
+// ValidationFeatures is VkValidationFeaturesEXT, chained onto InstanceCreateInfo
+type ValidationFeatures struct {
+	EnabledValidationFeatures  []ValidationFeatureEnable
+	DisabledValidationFeatures []ValidationFeatureDisable
+
+	common.NextOptions
+}
+
+func (o ValidationFeatures) PopulateCPointer(allocator *cgoparam.Allocator, preallocatedPointer unsafe.Pointer, next unsafe.Pointer) (unsafe.Pointer, error) {
+	if preallocatedPointer == nil {
+		preallocatedPointer = allocator.Malloc(int(unsafe.Sizeof(vkValidationFeatures{})))
+	}
+
+	features := (*vkValidationFeatures)(preallocatedPointer)
+	features.sType = structureTypeValidationFeatures
+	features.pNext = next
+
+	enabledCount, enabled := allocSlice(allocator, o.EnabledValidationFeatures)
+	features.enabledValidationFeatureCount = uint32(enabledCount)
+	features.pEnabledValidationFeatures = enabled
+
+	disabledCount, disabled := allocSlice(allocator, o.DisabledValidationFeatures)
+	features.disabledValidationFeatureCount = uint32(disabledCount)
+	features.pDisabledValidationFeatures = disabled
+
+	return preallocatedPointer, nil
+}
@@ -0,0 +1,144 @@
+package vertexinput
+
+import (
+	"reflect"
+
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// numeric is how a format's components are read by the shader
+type numeric int
+
+const (
+	numericFloat numeric = iota
+	numericUnsignedNormalized
+	numericSignedNormalized
+	numericUnsignedScaled
+	numericSignedScaled
+	numericUnsignedInt
+	numericSignedInt
+)
+
+func (n numeric) String() string {
+	switch n {
+	case numericUnsignedNormalized:
+		return "unsigned normalized"
+	case numericSignedNormalized:
+		return "signed normalized"
+	case numericUnsignedScaled:
+		return "unsigned scaled"
+	case numericSignedScaled:
+		return "signed scaled"
+	case numericUnsignedInt:
+		return "unsigned integer"
+	case numericSignedInt:
+		return "signed integer"
+	}
+
+	return "float"
+}
+
+// shaderClass is the kind of shader input a format can feed: normalized and scaled
+// formats are read as floats
+func (n numeric) shaderClass() numeric {
+	switch n {
+	case numericUnsignedInt, numericSignedInt:
+		return n
+	}
+
+	return numericFloat
+}
+
+var scalarBits = map[reflect.Kind]int{
+	reflect.Int8:    8,
+	reflect.Uint8:   8,
+	reflect.Int16:   16,
+	reflect.Uint16:  16,
+	reflect.Int32:   32,
+	reflect.Uint32:  32,
+	reflect.Float32: 32,
+	reflect.Int64:   64,
+	reflect.Uint64:  64,
+	reflect.Float64: 64,
+}
+
+type formatKey struct {
+	bits       int
+	components int
+	numeric    numeric
+}
+
+var formats = map[formatKey]core1_0.Format{
+	{8, 1, numericUnsignedNormalized}:  core1_0.FormatR8UnsignedNormalized,
+	{8, 1, numericSignedNormalized}:    core1_0.FormatR8SignedNormalized,
+	{8, 1, numericUnsignedScaled}:      core1_0.FormatR8UnsignedScaled,
+	{8, 1, numericSignedScaled}:        core1_0.FormatR8SignedScaled,
+	{8, 1, numericUnsignedInt}:         core1_0.FormatR8UnsignedInt,
+	{8, 1, numericSignedInt}:           core1_0.FormatR8SignedInt,
+	{8, 2, numericUnsignedNormalized}:  core1_0.FormatR8G8UnsignedNormalized,
+	{8, 2, numericSignedNormalized}:    core1_0.FormatR8G8SignedNormalized,
+	{8, 2, numericUnsignedScaled}:      core1_0.FormatR8G8UnsignedScaled,
+	{8, 2, numericSignedScaled}:        core1_0.FormatR8G8SignedScaled,
+	{8, 2, numericUnsignedInt}:         core1_0.FormatR8G8UnsignedInt,
+	{8, 2, numericSignedInt}:           core1_0.FormatR8G8SignedInt,
+	{8, 3, numericUnsignedNormalized}:  core1_0.FormatR8G8B8UnsignedNormalized,
+	{8, 3, numericSignedNormalized}:    core1_0.FormatR8G8B8SignedNormalized,
+	{8, 3, numericUnsignedScaled}:      core1_0.FormatR8G8B8UnsignedScaled,
+	{8, 3, numericSignedScaled}:        core1_0.FormatR8G8B8SignedScaled,
+	{8, 3, numericUnsignedInt}:         core1_0.FormatR8G8B8UnsignedInt,
+	{8, 3, numericSignedInt}:           core1_0.FormatR8G8B8SignedInt,
+	{8, 4, numericUnsignedNormalized}:  core1_0.FormatR8G8B8A8UnsignedNormalized,
+	{8, 4, numericSignedNormalized}:    core1_0.FormatR8G8B8A8SignedNormalized,
+	{8, 4, numericUnsignedScaled}:      core1_0.FormatR8G8B8A8UnsignedScaled,
+	{8, 4, numericSignedScaled}:        core1_0.FormatR8G8B8A8SignedScaled,
+	{8, 4, numericUnsignedInt}:         core1_0.FormatR8G8B8A8UnsignedInt,
+	{8, 4, numericSignedInt}:           core1_0.FormatR8G8B8A8SignedInt,
+	{16, 1, numericUnsignedNormalized}: core1_0.FormatR16UnsignedNormalized,
+	{16, 1, numericSignedNormalized}:   core1_0.FormatR16SignedNormalized,
+	{16, 1, numericUnsignedScaled}:     core1_0.FormatR16UnsignedScaled,
+	{16, 1, numericSignedScaled}:       core1_0.FormatR16SignedScaled,
+	{16, 1, numericUnsignedInt}:        core1_0.FormatR16UnsignedInt,
+	{16, 1, numericSignedInt}:          core1_0.FormatR16SignedInt,
+	{16, 2, numericUnsignedNormalized}: core1_0.FormatR16G16UnsignedNormalized,
+	{16, 2, numericSignedNormalized}:   core1_0.FormatR16G16SignedNormalized,
+	{16, 2, numericUnsignedScaled}:     core1_0.FormatR16G16UnsignedScaled,
+	{16, 2, numericSignedScaled}:       core1_0.FormatR16G16SignedScaled,
+	{16, 2, numericUnsignedInt}:        core1_0.FormatR16G16UnsignedInt,
+	{16, 2, numericSignedInt}:          core1_0.FormatR16G16SignedInt,
+	{16, 3, numericUnsignedNormalized}: core1_0.FormatR16G16B16UnsignedNormalized,
+	{16, 3, numericSignedNormalized}:   core1_0.FormatR16G16B16SignedNormalized,
+	{16, 3, numericUnsignedScaled}:     core1_0.FormatR16G16B16UnsignedScaled,
+	{16, 3, numericSignedScaled}:       core1_0.FormatR16G16B16SignedScaled,
+	{16, 3, numericUnsignedInt}:        core1_0.FormatR16G16B16UnsignedInt,
+	{16, 3, numericSignedInt}:          core1_0.FormatR16G16B16SignedInt,
+	{16, 4, numericUnsignedNormalized}: core1_0.FormatR16G16B16A16UnsignedNormalized,
+	{16, 4, numericSignedNormalized}:   core1_0.FormatR16G16B16A16SignedNormalized,
+	{16, 4, numericUnsignedScaled}:     core1_0.FormatR16G16B16A16UnsignedScaled,
+	{16, 4, numericSignedScaled}:       core1_0.FormatR16G16B16A16SignedScaled,
+	{16, 4, numericUnsignedInt}:        core1_0.FormatR16G16B16A16UnsignedInt,
+	{16, 4, numericSignedInt}:          core1_0.FormatR16G16B16A16SignedInt,
+	{32, 1, numericUnsignedInt}:        core1_0.FormatR32UnsignedInt,
+	{32, 1, numericSignedInt}:          core1_0.FormatR32SignedInt,
+	{32, 1, numericFloat}:              core1_0.FormatR32SignedFloat,
+	{32, 2, numericUnsignedInt}:        core1_0.FormatR32G32UnsignedInt,
+	{32, 2, numericSignedInt}:          core1_0.FormatR32G32SignedInt,
+	{32, 2, numericFloat}:              core1_0.FormatR32G32SignedFloat,
+	{32, 3, numericUnsignedInt}:        core1_0.FormatR32G32B32UnsignedInt,
+	{32, 3, numericSignedInt}:          core1_0.FormatR32G32B32SignedInt,
+	{32, 3, numericFloat}:              core1_0.FormatR32G32B32SignedFloat,
+	{32, 4, numericUnsignedInt}:        core1_0.FormatR32G32B32A32UnsignedInt,
+	{32, 4, numericSignedInt}:          core1_0.FormatR32G32B32A32SignedInt,
+	{32, 4, numericFloat}:              core1_0.FormatR32G32B32A32SignedFloat,
+	{64, 1, numericUnsignedInt}:        core1_0.FormatR64UnsignedInt,
+	{64, 1, numericSignedInt}:          core1_0.FormatR64SignedInt,
+	{64, 1, numericFloat}:              core1_0.FormatR64SignedFloat,
+	{64, 2, numericUnsignedInt}:        core1_0.FormatR64G64UnsignedInt,
+	{64, 2, numericSignedInt}:          core1_0.FormatR64G64SignedInt,
+	{64, 2, numericFloat}:              core1_0.FormatR64G64SignedFloat,
+	{64, 3, numericUnsignedInt}:        core1_0.FormatR64G64B64UnsignedInt,
+	{64, 3, numericSignedInt}:          core1_0.FormatR64G64B64SignedInt,
+	{64, 3, numericFloat}:              core1_0.FormatR64G64B64SignedFloat,
+	{64, 4, numericUnsignedInt}:        core1_0.FormatR64G64B64A64UnsignedInt,
+	{64, 4, numericSignedInt}:          core1_0.FormatR64G64B64A64SignedInt,
+	{64, 4, numericFloat}:              core1_0.FormatR64G64B64A64SignedFloat,
+}
@@ -0,0 +1,336 @@
+package vertexinput
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/core/v3/core1_0"
+)
+
+// TagName is the struct tag that declares a field's vertex attribute. It is a
+// comma-separated list starting with the attribute's location, optionally followed by
+// normalized or scaled for 8- and 16-bit integer fields:
+//
+//	Position vkngmath.Vec3[float32] `vk:"location=0"`
+//	Color    [4]uint8               `vk:"location=1,normalized"`
+//
+// Consecutive fields with the same location are combined into one attribute, so a
+// position can be declared as PosX, PosY, PosZ, PosW float32 with a single tag. A
+// vkngmath matrix takes one location for each column, starting at the tagged location.
+// Fields tagged with "-" and blank fields are padding, and any other untagged field is an
+// error.
+const TagName = "vk"
+
+const mathPackage = "github.com/vkngwrapper/math"
+
+// Binding is one vertex buffer binding, with attributes read from a Go struct
+type Binding struct {
+	Binding   int
+	Type      reflect.Type
+	InputRate core1_0.VertexInputRate
+}
+
+// PerVertex is a binding whose attributes advance once per vertex. value is a vertex
+// struct or a pointer to one.
+func PerVertex(binding int, value any) Binding {
+	return Binding{Binding: binding, Type: structType(value), InputRate: core1_0.VertexInputRateVertex}
+}
+
+// PerInstance is a binding whose attributes advance once per instance. value is an
+// instance struct or a pointer to one.
+func PerInstance(binding int, value any) Binding {
+	return Binding{Binding: binding, Type: structType(value), InputRate: core1_0.VertexInputRateInstance}
+}
+
+func structType(value any) reflect.Type {
+	goType := reflect.TypeOf(value)
+	for goType != nil && goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+
+	return goType
+}
+
+// Attribute is a vertex attribute along with the Go field or fields it was read from
+type Attribute struct {
+	core1_0.VertexInputAttributeDescription
+	Fields []string
+
+	numeric numeric
+	bits    int
+	// locations is the number of locations the attribute consumes, which is 2 for 64-bit
+	// 3- and 4-component vectors
+	locations uint32
+}
+
+// Layout is the vertex input state for a set of bindings
+type Layout struct {
+	Bindings   []core1_0.VertexInputBindingDescription
+	Attributes []Attribute
+}
+
+// New builds the vertex input state for bindings, with the stride of each binding taken
+// from the size of its struct
+func New(bindings ...Binding) (*Layout, error) {
+	layout := &Layout{}
+	usedBindings := make(map[int]bool)
+	usedLocations := make(map[uint32]string)
+
+	for _, binding := range bindings {
+		if binding.Type == nil || binding.Type.Kind() != reflect.Struct {
+			return nil, errors.Errorf("binding %d: %v is not a struct", binding.Binding, binding.Type)
+		}
+		if usedBindings[binding.Binding] {
+			return nil, errors.Errorf("binding %d is declared twice", binding.Binding)
+		}
+		usedBindings[binding.Binding] = true
+
+		layout.Bindings = append(layout.Bindings, core1_0.VertexInputBindingDescription{
+			Binding:   binding.Binding,
+			Stride:    int(binding.Type.Size()),
+			InputRate: binding.InputRate,
+		})
+
+		attributes, err := structAttributes(binding.Binding, binding.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, attribute := range attributes {
+			for location := attribute.Location; location < attribute.Location+attribute.locations; location++ {
+				previous, taken := usedLocations[location]
+				if taken {
+					return nil, errors.Errorf("%s.%s and %s both use location %d",
+						binding.Type, attribute.Fields[0], previous, location)
+				}
+				usedLocations[location] = binding.Type.String() + "." + attribute.Fields[0]
+			}
+		}
+
+		layout.Attributes = append(layout.Attributes, attributes...)
+	}
+
+	return layout, nil
+}
+
+// AttributeDescriptions returns the attributes in the form pipelines take them
+func (l *Layout) AttributeDescriptions() []core1_0.VertexInputAttributeDescription {
+	descriptions := make([]core1_0.VertexInputAttributeDescription, 0, len(l.Attributes))
+	for _, attribute := range l.Attributes {
+		descriptions = append(descriptions, attribute.VertexInputAttributeDescription)
+	}
+
+	return descriptions
+}
+
+// CreateInfo returns the pipeline vertex input state for the layout
+func (l *Layout) CreateInfo() *core1_0.PipelineVertexInputStateCreateInfo {
+	return &core1_0.PipelineVertexInputStateCreateInfo{
+		VertexBindingDescriptions:   l.Bindings,
+		VertexAttributeDescriptions: l.AttributeDescriptions(),
+	}
+}
+
+type fieldTag struct {
+	skip     bool
+	location int
+	modifier string
+}
+
+func parseTag(field reflect.StructField) (fieldTag, error) {
+	tag, tagged := field.Tag.Lookup(TagName)
+	if field.Name == "_" || tag == "-" {
+		return fieldTag{skip: true}, nil
+	}
+	if !tagged {
+		return fieldTag{}, errors.Errorf("field %s has no %s tag", field.Name, TagName)
+	}
+
+	parsed := fieldTag{location: -1}
+	for _, option := range strings.Split(tag, ",") {
+		option = strings.TrimSpace(option)
+		switch {
+		case strings.HasPrefix(option, "location="):
+			location, err := strconv.Atoi(strings.TrimPrefix(option, "location="))
+			if err != nil || location < 0 {
+				return fieldTag{}, errors.Errorf("field %s has invalid location %q", field.Name, option)
+			}
+			parsed.location = location
+		case option == "normalized" || option == "scaled":
+			if parsed.modifier != "" {
+				return fieldTag{}, errors.Errorf("field %s is both %s and %s", field.Name, parsed.modifier, option)
+			}
+			parsed.modifier = option
+		default:
+			return fieldTag{}, errors.Errorf("field %s has unknown %s tag option %q", field.Name, TagName, option)
+		}
+	}
+
+	if parsed.location < 0 {
+		return fieldTag{}, errors.Errorf("field %s has no location", field.Name)
+	}
+
+	return parsed, nil
+}
+
+// shape is a field's type broken down into scalars
+type shape struct {
+	scalar     reflect.Kind
+	components int
+	columns    int
+}
+
+func shapeOf(goType reflect.Type) (shape, error) {
+	if goType.PkgPath() == mathPackage {
+		name := goType.Name()
+		switch {
+		case (strings.HasPrefix(name, "Vec") || strings.HasPrefix(name, "Quaternion")) && goType.Kind() == reflect.Struct:
+			return shape{scalar: goType.Field(0).Type.Kind(), components: goType.NumField(), columns: 1}, nil
+		case strings.HasPrefix(name, "Mat") && goType.Kind() == reflect.Array:
+			// Mat4Row and the like are arrays too, but of scalars
+			column := goType.Elem()
+			if column.Kind() != reflect.Array {
+				return shape{}, errors.Errorf("%s is a single matrix row, not a matrix; use a Vec type for a vector attribute", goType)
+			}
+			return shape{scalar: column.Elem().Kind(), components: column.Len(), columns: goType.Len()}, nil
+		}
+		return shape{}, errors.Errorf("%s cannot be a vertex attribute", goType)
+	}
+
+	if goType.Kind() == reflect.Array {
+		elem := goType.Elem()
+		if _, isScalar := scalarBits[elem.Kind()]; !isScalar {
+			return shape{}, errors.Errorf("%s cannot be a vertex attribute", goType)
+		}
+		return shape{scalar: elem.Kind(), components: goType.Len(), columns: 1}, nil
+	}
+
+	if _, isScalar := scalarBits[goType.Kind()]; !isScalar {
+		return shape{}, errors.Errorf("%s cannot be a vertex attribute", goType)
+	}
+	return shape{scalar: goType.Kind(), components: 1, columns: 1}, nil
+}
+
+func structAttributes(binding int, structType reflect.Type) ([]Attribute, error) {
+	var attributes []Attribute
+	var last *Attribute
+	var lastShape shape
+	var lastEnd uintptr
+
+	for fieldIndex := 0; fieldIndex < structType.NumField(); fieldIndex++ {
+		field := structType.Field(fieldIndex)
+		tag, err := parseTag(field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", structType)
+		}
+		if tag.skip {
+			last = nil
+			continue
+		}
+
+		fieldShape, err := shapeOf(field.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s.%s", structType, field.Name)
+		}
+
+		// Fields declared together, like PosX, PosY, PosZ, PosW float32, extend the
+		// previous attribute
+		if last != nil && last.Location == uint32(tag.location) && fieldShape.columns == 1 && lastShape.columns == 1 {
+			if fieldShape.scalar != lastShape.scalar || field.Offset != lastEnd {
+				return nil, errors.Errorf("%s.%s shares location %d with %s but is not the same type directly after it",
+					structType, field.Name, tag.location, last.Fields[len(last.Fields)-1])
+			}
+
+			lastShape.components += fieldShape.components
+			err = last.setFormat(lastShape, tag.modifier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", structType, field.Name)
+			}
+			last.Fields = append(last.Fields, field.Name)
+			lastEnd = field.Offset + field.Type.Size()
+			continue
+		}
+
+		columnSize := field.Type.Size() / uintptr(fieldShape.columns)
+		location := uint32(tag.location)
+		for column := 0; column < fieldShape.columns; column++ {
+			attribute := Attribute{
+				VertexInputAttributeDescription: core1_0.VertexInputAttributeDescription{
+					Binding:  binding,
+					Location: location,
+					Offset:   int(field.Offset + uintptr(column)*columnSize),
+				},
+				Fields: []string{field.Name},
+			}
+
+			err = attribute.setFormat(shape{scalar: fieldShape.scalar, components: fieldShape.components, columns: 1}, tag.modifier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", structType, field.Name)
+			}
+
+			location += attribute.locations
+			attributes = append(attributes, attribute)
+		}
+
+		last = &attributes[len(attributes)-1]
+		lastShape = fieldShape
+		lastShape.columns = 1
+		lastEnd = field.Offset + field.Type.Size()
+		if fieldShape.columns > 1 {
+			// Matrix columns can't be extended by later fields
+			last = nil
+		}
+	}
+
+	return attributes, nil
+}
+
+func (a *Attribute) setFormat(fieldShape shape, modifier string) error {
+	if fieldShape.components > 4 {
+		return errors.Errorf("%d components is more than a vertex attribute can hold", fieldShape.components)
+	}
+
+	bits := scalarBits[fieldShape.scalar]
+	signed := fieldShape.scalar == reflect.Int8 || fieldShape.scalar == reflect.Int16 ||
+		fieldShape.scalar == reflect.Int32 || fieldShape.scalar == reflect.Int64
+
+	var class numeric
+	switch {
+	case fieldShape.scalar == reflect.Float32 || fieldShape.scalar == reflect.Float64:
+		if modifier != "" {
+			return errors.Errorf("float fields cannot be %s", modifier)
+		}
+		class = numericFloat
+	case modifier != "" && bits > 16:
+		return errors.Errorf("%d-bit fields cannot be %s", bits, modifier)
+	case modifier == "normalized" && signed:
+		class = numericSignedNormalized
+	case modifier == "normalized":
+		class = numericUnsignedNormalized
+	case modifier == "scaled" && signed:
+		class = numericSignedScaled
+	case modifier == "scaled":
+		class = numericUnsignedScaled
+	case signed:
+		class = numericSignedInt
+	default:
+		class = numericUnsignedInt
+	}
+
+	format, found := formats[formatKey{bits: bits, components: fieldShape.components, numeric: class}]
+	if !found {
+		return errors.Errorf("no format holds %d %d-bit %s components", fieldShape.components, bits, class)
+	}
+
+	a.Format = format
+	a.numeric = class
+	a.bits = bits
+	a.locations = 1
+	if bits == 64 && fieldShape.components > 2 {
+		a.locations = 2
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package vertexinput
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/internal/spirv"
+)
+
+// ShaderInput is a user-defined input variable of a vertex shader
+type ShaderInput struct {
+	Name     string
+	Location int
+	// Locations is the number of consecutive locations the input consumes
+	Locations int
+	// Components is the number of components at each location
+	Components int
+	// Bits is the width of each component
+	Bits int
+
+	numeric numeric
+}
+
+// ReflectInputs finds the input variables of a SPIR-V vertex shader that are fed by
+// vertex attributes. Built-in inputs like gl_VertexIndex are left out.
+func ReflectInputs(code []uint32) ([]ShaderInput, error) {
+	names := make(map[uint32]string)
+	locations := make(map[uint32]uint32)
+	builtIns := make(map[uint32]bool)
+	types := make(map[uint32]spirv.Type)
+	constants := make(map[uint32]uint32)
+
+	type variable struct {
+		id, pointerType uint32
+	}
+	var variables []variable
+
+	err := spirv.Instructions(code, func(opcode int, operands []uint32) {
+		switch opcode {
+		case spirv.OpName:
+			names[operands[0]] = spirv.DecodeString(operands[1:])
+		case spirv.OpDecorate:
+			switch operands[1] {
+			case spirv.DecorationLocation:
+				locations[operands[0]] = operands[2]
+			case spirv.DecorationBuiltIn:
+				builtIns[operands[0]] = true
+			}
+		case spirv.OpMemberDecorate:
+			// Built-in blocks such as gl_PerVertex are decorated member by member
+			if operands[2] == spirv.DecorationBuiltIn {
+				builtIns[operands[0]] = true
+			}
+		case spirv.OpTypeInt, spirv.OpTypeFloat, spirv.OpTypeVector, spirv.OpTypeMatrix, spirv.OpTypeArray, spirv.OpTypePointer:
+			types[operands[0]] = spirv.Type{Opcode: opcode, Operands: operands[1:]}
+		case spirv.OpConstant:
+			constants[operands[1]] = operands[2]
+		case spirv.OpVariable:
+			if operands[2] == spirv.StorageClassInput {
+				variables = append(variables, variable{id: operands[1], pointerType: operands[0]})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []ShaderInput
+	for _, candidate := range variables {
+		location, hasLocation := locations[candidate.id]
+		if builtIns[candidate.id] || !hasLocation {
+			continue
+		}
+
+		input := ShaderInput{
+			Name:      names[candidate.id],
+			Location:  int(location),
+			Locations: 1,
+		}
+
+		typeID := types[candidate.pointerType].Operands[1]
+		if builtIns[typeID] {
+			continue
+		}
+
+		// Arrays and matrices both take a run of locations
+		for {
+			declared := types[typeID]
+			if declared.Opcode == spirv.OpTypeArray {
+				input.Locations *= int(constants[declared.Operands[1]])
+			} else if declared.Opcode == spirv.OpTypeMatrix {
+				input.Locations *= int(declared.Operands[1])
+			} else {
+				break
+			}
+			typeID = declared.Operands[0]
+		}
+
+		input.Components = 1
+		if types[typeID].Opcode == spirv.OpTypeVector {
+			input.Components = int(types[typeID].Operands[1])
+			typeID = types[typeID].Operands[0]
+		}
+
+		scalar := types[typeID]
+		switch {
+		case scalar.Opcode == spirv.OpTypeFloat:
+			input.numeric = numericFloat
+		case scalar.Opcode == spirv.OpTypeInt && scalar.Operands[1] != 0:
+			input.numeric = numericSignedInt
+		case scalar.Opcode == spirv.OpTypeInt:
+			input.numeric = numericUnsignedInt
+		default:
+			return nil, errors.Errorf("input %s has an unsupported type", input.Name)
+		}
+		input.Bits = int(scalar.Operands[0])
+
+		// 64-bit three- and four-component vectors take two locations each
+		if input.Bits == 64 && input.Components > 2 {
+			input.Locations *= 2
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
+
+// Check compares the layout against the inputs of a SPIR-V vertex shader, returning an
+// error if an input has no attribute or is fed by an attribute of the wrong kind.
+// Attributes the shader doesn't read are allowed, and so are component counts that
+// differ, since Vulkan fills in or drops the extra components.
+func (l *Layout) Check(code []uint32) error {
+	inputs, err := ReflectInputs(code)
+	if err != nil {
+		return err
+	}
+
+	byLocation := make(map[uint32]Attribute)
+	for _, attribute := range l.Attributes {
+		byLocation[attribute.Location] = attribute
+	}
+
+	for _, input := range inputs {
+		end := uint32(input.Location + input.Locations)
+		for location := uint32(input.Location); location < end; {
+			attribute, found := byLocation[location]
+			if !found {
+				return errors.Errorf("shader input %s reads location %d, which has no attribute", input.Name, location)
+			}
+
+			if attribute.numeric.shaderClass() != input.numeric {
+				return errors.Errorf("shader input %s is a %s, but %s at location %d is %s",
+					input.Name, input.numeric, attribute.Fields[0], location, attribute.numeric)
+			}
+
+			if (input.Bits == 64) != (attribute.bits == 64) {
+				return errors.Errorf("shader input %s is %d-bit, but %s at location %d is %s",
+					input.Name, input.Bits, attribute.Fields[0], location, attribute.Format)
+			}
+
+			location += attribute.locations
+		}
+	}
+
+	return nil
+}
@@ -7,10 +7,7 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/vkngwrapper/core/v3"
 	"github.com/vkngwrapper/core/v3/common"
-	"github.com/vkngwrapper/core/v3/core1_0"
-	"github.com/vkngwrapper/core/v3/core1_1"
 	"github.com/vkngwrapper/examples/lunarg_samples/utils"
-	"github.com/vkngwrapper/extensions/v3/khr_portability_enumeration"
 )
 
 /*
@@ -48,54 +45,25 @@ func main() {
 	desiredVersion := common.Vulkan1_1
 	fmt.Printf("Loader/Runtime support detected for Vulkan %s\n", info.GlobalDriver.Loader().Version())
 
-	actualVersion := common.Vulkan1_1
-	if info.GlobalDriver.Loader().Version().IsAtLeast(desiredVersion) {
-		extensions, _, err := info.GlobalDriver.AvailableExtensions()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	/* Ask for 1.1, but accept 1.0: InitInstance and InitEnumerateDevice negotiate
+	 * the newest version the loader and a device both support */
+	info.DesiredAPIVersion = desiredVersion
+	info.MinimumAPIVersion = common.Vulkan1_0
 
-		var extensionList []string
-		var flags core1_0.InstanceCreateFlags
-
-		_, ok := extensions[khr_portability_enumeration.ExtensionName]
-		if ok {
-			extensionList = append(extensionList, khr_portability_enumeration.ExtensionName)
-			flags = khr_portability_enumeration.InstanceCreateEnumeratePortability
-		}
-
-		info.InstanceDriver, _, err = info.GlobalDriver.CreateInstance(nil, core1_0.InstanceCreateInfo{
-			ApplicationName:       "vulkan_1_1_sampler",
-			ApplicationVersion:    common.CreateVersion(1, 0, 0),
-			EngineName:            "vulkan_1_1_sampler",
-			EngineVersion:         common.CreateVersion(1, 0, 0),
-			APIVersion:            desiredVersion,
-			EnabledExtensionNames: extensionList,
-			Flags:                 flags,
-		})
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		defer info.InstanceDriver.DestroyInstance(nil)
-
-		instance11 := info.InstanceDriver.(core1_1.CoreInstanceDriver)
-		if instance11 == nil {
-			log.Fatalln("instance v1.1 not loaded")
-		}
-
-		physicalDevices, _, err := instance11.EnumeratePhysicalDevices()
-		if err != nil {
-			log.Fatalln(err)
-		}
+	err = info.InitInstance("vulkan_1_1_sampler", nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer info.InstanceDriver.DestroyInstance(nil)
 
-		for _, device := range physicalDevices {
-			if device.DeviceAPIVersion().IsAtLeast(desiredVersion) {
-				actualVersion = desiredVersion
+	err = info.InitEnumerateDevice()
+	if err != nil {
+		log.Fatalln(err)
+	}
 
-				break
-			}
-		}
+	actualVersion := info.DeviceAPIVersion
+	if info.InstanceDriver11 != nil {
+		fmt.Println("Vulkan 1.1 instance commands are available")
 	}
 
 	if actualVersion < desiredVersion {
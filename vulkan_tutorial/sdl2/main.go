@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/binary"
+	"fmt"
 	"image/png"
 	"log"
 	"math"
+	"os"
 	"runtime"
 	"unsafe"
 
@@ -17,6 +20,18 @@ import (
 	"github.com/vkngwrapper/core/v3"
 	"github.com/vkngwrapper/core/v3/common"
 	"github.com/vkngwrapper/core/v3/core1_0"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/blocklayout"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/breadcrumbs"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/debugnames"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/deletion"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/descriptors"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/framesync"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/mesh"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/pipelineregistry"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/queues"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/upload"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/validation"
+	"github.com/vkngwrapper/examples/lunarg_samples/utils/vertexinput"
 	"github.com/vkngwrapper/extensions/v3/ext_debug_utils"
 	"github.com/vkngwrapper/extensions/v3/khr_portability_enumeration"
 	"github.com/vkngwrapper/extensions/v3/khr_portability_subset"
@@ -45,6 +60,13 @@ func (i *QueueFamilyIndices) IsComplete() bool {
 	return i.GraphicsFamily != nil && i.PresentFamily != nil
 }
 
+// frameSubmission is a frame's number on the frame counter and the id breadcrumbs gave
+// its submission. The zero frameSubmission is a frame that never needs waiting for.
+type frameSubmission struct {
+	frame       uint64
+	breadcrumbs uint64
+}
+
 type SwapChainSupportDetails struct {
 	Capabilities *khr_surface.SurfaceCapabilities
 	Formats      []khr_surface.SurfaceFormat
@@ -52,9 +74,9 @@ type SwapChainSupportDetails struct {
 }
 
 type Vertex struct {
-	Position vkngmath.Vec3[float32]
-	Color    vkngmath.Vec3[float32]
-	TexCoord vkngmath.Vec2[float32]
+	Position vkngmath.Vec3[float32] `vk:"location=0"`
+	Color    vkngmath.Vec3[float32] `vk:"location=1"`
+	TexCoord vkngmath.Vec2[float32] `vk:"location=2"`
 }
 
 type UniformBufferObject struct {
@@ -63,43 +85,22 @@ type UniformBufferObject struct {
 	Proj  vkngmath.Mat4x4[float32]
 }
 
-func getVertexBindingDescription() []core1_0.VertexInputBindingDescription {
-	v := Vertex{}
-	return []core1_0.VertexInputBindingDescription{
-		{
-			Binding:   0,
-			Stride:    int(unsafe.Sizeof(v)),
-			InputRate: core1_0.VertexInputRateVertex,
-		},
-	}
+// uniformBufferLayout is the std140 layout of UniformBufferObject, matching the ubo block
+// in shaders/shader.vert
+func uniformBufferLayout() (*blocklayout.Type, error) {
+	return blocklayout.TypeOf(blocklayout.Std140, UniformBufferObject{})
 }
 
-func getVertexAttributeDescriptions() []core1_0.VertexInputAttributeDescription {
-	v := Vertex{}
-	return []core1_0.VertexInputAttributeDescription{
-		{
-			Binding:  0,
-			Location: 0,
-			Format:   core1_0.FormatR32G32B32SignedFloat,
-			Offset:   int(unsafe.Offsetof(v.Position)),
-		},
-		{
-			Binding:  0,
-			Location: 1,
-			Format:   core1_0.FormatR32G32B32SignedFloat,
-			Offset:   int(unsafe.Offsetof(v.Color)),
-		},
-		{
-			Binding:  0,
-			Location: 2,
-			Format:   core1_0.FormatR32G32SignedFloat,
-			Offset:   int(unsafe.Offsetof(v.TexCoord)),
-		},
-	}
+// vertexLayout is the vertex input state for Vertex, matching the inputs of
+// shaders/shader.vert
+func vertexLayout() (*vertexinput.Layout, error) {
+	return vertexinput.New(vertexinput.PerVertex(0, Vertex{}))
 }
 
 type HelloTriangleApplication struct {
-	window *sdl.Window
+	window     *sdl.Window
+	validation validation.Settings
+	printf     validation.PrintfCapture
 
 	globalDriver   core1_0.GlobalDriver
 	instanceDriver core1_0.CoreInstanceDriver
@@ -107,6 +108,9 @@ type HelloTriangleApplication struct {
 
 	debugDriver      ext_debug_utils.ExtensionDriver
 	debugMessenger   ext_debug_utils.DebugUtilsMessenger
+	names            *debugnames.Namer
+	breadcrumbs      *breadcrumbs.Tracker
+	pipelines        *pipelineregistry.Registry
 	surfaceExtension khr_surface.ExtensionDriver
 	surface          khr_surface.Surface
 
@@ -114,6 +118,12 @@ type HelloTriangleApplication struct {
 
 	graphicsQueue core1_0.Queue
 	presentQueue  core1_0.Queue
+	// queues wraps every Vulkan queue the app uses, so the graphics and present queues
+	// share a lock when they are the same queue. All submits, waits and presents go
+	// through syncGraphicsQueue and syncPresentQueue.
+	queues            *queues.Registry
+	syncGraphicsQueue *queues.Queue
+	syncPresentQueue  *queues.Queue
 
 	swapchainExtension    khr_swapchain.ExtensionDriver
 	swapchain             khr_swapchain.Swapchain
@@ -124,24 +134,28 @@ type HelloTriangleApplication struct {
 	swapchainFramebuffers []core1_0.Framebuffer
 
 	renderPass          core1_0.RenderPass
-	descriptorPool      core1_0.DescriptorPool
+	descriptors         *descriptors.Allocator
+	descriptorWriter    *descriptors.Writer
 	descriptorSets      []core1_0.DescriptorSet
 	descriptorSetLayout core1_0.DescriptorSetLayout
+	descriptorLayout    *descriptors.Layout
 	pipelineLayout      core1_0.PipelineLayout
 	graphicsPipeline    core1_0.Pipeline
 
 	commandPool    core1_0.CommandPool
 	commandBuffers []core1_0.CommandBuffer
 
+	frames                  *framesync.Counter
+	deletions               *deletion.Queue
 	imageAvailableSemaphore []core1_0.Semaphore
 	renderFinishedSemaphore []core1_0.Semaphore
-	inFlightFence           []core1_0.Fence
-	imagesInFlight          []core1_0.Fence
+	inFlightFrames          []frameSubmission
+	imagesInFlight          []frameSubmission
 	currentFrame            int
+	frameNumber             uint64
 	frameStart              float64
 
-	vertices           []Vertex
-	indices            []uint32
+	model              *mesh.Mesh[Vertex]
 	vertexBuffer       core1_0.Buffer
 	vertexBufferMemory core1_0.DeviceMemory
 	indexBuffer        core1_0.Buffer
@@ -226,7 +240,7 @@ func (app *HelloTriangleApplication) initVulkan() error {
 		return err
 	}
 
-	err = app.createSwapchain()
+	err = app.createSwapchain(khr_swapchain.Swapchain{})
 	if err != nil {
 		return err
 	}
@@ -305,7 +319,7 @@ func (app *HelloTriangleApplication) initVulkan() error {
 		return err
 	}
 
-	err = app.createDescriptorPool()
+	err = app.createDescriptorAllocator()
 	if err != nil {
 		return err
 	}
@@ -360,91 +374,124 @@ appLoop:
 		}
 	}
 
-	_, err := app.deviceDriver.DeviceWaitIdle()
+	res, err := app.deviceDriver.DeviceWaitIdle()
+	app.breadcrumbs.Check(res)
 	return err
 }
 
-func (app *HelloTriangleApplication) cleanupSwapChain() {
-	if app.colorImageView.Initialized() {
-		app.deviceDriver.DestroyImageView(app.colorImageView, nil)
-		app.colorImageView = core1_0.ImageView{}
-	}
-
-	if app.colorImage.Initialized() {
-		app.deviceDriver.DestroyImage(app.colorImage, nil)
-		app.colorImage = core1_0.Image{}
-	}
-
-	if app.colorImageMemory.Initialized() {
-		app.deviceDriver.FreeMemory(app.colorImageMemory, nil)
-		app.colorImageMemory = core1_0.DeviceMemory{}
-	}
-
-	if app.depthImageView.Initialized() {
-		app.deviceDriver.DestroyImageView(app.depthImageView, nil)
-		app.depthImageView = core1_0.ImageView{}
-	}
-
-	if app.depthImage.Initialized() {
-		app.deviceDriver.DestroyImage(app.depthImage, nil)
-		app.depthImage = core1_0.Image{}
+// cleanupSwapChain queues everything created for the current swapchain for destruction
+// once the GPU has finished the frames already submitted, which may still be using it.
+// The swapchain handle is left in place, so that the next one can be created from it.
+func (app *HelloTriangleApplication) cleanupSwapChain() error {
+	if app.deletions == nil {
+		return nil
 	}
 
-	if app.depthImageMemory.Initialized() {
-		app.deviceDriver.FreeMemory(app.depthImageMemory, nil)
-		app.depthImageMemory = core1_0.DeviceMemory{}
+	err := app.deletions.Destroy(
+		app.colorImageView, app.colorImage, app.colorImageMemory,
+		app.depthImageView, app.depthImage, app.depthImageMemory,
+	)
+	if err != nil {
+		return err
 	}
+	app.colorImageView = core1_0.ImageView{}
+	app.colorImage = core1_0.Image{}
+	app.colorImageMemory = core1_0.DeviceMemory{}
+	app.depthImageView = core1_0.ImageView{}
+	app.depthImage = core1_0.Image{}
+	app.depthImageMemory = core1_0.DeviceMemory{}
 
 	for _, framebuffer := range app.swapchainFramebuffers {
-		app.deviceDriver.DestroyFramebuffer(framebuffer, nil)
+		err = app.deletions.Destroy(framebuffer)
+		if err != nil {
+			return err
+		}
 	}
 	app.swapchainFramebuffers = []core1_0.Framebuffer{}
 
-	if len(app.commandBuffers) > 0 {
-		app.deviceDriver.FreeCommandBuffers(app.commandBuffers...)
-		app.commandBuffers = []core1_0.CommandBuffer{}
+	for _, buffer := range app.commandBuffers {
+		err = app.deletions.Destroy(buffer)
+		if err != nil {
+			return err
+		}
 	}
+	app.commandBuffers = []core1_0.CommandBuffer{}
 
+	// The registry keeps the pipeline alive, so if the new render pass is compatible
+	// with this one, recreateSwapChain gets the same pipeline back without recompiling
 	if app.graphicsPipeline.Initialized() {
-		app.deviceDriver.DestroyPipeline(app.graphicsPipeline, nil)
+		pipeline := app.graphicsPipeline
+		app.deletions.Defer(func() { app.pipelines.Release(pipeline) })
 		app.graphicsPipeline = core1_0.Pipeline{}
 	}
 
 	if app.pipelineLayout.Initialized() {
-		app.deviceDriver.DestroyPipelineLayout(app.pipelineLayout, nil)
+		app.pipelines.Forget(app.pipelineLayout)
+		err = app.deletions.Destroy(app.pipelineLayout)
+		if err != nil {
+			return err
+		}
 		app.pipelineLayout = core1_0.PipelineLayout{}
 	}
 
 	if app.renderPass.Initialized() {
-		app.deviceDriver.DestroyRenderPass(app.renderPass, nil)
+		app.pipelines.Forget(app.renderPass)
+		err = app.deletions.Destroy(app.renderPass)
+		if err != nil {
+			return err
+		}
 		app.renderPass = core1_0.RenderPass{}
 	}
 
 	for _, imageView := range app.swapchainImageViews {
-		app.deviceDriver.DestroyImageView(imageView, nil)
+		err = app.deletions.Destroy(imageView)
+		if err != nil {
+			return err
+		}
 	}
 	app.swapchainImageViews = []core1_0.ImageView{}
 
 	if app.swapchain.Initialized() {
-		app.swapchainExtension.DestroySwapchain(app.swapchain, nil)
-		app.swapchain = khr_swapchain.Swapchain{}
+		swapchain := app.swapchain
+		swapchainExtension := app.swapchainExtension
+		app.deletions.Defer(func() { swapchainExtension.DestroySwapchain(swapchain, nil) })
 	}
 
-	for i := 0; i < len(app.uniformBuffers); i++ {
-		app.deviceDriver.DestroyBuffer(app.uniformBuffers[i], nil)
+	for _, buffer := range app.uniformBuffers {
+		err = app.deletions.Destroy(buffer)
+		if err != nil {
+			return err
+		}
 	}
 	app.uniformBuffers = app.uniformBuffers[:0]
 
-	for i := 0; i < len(app.uniformBuffersMemory); i++ {
-		app.deviceDriver.FreeMemory(app.uniformBuffersMemory[i], nil)
+	for _, memory := range app.uniformBuffersMemory {
+		err = app.deletions.Destroy(memory)
+		if err != nil {
+			return err
+		}
 	}
 	app.uniformBuffersMemory = app.uniformBuffersMemory[:0]
 
-	app.deviceDriver.DestroyDescriptorPool(app.descriptorPool, nil)
+	// Every set is freed with the old allocator's pools, and the new swapchain's sets
+	// come from a fresh allocator
+	if app.descriptors != nil {
+		oldDescriptors := app.descriptors
+		app.deletions.Defer(oldDescriptors.Destroy)
+		app.descriptors = nil
+	}
+
+	return nil
 }
 
 func (app *HelloTriangleApplication) cleanup() {
-	app.cleanupSwapChain()
+	err := app.cleanupSwapChain()
+	if err == nil && app.deletions != nil {
+		_, err = app.deletions.Flush(context.Background())
+	}
+	if err != nil {
+		log.Println(err)
+	}
 
 	if app.textureSampler.Initialized() {
 		app.deviceDriver.DestroySampler(app.textureSampler, nil)
@@ -482,10 +529,12 @@ func (app *HelloTriangleApplication) cleanup() {
 		app.deviceDriver.FreeMemory(app.vertexBufferMemory, nil)
 	}
 
-	for _, fence := range app.inFlightFence {
-		app.deviceDriver.DestroyFence(fence, nil)
+	if app.frames != nil {
+		app.frames.Destroy()
 	}
 
+	app.queues.Destroy()
+
 	for _, semaphore := range app.renderFinishedSemaphore {
 		app.deviceDriver.DestroySemaphore(semaphore, nil)
 	}
@@ -498,6 +547,18 @@ func (app *HelloTriangleApplication) cleanup() {
 		app.deviceDriver.DestroyCommandPool(app.commandPool, nil)
 	}
 
+	if app.pipelines != nil {
+		app.pipelines.Destroy()
+	}
+
+	if app.descriptors != nil {
+		app.descriptors.Destroy()
+	}
+
+	if app.breadcrumbs != nil {
+		app.breadcrumbs.Destroy()
+	}
+
 	if app.deviceDriver != nil {
 		app.deviceDriver.DestroyDevice(nil)
 	}
@@ -529,14 +590,15 @@ func (app *HelloTriangleApplication) recreateSwapChain() error {
 		return nil
 	}
 
-	_, err := app.deviceDriver.DeviceWaitIdle()
+	// Frames in flight keep rendering with the old swapchain's objects, which are
+	// destroyed once they finish, so the device doesn't need to go idle
+	oldSwapchain := app.swapchain
+	err := app.cleanupSwapChain()
 	if err != nil {
 		return err
 	}
 
-	app.cleanupSwapChain()
-
-	err = app.createSwapchain()
+	err = app.createSwapchain(oldSwapchain)
 	if err != nil {
 		return err
 	}
@@ -576,7 +638,7 @@ func (app *HelloTriangleApplication) recreateSwapChain() error {
 		return err
 	}
 
-	err = app.createDescriptorPool()
+	err = app.createDescriptorAllocator()
 	if err != nil {
 		return err
 	}
@@ -591,10 +653,7 @@ func (app *HelloTriangleApplication) recreateSwapChain() error {
 		return err
 	}
 
-	app.imagesInFlight = []core1_0.Fence{}
-	for i := 0; i < len(app.swapchainImages); i++ {
-		app.imagesInFlight = append(app.imagesInFlight, core1_0.Fence{})
-	}
+	app.imagesInFlight = make([]frameSubmission, len(app.swapchainImages))
 
 	return nil
 }
@@ -650,6 +709,16 @@ func (app *HelloTriangleApplication) createInstance() error {
 
 		// Add debug messenger
 		instanceOptions.Next = app.debugMessengerOptions()
+
+		if app.validation.Enabled() {
+			validationExtension, next, err := app.validation.InstanceOptions(app.globalDriver, instanceOptions.Next)
+			if err != nil {
+				return err
+			}
+
+			instanceOptions.EnabledExtensionNames = append(instanceOptions.EnabledExtensionNames, validationExtension)
+			instanceOptions.Next = next
+		}
 	}
 
 	app.instanceDriver, _, err = app.globalDriver.CreateInstance(nil, instanceOptions)
@@ -661,10 +730,16 @@ func (app *HelloTriangleApplication) createInstance() error {
 }
 
 func (app *HelloTriangleApplication) debugMessengerOptions() ext_debug_utils.DebugUtilsMessengerCreateInfo {
+	severity := ext_debug_utils.SeverityError | ext_debug_utils.SeverityWarning
+	if app.validation.DebugPrintf {
+		// Shader printf output is delivered at info severity
+		severity |= ext_debug_utils.SeverityInfo
+	}
+
 	return ext_debug_utils.DebugUtilsMessengerCreateInfo{
-		MessageSeverity: ext_debug_utils.SeverityError | ext_debug_utils.SeverityWarning,
+		MessageSeverity: severity,
 		MessageType:     ext_debug_utils.TypeGeneral | ext_debug_utils.TypeValidation | ext_debug_utils.TypePerformance,
-		UserCallback:    app.logDebug,
+		UserCallback:    app.printf.Callback,
 	}
 }
 
@@ -752,23 +827,78 @@ func (app *HelloTriangleApplication) createLogicalDevice() error {
 		extensionNames = append(extensionNames, khr_portability_subset.ExtensionName)
 	}
 
+	// GPU-side breadcrumbs make device loss much easier to track down. They cost a marker
+	// write per breadcrumb, and with VK_AMD_buffer_marker a small host-visible buffer that
+	// AttachDevice allocates and keeps mapped for the life of the device.
+	markerExtension := breadcrumbs.DeviceExtension(extensions)
+	if markerExtension != "" {
+		extensionNames = append(extensionNames, markerExtension)
+	}
+
+	// Timeline semaphores give us a single frame counter to wait on, instead of a fence
+	// per frame in flight
+	frameSetup := framesync.Configure(app.physicalDevice, extensions)
+	if frameSetup.Extension != "" {
+		extensionNames = append(extensionNames, frameSetup.Extension)
+	}
+
 	app.deviceDriver, _, err = app.instanceDriver.CreateDevice(app.physicalDevice, nil, core1_0.DeviceCreateInfo{
 		QueueCreateInfos: queueFamilyOptions,
 		EnabledFeatures: &core1_0.PhysicalDeviceFeatures{
 			SamplerAnisotropy: true,
 		},
 		EnabledExtensionNames: extensionNames,
+		NextOptions:           common.NextOptions{Next: frameSetup.Features(nil)},
 	})
 	if err != nil {
 		return err
 	}
 
+	app.names = debugnames.New(app.instanceDriver, app.deviceDriver)
+
+	app.frames, err = framesync.New(app.deviceDriver, frameSetup)
+	if err != nil {
+		return err
+	}
+	app.deletions = deletion.New(app.deviceDriver, app.frames)
+
+	if app.frames.Timeline() {
+		err = app.names.Name(app.frames.Semaphore(), "Frame Counter")
+		if err != nil {
+			return err
+		}
+	}
+
+	app.pipelines = pipelineregistry.New(app.deviceDriver, nil)
+	app.pipelines.Names = app.names
+
+	app.breadcrumbs = breadcrumbs.New(0)
+	err = app.breadcrumbs.AttachDevice(app.deviceDriver, app.instanceDriver.GetPhysicalDeviceMemoryProperties(app.physicalDevice), extensionNames)
+	if err != nil {
+		return err
+	}
+
 	app.graphicsQueue = app.deviceDriver.GetQueue(*indices.GraphicsFamily, 0)
+	app.queues = queues.New(app.deviceDriver)
+	app.syncGraphicsQueue = app.queues.Get(app.graphicsQueue)
+	err = app.names.Name(app.graphicsQueue, "Graphics Queue")
+	if err != nil {
+		return err
+	}
+
 	app.presentQueue = app.deviceDriver.GetQueue(*indices.PresentFamily, 0)
-	return nil
+	app.syncPresentQueue = app.queues.Get(app.presentQueue)
+	if *indices.PresentFamily == *indices.GraphicsFamily {
+		return nil
+	}
+
+	return app.names.Name(app.presentQueue, "Present Queue")
 }
 
-func (app *HelloTriangleApplication) createSwapchain() error {
+// createSwapchain creates a swapchain for the surface. oldSwapchain, which may be left
+// uninitialized, is the swapchain being replaced; it is retired, but must still be
+// destroyed.
+func (app *HelloTriangleApplication) createSwapchain(oldSwapchain khr_swapchain.Swapchain) error {
 	app.swapchainExtension = khr_swapchain.CreateExtensionDriverFromCoreDriver(app.deviceDriver)
 
 	swapchainSupport, err := app.querySwapChainSupport(app.physicalDevice)
@@ -815,10 +945,17 @@ func (app *HelloTriangleApplication) createSwapchain() error {
 		CompositeAlpha: khr_surface.CompositeAlphaOpaque,
 		PresentMode:    presentMode,
 		Clipped:        true,
+		OldSwapchain:   oldSwapchain,
 	})
 	if err != nil {
 		return err
 	}
+
+	err = app.names.Name(swapchain, "Swapchain")
+	if err != nil {
+		return err
+	}
+
 	app.swapchainExtent = extent
 	app.swapchain = swapchain
 	app.swapchainImageFormat = surfaceFormat.Format
@@ -834,8 +971,13 @@ func (app *HelloTriangleApplication) createImageViews() error {
 	app.swapchainImages = images
 
 	var imageViews []core1_0.ImageView
-	for _, image := range images {
-		view, err := app.createImageView(image, app.swapchainImageFormat, core1_0.ImageAspectColor, 1)
+	for imageIndex, image := range images {
+		err = app.names.Namef(image, "Swapchain Image %d", imageIndex)
+		if err != nil {
+			return err
+		}
+
+		view, err := app.createImageView(image, app.swapchainImageFormat, core1_0.ImageAspectColor, 1, fmt.Sprintf("Swapchain Image View %d", imageIndex))
 		if err != nil {
 			return err
 		}
@@ -853,7 +995,7 @@ func (app *HelloTriangleApplication) createRenderPass() error {
 		return err
 	}
 
-	renderPass, _, err := app.deviceDriver.CreateRenderPass(nil, core1_0.RenderPassCreateInfo{
+	renderPassCreateInfo := core1_0.RenderPassCreateInfo{
 		Attachments: []core1_0.AttachmentDescription{
 			{
 				Format:         app.swapchainImageFormat,
@@ -919,19 +1061,25 @@ func (app *HelloTriangleApplication) createRenderPass() error {
 				DstAccessMask: core1_0.AccessColorAttachmentWrite | core1_0.AccessDepthStencilAttachmentWrite,
 			},
 		},
-	})
+	}
+
+	renderPass, _, err := app.deviceDriver.CreateRenderPass(nil, renderPassCreateInfo)
 	if err != nil {
 		return err
 	}
 
 	app.renderPass = renderPass
 
-	return nil
+	err = app.pipelines.DescribeRenderPass(app.renderPass, renderPassCreateInfo)
+	if err != nil {
+		return err
+	}
+
+	return app.names.Name(app.renderPass, "Main Render Pass")
 }
 
 func (app *HelloTriangleApplication) createDescriptorSetLayout() error {
-	var err error
-	app.descriptorSetLayout, _, err = app.deviceDriver.CreateDescriptorSetLayout(nil, core1_0.DescriptorSetLayoutCreateInfo{
+	descriptorSetLayoutCreateInfo := core1_0.DescriptorSetLayoutCreateInfo{
 		Bindings: []core1_0.DescriptorSetLayoutBinding{
 			{
 				Binding:         0,
@@ -948,12 +1096,25 @@ func (app *HelloTriangleApplication) createDescriptorSetLayout() error {
 				StageFlags: core1_0.StageFragment,
 			},
 		},
-	})
+	}
+
+	var err error
+	app.descriptorSetLayout, _, err = app.deviceDriver.CreateDescriptorSetLayout(nil, descriptorSetLayoutCreateInfo)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = app.pipelines.DescribeDescriptorSetLayout(app.descriptorSetLayout, descriptorSetLayoutCreateInfo)
+	if err != nil {
+		return err
+	}
+
+	app.descriptorLayout, err = descriptors.NewLayout(app.descriptorSetLayout, descriptorSetLayoutCreateInfo, "ubo", "texSampler")
+	if err != nil {
+		return err
+	}
+
+	return app.names.Name(app.descriptorSetLayout, "Main Descriptor Set Layout")
 }
 
 func bytesToBytecode(b []byte) []uint32 {
@@ -977,13 +1138,49 @@ func (app *HelloTriangleApplication) createGraphicsPipeline() error {
 		return err
 	}
 
-	vertShader, _, err := app.deviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
+	vertShaderCreateInfo := core1_0.ShaderModuleCreateInfo{
 		Code: bytesToBytecode(vertShaderBytes),
-	})
+	}
+	vertShader, _, err := app.deviceDriver.CreateShaderModule(nil, vertShaderCreateInfo)
 	if err != nil {
 		return err
 	}
 	defer app.deviceDriver.DestroyShaderModule(vertShader, nil)
+	defer app.pipelines.Forget(vertShader)
+
+	err = app.pipelines.DescribeShaderModule(vertShader, vertShaderCreateInfo)
+	if err != nil {
+		return err
+	}
+
+	err = app.names.Name(vertShader, "shaders/vert.spv")
+	if err != nil {
+		return err
+	}
+
+	vertexInput, err := vertexLayout()
+	if err != nil {
+		return err
+	}
+
+	if enableValidationLayers {
+		// Catch UniformBufferObject drifting out of sync with the shader's ubo block
+		uniformLayout, err := uniformBufferLayout()
+		if err != nil {
+			return err
+		}
+
+		err = uniformLayout.CheckBlock(vertShaderCreateInfo.Code, "ubo")
+		if err != nil {
+			return errors.Wrap(err, "shaders/vert.spv")
+		}
+
+		// ...and Vertex drifting out of sync with its inputs
+		err = vertexInput.Check(vertShaderCreateInfo.Code)
+		if err != nil {
+			return errors.Wrap(err, "shaders/vert.spv")
+		}
+	}
 
 	// Load fragment shader
 	fragShaderBytes, err := fileSystem.ReadFile("shaders/frag.spv")
@@ -991,17 +1188,24 @@ func (app *HelloTriangleApplication) createGraphicsPipeline() error {
 		return err
 	}
 
-	fragShader, _, err := app.deviceDriver.CreateShaderModule(nil, core1_0.ShaderModuleCreateInfo{
+	fragShaderCreateInfo := core1_0.ShaderModuleCreateInfo{
 		Code: bytesToBytecode(fragShaderBytes),
-	})
+	}
+	fragShader, _, err := app.deviceDriver.CreateShaderModule(nil, fragShaderCreateInfo)
 	if err != nil {
 		return err
 	}
 	defer app.deviceDriver.DestroyShaderModule(fragShader, nil)
+	defer app.pipelines.Forget(fragShader)
 
-	vertexInput := &core1_0.PipelineVertexInputStateCreateInfo{
-		VertexBindingDescriptions:   getVertexBindingDescription(),
-		VertexAttributeDescriptions: getVertexAttributeDescriptions(),
+	err = app.pipelines.DescribeShaderModule(fragShader, fragShaderCreateInfo)
+	if err != nil {
+		return err
+	}
+
+	err = app.names.Name(fragShader, "shaders/frag.spv")
+	if err != nil {
+		return err
 	}
 
 	inputAssembly := &core1_0.PipelineInputAssemblyStateCreateInfo{
@@ -1021,22 +1225,17 @@ func (app *HelloTriangleApplication) createGraphicsPipeline() error {
 		Name:   "main",
 	}
 
+	// The viewport and scissor are dynamic so that resizing the window doesn't change the
+	// pipeline description
 	viewport := &core1_0.PipelineViewportStateCreateInfo{
-		Viewports: []core1_0.Viewport{
-			{
-				X:        0,
-				Y:        0,
-				Width:    float32(app.swapchainExtent.Width),
-				Height:   float32(app.swapchainExtent.Height),
-				MinDepth: 0,
-				MaxDepth: 1,
-			},
-		},
-		Scissors: []core1_0.Rect2D{
-			{
-				Offset: core1_0.Offset2D{X: 0, Y: 0},
-				Extent: app.swapchainExtent,
-			},
+		Viewports: []core1_0.Viewport{{}},
+		Scissors:  []core1_0.Rect2D{{}},
+	}
+
+	dynamic := &core1_0.PipelineDynamicStateCreateInfo{
+		DynamicStates: []core1_0.DynamicState{
+			core1_0.DynamicStateViewport,
+			core1_0.DynamicStateScissor,
 		},
 	}
 
@@ -1078,41 +1277,51 @@ func (app *HelloTriangleApplication) createGraphicsPipeline() error {
 		},
 	}
 
-	app.pipelineLayout, _, err = app.deviceDriver.CreatePipelineLayout(nil, core1_0.PipelineLayoutCreateInfo{
+	pipelineLayoutCreateInfo := core1_0.PipelineLayoutCreateInfo{
 		SetLayouts: []core1_0.DescriptorSetLayout{
 			app.descriptorSetLayout,
 		},
-	})
+	}
+	app.pipelineLayout, _, err = app.deviceDriver.CreatePipelineLayout(nil, pipelineLayoutCreateInfo)
+	if err != nil {
+		return err
+	}
+
+	err = app.pipelines.DescribePipelineLayout(app.pipelineLayout, pipelineLayoutCreateInfo)
+	if err != nil {
+		return err
+	}
 
-	pipelines, _, err := app.deviceDriver.CreateGraphicsPipelines(nil, nil,
+	err = app.names.Name(app.pipelineLayout, "Main Pipeline Layout")
+	if err != nil {
+		return err
+	}
+
+	app.graphicsPipeline, err = app.pipelines.AcquireGraphics("Main Graphics Pipeline",
 		core1_0.GraphicsPipelineCreateInfo{
 			Stages: []core1_0.PipelineShaderStageCreateInfo{
 				vertStage,
 				fragStage,
 			},
-			VertexInputState:   vertexInput,
+			VertexInputState:   vertexInput.CreateInfo(),
 			InputAssemblyState: inputAssembly,
 			ViewportState:      viewport,
 			RasterizationState: rasterization,
 			MultisampleState:   multisample,
 			DepthStencilState:  depthStencil,
 			ColorBlendState:    colorBlend,
+			DynamicState:       dynamic,
 			Layout:             app.pipelineLayout,
 			RenderPass:         app.renderPass,
 			Subpass:            0,
 			BasePipelineIndex:  -1,
 		},
 	)
-	if err != nil {
-		return err
-	}
-	app.graphicsPipeline = pipelines[0]
-
-	return nil
+	return err
 }
 
 func (app *HelloTriangleApplication) createFramebuffers() error {
-	for _, imageView := range app.swapchainImageViews {
+	for imageIndex, imageView := range app.swapchainImageViews {
 		framebuffer, _, err := app.deviceDriver.CreateFramebuffer(nil, core1_0.FramebufferCreateInfo{
 			RenderPass: app.renderPass,
 			Layers:     1,
@@ -1128,6 +1337,11 @@ func (app *HelloTriangleApplication) createFramebuffers() error {
 			return err
 		}
 
+		err = app.names.Namef(framebuffer, "Swapchain Framebuffer %d", imageIndex)
+		if err != nil {
+			return err
+		}
+
 		app.swapchainFramebuffers = append(app.swapchainFramebuffers, framebuffer)
 	}
 
@@ -1149,7 +1363,7 @@ func (app *HelloTriangleApplication) createCommandPool() error {
 	}
 	app.commandPool = pool
 
-	return nil
+	return app.names.Name(app.commandPool, "Main Command Pool")
 }
 
 func (app *HelloTriangleApplication) createColorResources() error {
@@ -1162,7 +1376,8 @@ func (app *HelloTriangleApplication) createColorResources() error {
 		app.swapchainImageFormat,
 		core1_0.ImageTilingOptimal,
 		core1_0.ImageUsageTransientAttachment|core1_0.ImageUsageColorAttachment,
-		core1_0.MemoryPropertyDeviceLocal)
+		core1_0.MemoryPropertyDeviceLocal,
+		"MSAA Color Image")
 	if err != nil {
 		return err
 	}
@@ -1171,7 +1386,8 @@ func (app *HelloTriangleApplication) createColorResources() error {
 		app.colorImage,
 		app.swapchainImageFormat,
 		core1_0.ImageAspectColor,
-		1)
+		1,
+		"MSAA Color Image View")
 	return err
 }
 
@@ -1188,11 +1404,12 @@ func (app *HelloTriangleApplication) createDepthResources() error {
 		depthFormat,
 		core1_0.ImageTilingOptimal,
 		core1_0.ImageUsageDepthStencilAttachment,
-		core1_0.MemoryPropertyDeviceLocal)
+		core1_0.MemoryPropertyDeviceLocal,
+		"Depth Image")
 	if err != nil {
 		return err
 	}
-	app.depthImageView, err = app.createImageView(app.depthImage, depthFormat, core1_0.ImageAspectDepth, 1)
+	app.depthImageView, err = app.createImageView(app.depthImage, depthFormat, core1_0.ImageAspectDepth, 1, "Depth Image View")
 	return err
 }
 
@@ -1233,7 +1450,7 @@ func (app *HelloTriangleApplication) createTextureImage() error {
 
 	app.mipLevels = int(math.Log2(math.Max(float64(imageDims.X), float64(imageDims.Y))))
 
-	stagingBuffer, stagingMemory, err := app.createBuffer(imageSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+	stagingBuffer, stagingMemory, err := app.createBuffer(imageSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent, "Texture Staging Buffer")
 	if err != nil {
 		return err
 	}
@@ -1250,7 +1467,7 @@ func (app *HelloTriangleApplication) createTextureImage() error {
 		}
 	}
 
-	err = writeData(app.deviceDriver, stagingMemory, 0, pixelData)
+	err = upload.Write(app.deviceDriver, stagingMemory, 0, pixelData)
 	if err != nil {
 		return err
 	}
@@ -1263,7 +1480,8 @@ func (app *HelloTriangleApplication) createTextureImage() error {
 		core1_0.FormatR8G8B8A8SRGB,
 		core1_0.ImageTilingOptimal,
 		core1_0.ImageUsageTransferSrc|core1_0.ImageUsageTransferDst|core1_0.ImageUsageSampled,
-		core1_0.MemoryPropertyDeviceLocal)
+		core1_0.MemoryPropertyDeviceLocal,
+		"images/viking_room.png")
 	if err != nil {
 		return err
 	}
@@ -1423,7 +1641,7 @@ func (app *HelloTriangleApplication) getMaxUsableSampleCount() (core1_0.SampleCo
 
 func (app *HelloTriangleApplication) createTextureImageView() error {
 	var err error
-	app.textureImageView, err = app.createImageView(app.textureImage, core1_0.FormatR8G8B8A8SRGB, core1_0.ImageAspectColor, app.mipLevels)
+	app.textureImageView, err = app.createImageView(app.textureImage, core1_0.FormatR8G8B8A8SRGB, core1_0.ImageAspectColor, app.mipLevels, "images/viking_room.png View")
 	return err
 }
 
@@ -1449,11 +1667,14 @@ func (app *HelloTriangleApplication) createSampler() error {
 		MinLod:     0,
 		MaxLod:     float32(app.mipLevels),
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	return app.names.Name(app.textureSampler, "Texture Sampler")
 }
 
-func (app *HelloTriangleApplication) createImageView(image core1_0.Image, format core1_0.Format, aspect core1_0.ImageAspectFlags, mipLevels int) (core1_0.ImageView, error) {
+func (app *HelloTriangleApplication) createImageView(image core1_0.Image, format core1_0.Format, aspect core1_0.ImageAspectFlags, mipLevels int, name string) (core1_0.ImageView, error) {
 	imageView, _, err := app.deviceDriver.CreateImageView(nil, core1_0.ImageViewCreateInfo{
 		Image:    image,
 		ViewType: core1_0.ImageViewType2D,
@@ -1466,10 +1687,14 @@ func (app *HelloTriangleApplication) createImageView(image core1_0.Image, format
 			LayerCount:     1,
 		},
 	})
-	return imageView, err
+	if err != nil {
+		return core1_0.ImageView{}, err
+	}
+
+	return imageView, app.names.Name(imageView, name)
 }
 
-func (app *HelloTriangleApplication) createImage(width, height int, mipLevels int, numSamples core1_0.SampleCountFlags, format core1_0.Format, tiling core1_0.ImageTiling, usage core1_0.ImageUsageFlags, memoryProperties core1_0.MemoryPropertyFlags) (core1_0.Image, core1_0.DeviceMemory, error) {
+func (app *HelloTriangleApplication) createImage(width, height int, mipLevels int, numSamples core1_0.SampleCountFlags, format core1_0.Format, tiling core1_0.ImageTiling, usage core1_0.ImageUsageFlags, memoryProperties core1_0.MemoryPropertyFlags, name string) (core1_0.Image, core1_0.DeviceMemory, error) {
 	image, _, err := app.deviceDriver.CreateImage(nil, core1_0.ImageCreateInfo{
 		ImageType: core1_0.ImageType2D,
 		Extent: core1_0.Extent3D{
@@ -1490,6 +1715,11 @@ func (app *HelloTriangleApplication) createImage(width, height int, mipLevels in
 		return core1_0.Image{}, core1_0.DeviceMemory{}, err
 	}
 
+	err = app.names.Name(image, name)
+	if err != nil {
+		return image, core1_0.DeviceMemory{}, err
+	}
+
 	memReqs := app.deviceDriver.GetImageMemoryRequirements(image)
 	memoryIndex, err := app.findMemoryType(memReqs.MemoryTypeBits, memoryProperties)
 	if err != nil {
@@ -1500,6 +1730,14 @@ func (app *HelloTriangleApplication) createImage(width, height int, mipLevels in
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memoryIndex,
 	})
+	if err != nil {
+		return image, core1_0.DeviceMemory{}, err
+	}
+
+	err = app.names.Name(imageMemory, name+" Memory")
+	if err != nil {
+		return image, imageMemory, err
+	}
 
 	_, err = app.deviceDriver.BindImageMemory(image, imageMemory, 0)
 	if err != nil {
@@ -1586,50 +1824,25 @@ func (app *HelloTriangleApplication) copyBufferToImage(buffer core1_0.Buffer, im
 	return app.endSingleTimeCommands(cmdBuffer)
 }
 
-func writeData(driver core1_0.DeviceDriver, memory core1_0.DeviceMemory, offset int, data any) error {
-	bufferSize := binary.Size(data)
-
-	memoryPtr, _, err := driver.MapMemory(memory, offset, bufferSize, 0)
-	if err != nil {
-		return err
-	}
-	defer driver.UnmapMemory(memory)
-
-	dataBuffer := unsafe.Slice((*byte)(memoryPtr), bufferSize)
-
-	buf := &bytes.Buffer{}
-	err = binary.Write(buf, common.ByteOrder, data)
-	if err != nil {
-		return err
-	}
-
-	copy(dataBuffer, buf.Bytes())
-	return nil
+// objVertexKey identifies a vertex in an OBJ file: the same position can appear with
+// different texture coordinates on either side of a UV seam
+type objVertexKey struct {
+	position, uv int
 }
 
-func (app *HelloTriangleApplication) addVertex(decoder *obj.Decoder, uniqueVertices map[int]uint32, face obj.Face, faceIndex int) {
-	vertInd := face.Vertices[faceIndex]
-	index, vertexExists := uniqueVertices[vertInd]
-
-	if !vertexExists {
-		vert := Vertex{Position: vkngmath.Vec3[float32]{
-			decoder.Vertices[vertInd*3],
-			decoder.Vertices[vertInd*3+1],
-			decoder.Vertices[vertInd*3+2],
-		}, Color: vkngmath.Vec3[float32]{1, 1, 1}}
-
-		uvInd := face.Uvs[faceIndex]
-		vert.TexCoord = vkngmath.Vec2[float32]{
-			decoder.Uvs[uvInd*2],
-			1.0 - decoder.Uvs[uvInd*2+1],
-		}
-
-		index = uint32(len(app.vertices))
-		app.vertices = append(app.vertices, vert)
-		uniqueVertices[vertInd] = index
+func objVertex(decoder *obj.Decoder, key objVertexKey) Vertex {
+	return Vertex{
+		Position: vkngmath.Vec3[float32]{
+			decoder.Vertices[key.position*3],
+			decoder.Vertices[key.position*3+1],
+			decoder.Vertices[key.position*3+2],
+		},
+		Color: vkngmath.Vec3[float32]{1, 1, 1},
+		TexCoord: vkngmath.Vec2[float32]{
+			decoder.Uvs[key.uv*2],
+			1.0 - decoder.Uvs[key.uv*2+1],
+		},
 	}
-
-	app.indices = append(app.indices, index)
 }
 
 func (app *HelloTriangleApplication) loadModel() error {
@@ -1650,27 +1863,29 @@ func (app *HelloTriangleApplication) loadModel() error {
 		return err
 	}
 
-	uniqueVertices := make(map[int]uint32)
+	builder := mesh.NewBuilder[objVertexKey, Vertex]()
 
 	for _, decodedObj := range decoder.Objects {
 		for _, face := range decodedObj.Faces {
 			// We need to triangularize faces
 			for i := 2; i < len(face.Vertices); i++ {
-				app.addVertex(decoder, uniqueVertices, face, 0)
-				app.addVertex(decoder, uniqueVertices, face, i-1)
-				app.addVertex(decoder, uniqueVertices, face, i)
+				for _, faceIndex := range []int{0, i - 1, i} {
+					key := objVertexKey{position: face.Vertices[faceIndex], uv: face.Uvs[faceIndex]}
+					builder.Add(key, func() Vertex { return objVertex(decoder, key) })
+				}
 			}
 		}
 	}
 
+	app.model = &builder.Mesh
 	return nil
 }
 
 func (app *HelloTriangleApplication) createVertexBuffer() error {
 	var err error
-	bufferSize := binary.Size(app.vertices)
+	bufferSize := binary.Size(app.model.Vertices)
 
-	stagingBuffer, stagingBufferMemory, err := app.createBuffer(bufferSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+	stagingBuffer, stagingBufferMemory, err := app.createBuffer(bufferSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent, "Vertex Staging Buffer")
 	if stagingBuffer.Initialized() {
 		defer app.deviceDriver.DestroyBuffer(stagingBuffer, nil)
 	}
@@ -1682,12 +1897,12 @@ func (app *HelloTriangleApplication) createVertexBuffer() error {
 		return err
 	}
 
-	err = writeData(app.deviceDriver, stagingBufferMemory, 0, app.vertices)
+	err = upload.Write(app.deviceDriver, stagingBufferMemory, 0, app.model.Vertices)
 	if err != nil {
 		return err
 	}
 
-	app.vertexBuffer, app.vertexBufferMemory, err = app.createBuffer(bufferSize, core1_0.BufferUsageTransferDst|core1_0.BufferUsageVertexBuffer, core1_0.MemoryPropertyDeviceLocal)
+	app.vertexBuffer, app.vertexBufferMemory, err = app.createBuffer(bufferSize, core1_0.BufferUsageTransferDst|core1_0.BufferUsageVertexBuffer, core1_0.MemoryPropertyDeviceLocal, "Vertex Buffer")
 	if err != nil {
 		return err
 	}
@@ -1696,9 +1911,10 @@ func (app *HelloTriangleApplication) createVertexBuffer() error {
 }
 
 func (app *HelloTriangleApplication) createIndexBuffer() error {
-	bufferSize := binary.Size(app.indices)
+	indexData := app.model.IndexData()
+	bufferSize := binary.Size(indexData)
 
-	stagingBuffer, stagingBufferMemory, err := app.createBuffer(bufferSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+	stagingBuffer, stagingBufferMemory, err := app.createBuffer(bufferSize, core1_0.BufferUsageTransferSrc, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent, "Index Staging Buffer")
 	if stagingBuffer.Initialized() {
 		defer app.deviceDriver.DestroyBuffer(stagingBuffer, nil)
 	}
@@ -1710,12 +1926,12 @@ func (app *HelloTriangleApplication) createIndexBuffer() error {
 		return err
 	}
 
-	err = writeData(app.deviceDriver, stagingBufferMemory, 0, app.indices)
+	err = upload.WriteValue(app.deviceDriver, stagingBufferMemory, 0, indexData)
 	if err != nil {
 		return err
 	}
 
-	app.indexBuffer, app.indexBufferMemory, err = app.createBuffer(bufferSize, core1_0.BufferUsageTransferDst|core1_0.BufferUsageIndexBuffer, core1_0.MemoryPropertyDeviceLocal)
+	app.indexBuffer, app.indexBufferMemory, err = app.createBuffer(bufferSize, core1_0.BufferUsageTransferDst|core1_0.BufferUsageIndexBuffer, core1_0.MemoryPropertyDeviceLocal, "Index Buffer")
 	if err != nil {
 		return err
 	}
@@ -1724,10 +1940,14 @@ func (app *HelloTriangleApplication) createIndexBuffer() error {
 }
 
 func (app *HelloTriangleApplication) createUniformBuffers() error {
-	bufferSize := int(unsafe.Sizeof(UniformBufferObject{}))
+	uniformLayout, err := uniformBufferLayout()
+	if err != nil {
+		return err
+	}
+	bufferSize := uniformLayout.Size
 
 	for i := 0; i < len(app.swapchainImages); i++ {
-		buffer, memory, err := app.createBuffer(bufferSize, core1_0.BufferUsageUniformBuffer, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent)
+		buffer, memory, err := app.createBuffer(bufferSize, core1_0.BufferUsageUniformBuffer, core1_0.MemoryPropertyHostVisible|core1_0.MemoryPropertyHostCoherent, fmt.Sprintf("Uniform Buffer %d", i))
 		if err != nil {
 			return err
 		}
@@ -1739,72 +1959,46 @@ func (app *HelloTriangleApplication) createUniformBuffers() error {
 	return nil
 }
 
-func (app *HelloTriangleApplication) createDescriptorPool() error {
-	var err error
-	app.descriptorPool, _, err = app.deviceDriver.CreateDescriptorPool(nil, core1_0.DescriptorPoolCreateInfo{
-		MaxSets: len(app.swapchainImages),
-		PoolSizes: []core1_0.DescriptorPoolSize{
-			{
-				Type:            core1_0.DescriptorTypeUniformBuffer,
-				DescriptorCount: len(app.swapchainImages),
-			},
-			{
-				Type:            core1_0.DescriptorTypeCombinedImageSampler,
-				DescriptorCount: len(app.swapchainImages),
-			},
-		},
+func (app *HelloTriangleApplication) createDescriptorAllocator() error {
+	app.descriptors = descriptors.NewAllocator(app.deviceDriver, len(app.swapchainImages), []descriptors.PoolRatio{
+		{Type: core1_0.DescriptorTypeUniformBuffer, PerSet: 1},
+		{Type: core1_0.DescriptorTypeCombinedImageSampler, PerSet: 1},
 	})
-	return err
+	app.descriptors.Names = app.names
+	app.descriptors.Name = "Main Descriptor Pool"
+	app.descriptorWriter = descriptors.NewWriter(app.deviceDriver, app.descriptors)
+
+	return nil
 }
 
 func (app *HelloTriangleApplication) createDescriptorSets() error {
-	var allocLayouts []core1_0.DescriptorSetLayout
+	uniformLayout, err := uniformBufferLayout()
+	if err != nil {
+		return err
+	}
+
+	var groups []*descriptors.Group
 	for i := 0; i < len(app.swapchainImages); i++ {
-		allocLayouts = append(allocLayouts, app.descriptorSetLayout)
+		groups = append(groups, app.descriptorLayout.Group().
+			Buffer(descriptors.Named("ubo"), core1_0.DescriptorBufferInfo{
+				Buffer: app.uniformBuffers[i],
+				Offset: 0,
+				Range:  uniformLayout.Size,
+			}).
+			Image(descriptors.Named("texSampler"), core1_0.DescriptorImageInfo{
+				ImageView:   app.textureImageView,
+				Sampler:     app.textureSampler,
+				ImageLayout: core1_0.ImageLayoutShaderReadOnlyOptimal,
+			}))
 	}
 
-	var err error
-	app.descriptorSets, _, err = app.deviceDriver.AllocateDescriptorSets(core1_0.DescriptorSetAllocateInfo{
-		DescriptorPool: app.descriptorPool,
-		SetLayouts:     allocLayouts,
-	})
+	app.descriptorSets, err = app.descriptorWriter.WriteAll(groups...)
 	if err != nil {
 		return err
 	}
 
 	for i := 0; i < len(app.swapchainImages); i++ {
-		err = app.deviceDriver.UpdateDescriptorSets([]core1_0.WriteDescriptorSet{
-			{
-				DstSet:          app.descriptorSets[i],
-				DstBinding:      0,
-				DstArrayElement: 0,
-
-				DescriptorType: core1_0.DescriptorTypeUniformBuffer,
-
-				BufferInfo: []core1_0.DescriptorBufferInfo{
-					{
-						Buffer: app.uniformBuffers[i],
-						Offset: 0,
-						Range:  int(unsafe.Sizeof(UniformBufferObject{})),
-					},
-				},
-			},
-			{
-				DstSet:          app.descriptorSets[i],
-				DstBinding:      1,
-				DstArrayElement: 0,
-
-				DescriptorType: core1_0.DescriptorTypeCombinedImageSampler,
-
-				ImageInfo: []core1_0.DescriptorImageInfo{
-					{
-						ImageView:   app.textureImageView,
-						Sampler:     app.textureSampler,
-						ImageLayout: core1_0.ImageLayoutShaderReadOnlyOptimal,
-					},
-				},
-			},
-		}, nil)
+		err = app.names.Namef(app.descriptorSets[i], "Descriptor Set %d", i)
 		if err != nil {
 			return err
 		}
@@ -1813,7 +2007,7 @@ func (app *HelloTriangleApplication) createDescriptorSets() error {
 	return nil
 }
 
-func (app *HelloTriangleApplication) createBuffer(size int, usage core1_0.BufferUsageFlags, properties core1_0.MemoryPropertyFlags) (core1_0.Buffer, core1_0.DeviceMemory, error) {
+func (app *HelloTriangleApplication) createBuffer(size int, usage core1_0.BufferUsageFlags, properties core1_0.MemoryPropertyFlags, name string) (core1_0.Buffer, core1_0.DeviceMemory, error) {
 	buffer, _, err := app.deviceDriver.CreateBuffer(nil, core1_0.BufferCreateInfo{
 		Size:        size,
 		Usage:       usage,
@@ -1823,6 +2017,11 @@ func (app *HelloTriangleApplication) createBuffer(size int, usage core1_0.Buffer
 		return core1_0.Buffer{}, core1_0.DeviceMemory{}, err
 	}
 
+	err = app.names.Name(buffer, name)
+	if err != nil {
+		return buffer, core1_0.DeviceMemory{}, err
+	}
+
 	memRequirements := app.deviceDriver.GetBufferMemoryRequirements(buffer)
 	memoryTypeIndex, err := app.findMemoryType(memRequirements.MemoryTypeBits, properties)
 	if err != nil {
@@ -1837,6 +2036,11 @@ func (app *HelloTriangleApplication) createBuffer(size int, usage core1_0.Buffer
 		return buffer, core1_0.DeviceMemory{}, err
 	}
 
+	err = app.names.Name(memory, name+" Memory")
+	if err != nil {
+		return buffer, memory, err
+	}
+
 	_, err = app.deviceDriver.BindBufferMemory(buffer, memory, 0)
 	return buffer, memory, err
 }
@@ -1864,7 +2068,7 @@ func (app *HelloTriangleApplication) endSingleTimeCommands(buffer core1_0.Comman
 		return err
 	}
 
-	_, err = app.deviceDriver.QueueSubmit(app.graphicsQueue, nil,
+	_, err = app.syncGraphicsQueue.Submit(nil,
 		core1_0.SubmitInfo{
 			CommandBuffers: []core1_0.CommandBuffer{buffer},
 		},
@@ -1874,7 +2078,7 @@ func (app *HelloTriangleApplication) endSingleTimeCommands(buffer core1_0.Comman
 		return err
 	}
 
-	_, err = app.deviceDriver.QueueWaitIdle(app.graphicsQueue)
+	_, err = app.syncGraphicsQueue.WaitIdle()
 	if err != nil {
 		return err
 	}
@@ -1929,10 +2133,19 @@ func (app *HelloTriangleApplication) createCommandBuffers() error {
 	app.commandBuffers = buffers
 
 	for bufferIdx, buffer := range buffers {
+		err = app.names.Namef(buffer, "Swapchain Image %d Commands", bufferIdx)
+		if err != nil {
+			return err
+		}
+
 		_, err = app.deviceDriver.BeginCommandBuffer(buffer, core1_0.CommandBufferBeginInfo{})
 		if err != nil {
 			return err
 		}
+		app.breadcrumbs.Reset(buffer)
+
+		renderPassLabel := app.names.BeginCommandLabel(buffer, "Main Render Pass", nil)
+		app.breadcrumbs.Pass(buffer, "Main Render Pass")
 
 		err = app.deviceDriver.CmdBeginRenderPass(buffer, core1_0.SubpassContentsInline,
 			core1_0.RenderPassBeginInfo{
@@ -1952,13 +2165,33 @@ func (app *HelloTriangleApplication) createCommandBuffers() error {
 		}
 
 		app.deviceDriver.CmdBindPipeline(buffer, core1_0.PipelineBindPointGraphics, app.graphicsPipeline)
+		app.breadcrumbs.BindPipeline(buffer, "Main Graphics Pipeline")
+
+		app.deviceDriver.CmdSetViewport(buffer, core1_0.Viewport{
+			X:        0,
+			Y:        0,
+			Width:    float32(app.swapchainExtent.Width),
+			Height:   float32(app.swapchainExtent.Height),
+			MinDepth: 0,
+			MaxDepth: 1,
+		})
+		app.deviceDriver.CmdSetScissor(buffer, core1_0.Rect2D{
+			Offset: core1_0.Offset2D{X: 0, Y: 0},
+			Extent: app.swapchainExtent,
+		})
+
+		drawLabel := app.names.BeginCommandLabel(buffer, "meshes/viking_room.obj", nil)
 		app.deviceDriver.CmdBindVertexBuffers(buffer, 0, []core1_0.Buffer{app.vertexBuffer}, []int{0})
-		app.deviceDriver.CmdBindIndexBuffer(buffer, app.indexBuffer, 0, core1_0.IndexTypeUInt32)
+		app.deviceDriver.CmdBindIndexBuffer(buffer, app.indexBuffer, 0, app.model.IndexType())
 		app.deviceDriver.CmdBindDescriptorSets(buffer, core1_0.PipelineBindPointGraphics, app.pipelineLayout, 0, []core1_0.DescriptorSet{
 			app.descriptorSets[bufferIdx],
 		}, nil)
-		app.deviceDriver.CmdDrawIndexed(buffer, len(app.indices), 1, 0, 0, 0)
+		app.breadcrumbs.Draw(buffer, "meshes/viking_room.obj")
+		app.deviceDriver.CmdDrawIndexed(buffer, len(app.model.Indices), 1, 0, 0, 0)
+		drawLabel.End()
+
 		app.deviceDriver.CmdEndRenderPass(buffer)
+		renderPassLabel.End()
 
 		_, err = app.deviceDriver.EndCommandBuffer(buffer)
 		if err != nil {
@@ -1976,17 +2209,14 @@ func (app *HelloTriangleApplication) createSyncObjects() error {
 			return err
 		}
 
-		app.imageAvailableSemaphore = append(app.imageAvailableSemaphore, semaphore)
-
-		fence, _, err := app.deviceDriver.CreateFence(nil, core1_0.FenceCreateInfo{
-			Flags: core1_0.FenceCreateSignaled,
-		})
+		err = app.names.Namef(semaphore, "Frame %d Image Available", i)
 		if err != nil {
 			return err
 		}
 
-		app.inFlightFence = append(app.inFlightFence, fence)
+		app.imageAvailableSemaphore = append(app.imageAvailableSemaphore, semaphore)
 	}
+	app.inFlightFrames = make([]frameSubmission, MaxFramesInFlight)
 
 	for i := 0; i < len(app.swapchainImages); i++ {
 		semaphore, _, err := app.deviceDriver.CreateSemaphore(nil, core1_0.SemaphoreCreateInfo{})
@@ -1994,18 +2224,38 @@ func (app *HelloTriangleApplication) createSyncObjects() error {
 			return err
 		}
 
+		err = app.names.Namef(semaphore, "Swapchain Image %d Render Finished", i)
+		if err != nil {
+			return err
+		}
+
 		app.renderFinishedSemaphore = append(app.renderFinishedSemaphore, semaphore)
+	}
+	app.imagesInFlight = make([]frameSubmission, len(app.swapchainImages))
+
+	return nil
+}
 
-		app.imagesInFlight = append(app.imagesInFlight, core1_0.Fence{})
+// waitForFrame blocks until the GPU has finished submitted, and marks its breadcrumbs
+// complete
+func (app *HelloTriangleApplication) waitForFrame(submitted frameSubmission) error {
+	res, err := app.frames.Wait(context.Background(), submitted.frame)
+	app.breadcrumbs.Check(res)
+	if err != nil {
+		return err
 	}
 
+	app.breadcrumbs.CompleteSubmission(submitted.breadcrumbs)
 	return nil
 }
 
 func (app *HelloTriangleApplication) drawFrame() error {
-	fences := []core1_0.Fence{app.inFlightFence[app.currentFrame]}
+	err := app.waitForFrame(app.inFlightFrames[app.currentFrame])
+	if err != nil {
+		return err
+	}
 
-	_, err := app.deviceDriver.WaitForFences(true, common.NoTimeout, fences...)
+	_, err = app.deletions.Collect()
 	if err != nil {
 		return err
 	}
@@ -2017,25 +2267,26 @@ func (app *HelloTriangleApplication) drawFrame() error {
 		return err
 	}
 
-	if app.imagesInFlight[imageIndex].Initialized() {
-		_, err := app.deviceDriver.WaitForFences(true, common.NoTimeout, app.imagesInFlight[imageIndex])
-		if err != nil {
-			return err
-		}
-	}
-	app.imagesInFlight[imageIndex] = app.inFlightFence[app.currentFrame]
-
-	_, err = app.deviceDriver.ResetFences(fences...)
+	// The swapchain may hand back an image that an older frame is still rendering to
+	err = app.waitForFrame(app.imagesInFlight[imageIndex])
 	if err != nil {
 		return err
 	}
 
+	app.frameNumber++
+	app.printf.BeginFrame(app.frameNumber)
+	app.breadcrumbs.BeginFrame(app.frameNumber)
+	app.printf.TrackCommandBuffers(app.frameNumber, app.commandBuffers[imageIndex])
+
 	err = app.updateUniformBuffer(imageIndex)
 	if err != nil {
 		return err
 	}
 
-	_, err = app.deviceDriver.QueueSubmit(app.graphicsQueue, &app.inFlightFence[app.currentFrame],
+	frameLabel := app.names.BeginQueueLabel(app.graphicsQueue, fmt.Sprintf("Frame %d", app.frameNumber), nil)
+	defer frameLabel.End()
+
+	frame, res, err := app.frames.Submit(app.syncGraphicsQueue,
 		core1_0.SubmitInfo{
 			WaitSemaphores:   []core1_0.Semaphore{app.imageAvailableSemaphore[app.currentFrame]},
 			WaitDstStageMask: []core1_0.PipelineStageFlags{core1_0.PipelineStageColorAttachmentOutput},
@@ -2043,15 +2294,23 @@ func (app *HelloTriangleApplication) drawFrame() error {
 			SignalSemaphores: []core1_0.Semaphore{app.renderFinishedSemaphore[imageIndex]},
 		},
 	)
+	app.breadcrumbs.Check(res)
 	if err != nil {
 		return err
 	}
+	submitted := frameSubmission{
+		frame:       frame,
+		breadcrumbs: app.breadcrumbs.Submitted(app.graphicsQueue, core1_0.Fence{}, app.commandBuffers[imageIndex]),
+	}
+	app.inFlightFrames[app.currentFrame] = submitted
+	app.imagesInFlight[imageIndex] = submitted
 
-	res, err = app.swapchainExtension.QueuePresent(app.presentQueue, khr_swapchain.PresentInfo{
+	res, err = app.syncPresentQueue.Present(app.swapchainExtension, khr_swapchain.PresentInfo{
 		WaitSemaphores: []core1_0.Semaphore{app.renderFinishedSemaphore[imageIndex]},
 		Swapchains:     []khr_swapchain.Swapchain{app.swapchain},
 		ImageIndices:   []int{imageIndex},
 	})
+	app.breadcrumbs.Check(res)
 	if res == khr_swapchain.VKErrorOutOfDate || res == khr_swapchain.VKSuboptimal {
 		return app.recreateSwapChain()
 	} else if err != nil {
@@ -2083,8 +2342,19 @@ func (app *HelloTriangleApplication) updateUniformBuffer(currentImage int) error
 
 	ubo.Proj.SetPerspective(fovy, aspectRatio, near, far)
 
-	err := writeData(app.deviceDriver, app.uniformBuffersMemory[currentImage], 0, &ubo)
-	return err
+	uniformLayout, err := uniformBufferLayout()
+	if err != nil {
+		return err
+	}
+
+	memory := app.uniformBuffersMemory[currentImage]
+	memoryPtr, _, err := app.deviceDriver.MapMemory(memory, 0, uniformLayout.Size, 0)
+	if err != nil {
+		return err
+	}
+	defer app.deviceDriver.UnmapMemory(memory)
+
+	return uniformLayout.EncodeInto(unsafe.Slice((*byte)(memoryPtr), uniformLayout.Size), &ubo)
 }
 
 func (app *HelloTriangleApplication) chooseSwapSurfaceFormat(availableFormats []khr_surface.SurfaceFormat) khr_surface.SurfaceFormat {
@@ -2226,6 +2496,22 @@ func main() {
 	app := &HelloTriangleApplication{
 		msaaSamples: core1_0.Samples1,
 	}
+	app.printf.Forward = app.logDebug
+	app.printf.Logger = log.Default()
+
+	for _, arg := range os.Args[1:] {
+		if app.validation.ParseArg(arg) {
+			continue
+		} else if arg == "--help" || arg == "-h" {
+			fmt.Println("\nOptions")
+			validation.PrintUsage()
+			os.Exit(0)
+		} else {
+			fmt.Printf("\nUnrecognized option: %s\n", arg)
+			fmt.Println("\nUse --help or -h for option list.")
+			os.Exit(0)
+		}
+	}
 
 	err := app.Run()
 	if err != nil {